@@ -0,0 +1,176 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// activeWindowWidget shows the focused window's icon and title. Like the
+// taskbar it watches the shared X connection and updates on PropertyNotify
+// for _NET_ACTIVE_WINDOW on the root and title changes on the focused window.
+type activeWindowWidget struct {
+	X      *xgb.Conn
+	events *xWatch
+	root   xproto.Window
+	box    *fyne.Container
+
+	icon     *canvas.Image
+	iconSize int
+	showIcon bool
+	title    *widget.Label
+	maxLen   int
+	ellipsis string
+
+	activeWindow, netWMName, netWMIcon xproto.Atom
+
+	mu     sync.Mutex
+	active xproto.Window
+}
+
+// newActiveWindowWidget watches X and subscribes to root property changes;
+// titles are clamped to maxLen characters, ending in ellipsis, and
+// icons scaled to iconSize pixels, or not shown when iconSize is 0
+func newActiveWindowWidget(maxLen int, ellipsis string, iconSize int) (*activeWindowWidget, error) {
+	watch, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	X := watch.X
+	a := &activeWindowWidget{
+		X:        X,
+		events:   watch,
+		maxLen:   maxLen,
+		ellipsis: ellipsis,
+		root:     xproto.Setup(X).DefaultScreen(X).Root,
+		icon:     canvas.NewImageFromImage(nil),
+		iconSize: iconSize,
+		showIcon: iconSize > 0,
+		title:    widget.NewLabel(""),
+	}
+	a.icon.FillMode = canvas.ImageFillContain
+	a.icon.SetMinSize(fyne.NewSize(float32(iconSize), float32(iconSize)))
+	a.icon.Hide()
+	a.box = container.NewHBox(container.NewCenter(a.icon), a.title)
+	for name, atom := range map[string]*xproto.Atom{
+		"_NET_ACTIVE_WINDOW": &a.activeWindow,
+		"_NET_WM_NAME":       &a.netWMName,
+		"_NET_WM_ICON":       &a.netWMIcon,
+	} {
+		if *atom, err = internAtom(X, name); err != nil {
+			watch.Close()
+			return nil, err
+		}
+	}
+	if err := watch.Select(a.root, xproto.EventMaskPropertyChange); err != nil {
+		watch.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// CanvasObject returns the object to place in the bar
+func (a *activeWindowWidget) CanvasObject() fyne.CanvasObject {
+	return a.box
+}
+
+// Run shows the current window and then follows focus and title changes
+// until Close or the connection closes
+func (a *activeWindowWidget) Run() {
+	a.refresh()
+	for {
+		ev, ok := a.events.Next()
+		if !ok {
+			return
+		}
+		pn, ok := ev.(xproto.PropertyNotifyEvent)
+		if !ok {
+			continue
+		}
+		switch pn.Atom {
+		case a.activeWindow, a.netWMName, xproto.AtomWmName, a.netWMIcon:
+			a.refresh()
+		}
+	}
+}
+
+// Close stops Run
+func (a *activeWindowWidget) Close() {
+	a.events.Close()
+}
+
+// refresh reads the focused window's title and icon
+func (a *activeWindowWidget) refresh() {
+	var win xproto.Window
+	if values, err := getProperty32(a.X, a.root, "_NET_ACTIVE_WINDOW"); err == nil && len(values) > 0 {
+		win = xproto.Window(values[0])
+	}
+	a.mu.Lock()
+	if win != a.active {
+		// Follow title and icon changes of the newly focused window only
+		if a.active != 0 {
+			a.events.Select(a.active, 0)
+		}
+		if win != 0 {
+			a.events.Select(win, xproto.EventMaskPropertyChange)
+		}
+	}
+	a.active = win
+	a.mu.Unlock()
+
+	if win == 0 {
+		setLabelText(a.title, "")
+		a.icon.Hide()
+		return
+	}
+	setLabelText(a.title, clampTextWith(windowTitle(a.X, win), a.maxLen, a.ellipsis))
+	if !a.showIcon {
+		return
+	}
+	if img := windowIcon(a.X, win, a.iconSize); img != nil {
+		a.icon.Image = scaleIcon(img, a.iconSize)
+		a.icon.Refresh()
+		a.icon.Show()
+	} else {
+		a.icon.Hide()
+	}
+}
+
+// windowIcon decodes the _NET_WM_ICON entry closest to size (preferring
+// larger ones, which scale down cleanly), or nil if the window has none.
+// The property holds width, height and width*height ARGB pixels per icon.
+func windowIcon(X *xgb.Conn, win xproto.Window, size int) image.Image {
+	data, err := getProperty32(X, win, "_NET_WM_ICON")
+	if err != nil {
+		return nil
+	}
+	bestAt, bestW, bestH := -1, 0, 0
+	for i := 0; i+2 <= len(data); {
+		w, h := int(data[i]), int(data[i+1])
+		if w <= 0 || h <= 0 || i+2+w*h > len(data) {
+			break
+		}
+		if bestAt < 0 || (bestW < size && w > bestW) || (w >= size && w < bestW) {
+			bestAt, bestW, bestH = i+2, w, h
+		}
+		i += 2 + w*h
+	}
+	if bestAt < 0 {
+		return nil
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, bestW, bestH))
+	for p := 0; p < bestW*bestH; p++ {
+		argb := data[bestAt+p]
+		img.SetNRGBA(p%bestW, p/bestW, color.NRGBA{
+			R: uint8(argb >> 16), G: uint8(argb >> 8), B: uint8(argb), A: uint8(argb >> 24),
+		})
+	}
+	return img
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// appIndex keeps the scanned applications in memory so the Start Menu opens
+// without reading every .desktop file again. inotify marks it stale when a
+// file in one of the directories changes, and the next Entries rescans.
+type appIndex struct {
+	dirs   []string
+	hidden []string
+
+	mu      sync.Mutex
+	entries []DesktopEntry
+	err     error
+	stale   bool
+	watched bool // without a watch every Entries rescans
+}
+
+// newAppIndex creates an index of dirs, leaving out the hidden apps; it is
+// scanned on the first Entries
+func newAppIndex(dirs, hidden []string) *appIndex {
+	return &appIndex{dirs: dirs, hidden: hidden, stale: true}
+}
+
+// Entries returns the applications, sorted by Name, scanning the
+// directories first if they changed since the last scan. The caller may
+// reorder the returned slice.
+func (x *appIndex) Entries() ([]DesktopEntry, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.stale || !x.watched {
+		x.entries, x.err = scanApplicationDirs(x.dirs, x.hidden)
+		x.stale = false
+	}
+	return append([]DesktopEntry(nil), x.entries...), x.err
+}
+
+// Watch marks the index stale whenever a file in its directories, or in a
+// directory created in them later, is created, written, removed or renamed,
+// until ctx is done. Directories that don't exist are not watched.
+func (x *appIndex) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range x.dirs {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				if err := w.Add(path); err != nil {
+					log.Printf("Not watching %s for new applications: %v", path, err)
+				}
+			}
+			return nil
+		})
+	}
+	x.mu.Lock()
+	x.watched = true
+	x.mu.Unlock()
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Has(fsnotify.Create) {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						w.Add(ev.Name)
+					}
+				}
+				x.mu.Lock()
+				x.stale = true
+				x.mu.Unlock()
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Println("Watching the application directories:", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// WirePlumber default device names understood by wpctl
+const (
+	wpctlSink   = "@DEFAULT_AUDIO_SINK@"
+	wpctlSource = "@DEFAULT_AUDIO_SOURCE@"
+)
+
+// audioMuteWidget shows output and input mute as two glyphs in one click
+// area; clicking a glyph toggles that device. The state is read with wpctl,
+// so it needs PipeWire with WirePlumber.
+type audioMuteWidget struct {
+	output *widget.Label
+	input  *widget.Label
+	area   *regionArea
+}
+
+// init registers the audio mute widget
+func init() {
+	registerWidget("audio", func(cfg Config) bool { return cfg.ShowAudioMute }, func(Config) (Widget, error) {
+		return newAudioMuteWidget(), nil
+	})
+}
+
+// newAudioMuteWidget creates the widget, hidden until the first Update
+func newAudioMuteWidget() *audioMuteWidget {
+	a := &audioMuteWidget{output: widget.NewLabel(""), input: widget.NewLabel("")}
+	a.area = newRegionArea(
+		[]fyne.CanvasObject{a.output, a.input},
+		[]func(){func() { a.toggle(wpctlSink) }, func() { a.toggle(wpctlSource) }},
+	)
+	a.area.Hide()
+	return a
+}
+
+// Render returns the object to place in the bar
+func (a *audioMuteWidget) Render() fyne.CanvasObject {
+	return a.area
+}
+
+// Interval polls once a second; a toggle from the bar updates at once
+func (a *audioMuteWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// Update reads both mute states; the widget hides while wpctl fails
+func (a *audioMuteWidget) Update(context.Context) {
+	sinkMuted, err := wpctlMuted(wpctlSink)
+	if err != nil {
+		a.area.Hide()
+		return
+	}
+	setLabelText(a.output, muteGlyph(sinkMuted, "🔊", "🔇"))
+	if sourceMuted, err := wpctlMuted(wpctlSource); err == nil {
+		setLabelText(a.input, muteGlyph(sourceMuted, "🎤", "🚫"))
+		a.input.Show()
+	} else {
+		// No capture device
+		a.input.Hide()
+	}
+	a.area.Show()
+}
+
+// toggle flips the mute state of device and refreshes the glyphs
+func (a *audioMuteWidget) toggle(device string) {
+	if err := exec.Command("wpctl", "set-mute", device, "toggle").Run(); err != nil {
+		log.Printf("Failed to toggle mute of %s: %v", device, err)
+		return
+	}
+	a.Update(context.Background())
+}
+
+// wpctlMuted reports whether device is muted. wpctl prints e.g.
+// "Volume: 0.40 [MUTED]".
+func wpctlMuted(device string) (bool, error) {
+	out, err := exec.Command("wpctl", "get-volume", device).Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), "[MUTED]"), nil
+}
+
+// muteGlyph picks the glyph for a mute state
+func muteGlyph(muted bool, on, off string) string {
+	if muted {
+		return off
+	}
+	return on
+}
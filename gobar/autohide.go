@@ -0,0 +1,166 @@
+package main
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// autoHidePoll is how often the pointer is checked in auto-hide mode while
+// the bar is shown, or hidden without a reveal strip
+const autoHidePoll = 150 * time.Millisecond
+
+// autoHider decides the bar's visibility in auto-hide mode: it reveals the
+// bar when the pointer touches the bar's screen edge and hides it once the
+// pointer has been off the bar for delay. While the bar is hidden a 1px
+// input-only strip on its edge reports the pointer entering it, so the
+// hidden bar needs no polling.
+type autoHider struct {
+	X        *xgb.Conn
+	events   *xWatch
+	root     xproto.Window
+	geometry barGeometry
+	delay    time.Duration
+	leftAt   time.Time
+
+	strip  xproto.Window // 0 when it couldn't be created
+	reveal chan struct{} // receives when the pointer enters the strip
+}
+
+// newAutoHider watches X for pointer queries and creates the reveal strip,
+// unmapped; without it a hidden bar falls back to polling
+func newAutoHider(g barGeometry, delay time.Duration) (*autoHider, error) {
+	events, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	X := events.X
+	a := &autoHider{X: X, events: events, root: xproto.Setup(X).DefaultScreen(X).Root, geometry: g, delay: delay, reveal: make(chan struct{}, 1)}
+	if err := a.createStrip(); err != nil {
+		log.Println("Auto-hide polls the pointer, no reveal strip:", err)
+		// Nothing reads the events without the strip
+		events.Close()
+	}
+	return a, nil
+}
+
+// createStrip creates the reveal strip and follows its EnterNotify events
+func (a *autoHider) createStrip() error {
+	wid, err := xproto.NewWindowId(a.X)
+	if err != nil {
+		return err
+	}
+	x, y, w, h := a.stripRect()
+	// Override-redirect keeps the WM from framing or tiling the strip
+	err = xproto.CreateWindowChecked(a.X, 0, wid, a.root, x, y, w, h, 0,
+		xproto.WindowClassInputOnly, 0,
+		xproto.CwOverrideRedirect|xproto.CwEventMask,
+		[]uint32{1, xproto.EventMaskEnterWindow}).Check()
+	if err != nil {
+		return err
+	}
+	a.strip = wid
+	go func() {
+		for {
+			ev, ok := a.events.Next()
+			if !ok {
+				return // connection closed
+			}
+			if enter, ok := ev.(xproto.EnterNotifyEvent); ok && enter.Event == a.strip {
+				select {
+				case a.reveal <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// stripRect is the reveal strip's rectangle: the bar's outermost pixel row
+// or column
+func (a *autoHider) stripRect() (x, y int16, w, h uint16) {
+	g := a.geometry
+	x, y = int16(g.x), int16(g.y)
+	switch g.edge {
+	case "left", "right":
+		if g.edge == "right" {
+			x += int16(g.thickness - 1)
+		}
+		return x, y, 1, uint16(g.length)
+	default:
+		if g.edge == "bottom" {
+			y += int16(g.thickness - 1)
+		}
+		return x, y, uint16(g.length), 1
+	}
+}
+
+// showStrip maps the reveal strip at the bar's current edge while the bar is
+// hidden and unmaps it while shown, so it never takes the bar's clicks
+func (a *autoHider) showStrip(hidden bool) {
+	if a.strip == 0 {
+		return
+	}
+	if !hidden {
+		xproto.UnmapWindow(a.X, a.strip)
+		return
+	}
+	x, y, w, h := a.stripRect()
+	xproto.ConfigureWindow(a.X, a.strip,
+		xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(x), uint32(y), uint32(w), uint32(h), xproto.StackModeAbove})
+	xproto.MapWindow(a.X, a.strip)
+}
+
+// depth is how far the pointer is from the bar's edge, in pixels. Outside
+// the bar's span, e.g. on another monitor, it counts as far away.
+func (a *autoHider) depth(x, y int) int {
+	g := a.geometry
+	along, across := x-g.x, y-g.y
+	if g.edge == "left" || g.edge == "right" {
+		along, across = y-g.y, x-g.x
+	}
+	depth := across
+	if g.edge == "right" || g.edge == "bottom" {
+		depth = g.thickness - 1 - across
+	}
+	if along < 0 || along >= g.length || depth < 0 {
+		return math.MaxInt32
+	}
+	return depth
+}
+
+// polls reports whether the pointer needs polling: while the bar is shown,
+// or while it is hidden without a reveal strip
+func (a *autoHider) polls(visible bool) bool {
+	return visible || a.strip == 0
+}
+
+// next reports whether the bar should be visible, given whether it is now
+func (a *autoHider) next(visible bool) bool {
+	if !a.polls(visible) {
+		// The strip reports the pointer itself
+		return false
+	}
+	pointer, err := xproto.QueryPointer(a.X, a.root).Reply()
+	if err != nil {
+		return visible
+	}
+	depth := a.depth(int(pointer.RootX), int(pointer.RootY))
+	if !visible {
+		a.leftAt = time.Time{}
+		return depth <= 0
+	}
+	if depth < a.geometry.thickness {
+		a.leftAt = time.Time{}
+		return true
+	}
+	if a.leftAt.IsZero() {
+		a.leftAt = time.Now()
+	}
+	return time.Since(a.leftAt) < a.delay
+}
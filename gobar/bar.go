@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// barEnv is what the bars share. With AllOutputs every output gets a bar
+// built by build with the same widgets: those that do their work in the
+// background, like the clock, custom widgets and plugins, do it once for
+// all bars, the others run on each bar's own scheduler.
+type barEnv struct {
+	app           fyne.App
+	menu          *startMenu
+	run           *runDialog // nil without ShowRunButton
+	clock         *clockWidget
+	qtile         *qtileClient
+	desktop       *desktopToggler
+	idle          *idleSource // nil without the idle widget
+	customs       []*customWidget
+	plugins       []*pluginWidget
+	publicIP      *publicIPWidget     // nil when off
+	notifications *notificationCenter // nil when off
+}
+
+// barView is the widgets of one bar
+type barView struct {
+	box    barBox
+	banner *widget.Button
+}
+
+// build creates the widgets of the bar in bar, configured by cfg, and
+// schedules their updates on sched; the bar's tasks and connections end with
+// ctx. Values are exported to metrics, which is nil when export is off.
+func (e *barEnv) build(ctx context.Context, sched *scheduler, cfg Config, bar *barWindow, metrics *metricsRegistry) *barView {
+	vertical := cfg.Orientation == "vertical"
+	barHeight := cfg.BarHeight
+	if barHeight <= 0 {
+		barHeight = defaultBarHeight
+	}
+	// Window and tray icons are scaled to fit the bar
+	iconSize := barIconSize(barHeight)
+	// Banner explaining degraded mode when X11 setup fails; click to dismiss
+	var banner *widget.Button
+	banner = widget.NewButton("", func() { banner.Hide() })
+	banner.Importance = widget.DangerImportance
+	banner.Hide()
+
+	// Arrange widgets horizontally
+	statusBar := newBarBox(container.New(barLayout{gap: cfg.PaddingInner, vertical: vertical}, banner), cfg.Spacers, cfg.GroupSeparator, cfg.WidgetColors)
+	registered := newWidgetSet(ctx, cfg, sched, statusBar, metrics)
+	if cfg.LogoPath != "" {
+		// Square image sized to the bar, optionally clickable
+		logo := canvas.NewImageFromFile(expandPath(cfg.LogoPath))
+		logo.FillMode = canvas.ImageFillContain
+		logo.SetMinSize(fyne.NewSize(barHeight, barHeight))
+		if cfg.LogoClickCommand != "" {
+			statusBar.add("logo", newTapArea(logo, func() { launchCommand(cfg.LogoClickCommand) }))
+		} else {
+			statusBar.add("logo", logo)
+		}
+	}
+	statusBar.add("start", widget.NewButton("Start Menu", e.menu.Show))
+	if cfg.ShowTerminalButton {
+		statusBar.add("terminal", widget.NewButton("Terminal", func() {
+			home, _ := os.UserHomeDir()
+			launchCommandIn(cfg.terminal(), home)
+		}))
+	}
+	if e.run != nil {
+		statusBar.add("run", widget.NewButton("Run", e.run.Show))
+	}
+	statusBar.Add(widget.NewSeparator())
+	if cfg.ShowGroups {
+		groups := newGroupsWidget(e.qtile, cfg.WrapGroups)
+		statusBar.add("groups", groups.CanvasObject(), widget.NewSeparator())
+		// Qtile publishes the current group through EWMH, so switches show at
+		// once instead of on the next tick
+		if events, err := watchRootProperties([]string{"_NET_CURRENT_DESKTOP", "_NET_NUMBER_OF_DESKTOPS", "_NET_DESKTOP_NAMES"}, groups.Update); err != nil {
+			log.Println("Groups update once a second, cannot watch the root window:", err)
+		} else {
+			context.AfterFunc(ctx, events.Close)
+		}
+		sched.Every("groups", time.Second, groups.Update)
+	}
+	if cfg.ShowLayout {
+		// Current layout; clicking cycles layouts, hidden while Qtile is unreachable
+		layoutButton := widget.NewButton("", nil)
+		layoutButton.Importance = widget.LowImportance
+		layoutButton.OnTapped = func() {
+			if err := nextLayout(e.qtile); err != nil {
+				log.Println("Failed to change layout:", err)
+				return
+			}
+			if name, err := currentLayout(e.qtile); err == nil {
+				layoutButton.SetText(cfg.prefix("layout") + name)
+			}
+		}
+		layoutButton.Hide()
+		statusBar.add("layout", layoutButton, widget.NewSeparator())
+		sched.Every("layout", time.Second, func() {
+			if name, err := currentLayout(e.qtile); err == nil {
+				setButtonText(layoutButton, cfg.prefix("layout")+name)
+				layoutButton.Show()
+			} else {
+				layoutButton.Hide()
+			}
+		})
+	}
+	if cfg.ShowTaskbar {
+		if taskbar, err := newTaskbarWidget(cfg.maxWidth("taskbar"), iconSize); err != nil {
+			log.Println("Taskbar disabled, X connection failed:", err)
+		} else {
+			statusBar.add("taskbar", taskbar.CanvasObject(), widget.NewSeparator())
+			sched.Go("taskbar", taskbar.Run)
+			context.AfterFunc(ctx, taskbar.Close)
+		}
+	}
+	if cfg.ShowActiveWindow {
+		activeIcon := iconSize
+		if !cfg.ActiveWindowIcon {
+			activeIcon = 0
+		}
+		if active, err := newActiveWindowWidget(cfg.maxWidth("title"), cfg.ActiveWindowEllipsis, activeIcon); err != nil {
+			log.Println("Active window widget disabled, X connection failed:", err)
+		} else {
+			statusBar.add("title", active.CanvasObject(), widget.NewSeparator())
+			sched.Go("title", active.Run)
+			context.AfterFunc(ctx, active.Close)
+		}
+	}
+	statusBar.add("time", e.clock.View(ctx, cfg.prefix("time")), widget.NewSeparator())
+	registered.place("cpu", widget.NewSeparator())
+	registered.place("ram", widget.NewSeparator())
+	registered.place("net", widget.NewSeparator())
+	registered.place("battery", widget.NewSeparator())
+	if cfg.ShowKbdBacklight {
+		// The keyboard backlight is watched where it reports the level set by
+		// the hardware, and polled elsewhere
+		kbdBacklight := newKbdBacklightWidget(cfg.prefix("kbd"))
+		statusBar.add("kbd", kbdBacklight.CanvasObject())
+		sysfsPoll := time.Duration(cfg.SysfsPollMs) * time.Millisecond
+		if files := kbdBacklight.files(); files != nil {
+			sched.Watch("kbd", files, sysfsPoll, kbdBacklight.Update)
+		} else {
+			sched.Every("kbd", sysfsPoll, kbdBacklight.Update)
+		}
+	}
+	registered.place("keyboard")
+	registered.place("proc", widget.NewSeparator())
+	if e.idle != nil {
+		idleLabel := widget.NewLabel(cfg.prefix("idle"))
+		statusBar.add("idle", idleLabel, widget.NewSeparator())
+		sched.Every("idle", time.Second, func() {
+			if d, err := e.idle.Idle(); err == nil {
+				setLabelText(idleLabel, cfg.prefix("idle")+formatIdle(d))
+			}
+		})
+	}
+	if cfg.LogTailFile != "" {
+		logView := newLogView(cfg.prefix("log"), cfg.maxWidth("log"))
+		statusBar.add("log", logView.area, widget.NewSeparator())
+		sched.Every("log", time.Second, logTailer(expandPath(cfg.LogTailFile), logView.set))
+	}
+	for _, custom := range e.customs {
+		statusBar.add("custom:"+custom.spec.Name, custom.View(ctx), widget.NewSeparator())
+	}
+	for _, plugin := range e.plugins {
+		statusBar.add("plugin:"+plugin.spec.Name, plugin.View(ctx), widget.NewSeparator())
+	}
+	// Registered widgets without a place of their own follow the plugins
+	registered.placeRest()
+	if cfg.ShowScreenshot {
+		var screenshotButton *widget.Button
+		screenshotButton = widget.NewButton("📷", func() {
+			go func() {
+				path := screenshotPath(cfg.ScreenshotPath, cfg.ScreenshotTimeFormat, time.Now())
+				if err := takeScreenshot(cfg.ScreenshotCommand, path); err != nil {
+					log.Println("Screenshot failed:", err)
+					screenshotButton.SetText("📷 ✗")
+				} else {
+					screenshotButton.SetText("📷 ✓")
+					if cfg.ScreenshotCopyPath {
+						bar.win.Clipboard().SetContent(path)
+					}
+				}
+				// Show the result briefly, then restore the button
+				time.Sleep(2 * time.Second)
+				screenshotButton.SetText("📷")
+			}()
+		})
+		screenshotButton.Importance = widget.LowImportance
+		statusBar.add("screenshot", screenshotButton)
+	}
+	if cfg.ShowDesktopButton {
+		desktopButton := widget.NewButton("🖥", e.desktop.Toggle)
+		desktopButton.Importance = widget.LowImportance
+		statusBar.add("desktop", desktopButton)
+	}
+	if cfg.ShowScreenOff {
+		screenOffButton := widget.NewButton("⏻", func() { go screenOff(cfg) })
+		screenOffButton.Importance = widget.LowImportance
+		statusBar.add("screenoff", screenOffButton)
+	}
+	if e.notifications != nil {
+		statusBar.add("notifications", e.notifications.View(ctx, cfg.prefix("notifications"), cfg.prefixAs("notifications", dndIcon)))
+	}
+	if cfg.ShowMedia {
+		artSize := 0
+		if cfg.MediaShowArt {
+			artSize = iconSize
+		}
+		if media, err := newMediaWidget(cfg.maxWidth("media"), artSize, cfg.MediaControls); err != nil {
+			log.Println("Media widget disabled, cannot use D-Bus:", err)
+		} else {
+			statusBar.add("media", media.CanvasObject())
+			sched.Go("media", func() { media.Run(cfg.prefix("media")) })
+			sched.Every("media-position", time.Second, func() { media.Update(cfg.prefix("media")) })
+			context.AfterFunc(ctx, media.Close)
+		}
+	}
+	registered.place("audio")
+	registered.place("mic")
+	registered.place("camera")
+	registered.place("power")
+	registered.place("session")
+	if e.publicIP != nil {
+		statusBar.add("wan", e.publicIP.View(ctx, cfg.prefix("wan")))
+	}
+	registered.place("vpn")
+	if cfg.ShowNMConnection {
+		if nm, err := newNMConnectionWidget(e.app, cfg.prefix("nm")); err != nil {
+			log.Println("Connection widget disabled, cannot use the system bus:", err)
+		} else {
+			statusBar.add("nm", nm.CanvasObject(), widget.NewSeparator())
+			sched.Go("nm", nm.Run)
+			context.AfterFunc(ctx, nm.Close)
+		}
+	}
+	if cfg.ShowDisplayMode {
+		if display, err := newDisplayModeWidget(cfg.Output, cfg.prefix("display"), cfg.DisplayModeCommand); err != nil {
+			log.Println("Display mode widget disabled:", err)
+		} else {
+			statusBar.add("display", display.CanvasObject(), widget.NewSeparator())
+			sched.Go("display", display.Run)
+			context.AfterFunc(ctx, display.Close)
+		}
+	}
+	return &barView{box: statusBar, banner: banner}
+}
+
+// content puts the widgets in cfg's order and lays them out with its padding
+// and background; right-clicking the background opens the bar menu
+func (v *barView) content(a fyne.App, cfg Config) fyne.CanvasObject {
+	if len(cfg.Widgets) > 0 {
+		v.box.order(cfg.Widgets)
+	}
+	content := container.New(insetLayout{left: cfg.PaddingLeft, right: cfg.PaddingRight}, v.box.Container)
+	if cfg.BackgroundColor != "" {
+		// Solid or translucent rectangle behind the widgets, independent of the theme
+		if bg, err := parseHexColor(cfg.BackgroundColor); err != nil {
+			log.Println("Ignoring BackgroundColor:", err)
+		} else {
+			rect := canvas.NewRectangle(bg)
+			rect.CornerRadius = cfg.CornerRadius
+			content = container.NewStack(rect, content)
+		}
+	}
+	return container.NewStack(newBarBackground(newBarMenu(a, v.box).Show), content)
+}
+
+// showBanner logs msg and shows it on the bar until clicked
+func (v *barView) showBanner(msg string) {
+	log.Println(msg)
+	v.banner.SetText("⚠ " + msg + " (click to dismiss)")
+	v.banner.Show()
+}
+
+// decorate applies cfg's ClickThrough and Opacity to the docked bar winID,
+// shaping its input on sched
+func (v *barView) decorate(sched *scheduler, cfg Config, winID uint32, w fyne.Window) {
+	if cfg.ClickThrough {
+		// Empty parts of the overlay pass clicks to the windows below
+		if shaper, err := newInputShaper(winID); err != nil {
+			log.Println("Click-through disabled, X Shape extension unavailable:", err)
+		} else {
+			sched.Every("clickthrough", time.Second, func() {
+				if err := shaper.Set(widgetRects(w.Canvas(), v.box.Objects)); err != nil {
+					log.Println("Failed to update the click-through region:", err)
+				}
+			})
+		}
+	}
+	if cfg.Opacity < 1 {
+		if err := setWindowOpacity(winID, cfg.Opacity); err != nil {
+			log.Println("Failed to set window opacity:", err)
+		}
+	}
+}
+
+// fullBar is the bar of another output with AllOutputs: built like the main
+// bar, with widgets and tasks of its own that Close stops
+type fullBar struct {
+	*barWindow
+	cfg     Config
+	sched   *scheduler
+	view    *barView
+	cancel  context.CancelFunc
+	visible bool
+}
+
+// newFullBar builds and shows the bar for m, its tasks listed on sched under
+// the output's name; the tray, notification popups, idle dimming and
+// auto-hide stay on the main bar
+func newFullBar(e *barEnv, sched *scheduler, cfg Config, m MonitorGeometry, barHeight float32) *fullBar {
+	cfg.Output = m.Output
+	cfg.Compact = cfg.compactOn(m)
+	b := createBar(e.app, cfg, "Go Taskbar", m, barHeight)
+	ctx, cancel := context.WithCancel(sched.ctx)
+	f := &fullBar{barWindow: b, cfg: cfg, sched: sched.sub(ctx, m.Output), cancel: cancel, visible: true}
+	f.view = e.build(ctx, f.sched, cfg, b, nil)
+	b.win.SetContent(f.view.content(e.app, cfg))
+	b.win.Show()
+	go func() {
+		winID, err := f.dock(cfg.ReserveSpace, cfg.AlwaysOnTop)
+		if errors.Is(err, errNoX11Window) {
+			f.view.showBanner("No X11 window, bar is not docked")
+			return
+		}
+		if err != nil {
+			f.view.showBanner("Dock setup failed, bar is not docked: " + err.Error())
+		}
+		f.view.decorate(f.sched, cfg, winID, b.win)
+	}()
+	return f
+}
+
+// move places the bar on m
+func (f *fullBar) move(m MonitorGeometry, barHeight float32) {
+	if f.place(f.cfg, m, barHeight) {
+		if err := f.redock(f.cfg.ReserveSpace && f.visible); err != nil {
+			log.Printf("Failed to move the bar on %s: %v", m.Output, err)
+		}
+	}
+}
+
+// setVisible shows or hides the bar, releasing its strut while hidden
+func (f *fullBar) setVisible(visible bool) {
+	f.visible = visible
+	if winID := f.winID.Load(); winID != 0 && f.cfg.ReserveSpace {
+		if err := setStrut(winID, f.geometry, visible); err != nil {
+			log.Println("Failed to update strut:", err)
+		}
+	}
+	if visible {
+		f.win.Show()
+	} else {
+		f.win.Hide()
+	}
+}
+
+// Close stops the bar's tasks and closes its window
+func (f *fullBar) Close() {
+	f.cancel()
+	f.close(f.cfg.ReserveSpace)
+}
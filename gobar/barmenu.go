@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// barBackground sits behind the widgets and opens the bar menu on a right
+// click, so the bar has its own controls when no tray host shows gobar's
+// tray icon
+type barBackground struct {
+	widget.BaseWidget
+	onMenu func()
+}
+
+// newBarBackground calls onMenu on each right click
+func newBarBackground(onMenu func()) *barBackground {
+	b := &barBackground{onMenu: onMenu}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// Tapped implements fyne.Tappable; plain clicks do nothing
+func (b *barBackground) Tapped(*fyne.PointEvent) {}
+
+// TappedSecondary implements fyne.SecondaryTappable
+func (b *barBackground) TappedSecondary(*fyne.PointEvent) {
+	b.onMenu()
+}
+
+// CreateRenderer draws nothing; the bar's background shows through
+func (b *barBackground) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewWithoutLayout())
+}
+
+// barMenu is the window opened from the bar background, with the tray's
+// Reload Config, Edit Config and Quit actions and a switch for each widget.
+// Like the other popups it is a separate window, since menus in the bar's
+// canvas would be clipped to its height.
+type barMenu struct {
+	app fyne.App
+	bar barBox
+	win fyne.Window
+}
+
+// newBarMenu prepares the menu, created on first Show
+func newBarMenu(a fyne.App, bar barBox) *barMenu {
+	return &barMenu{app: a, bar: bar}
+}
+
+// Show opens the menu, listing the widgets with their current visibility
+func (m *barMenu) Show() {
+	if m.win == nil {
+		m.win = m.app.NewWindow("gobar")
+		m.win.SetCloseIntercept(m.win.Hide)
+	}
+	action := func(label string, get func() func()) *widget.Button {
+		b := widget.NewButton(label, func() {
+			m.win.Hide()
+			tray.mu.Lock()
+			handler := get()
+			tray.mu.Unlock()
+			if handler != nil {
+				handler()
+			}
+		})
+		b.Alignment = widget.ButtonAlignLeading
+		b.Importance = widget.LowImportance
+		return b
+	}
+	toggles := container.NewVBox()
+	for _, name := range m.bar.names() {
+		name := name
+		check := widget.NewCheck(name, func(on bool) { m.bar.setVisible(name, on) })
+		check.Checked = m.bar.widgets[name][0].Visible()
+		toggles.Add(check)
+	}
+	quit := widget.NewButton("Quit", m.app.Quit)
+	quit.Alignment = widget.ButtonAlignLeading
+	quit.Importance = widget.LowImportance
+	m.win.SetContent(container.NewVBox(
+		action("Reload Config", func() func() { return tray.onReload }),
+		action("Edit Config", func() func() { return tray.onEdit }),
+		widget.NewAccordion(widget.NewAccordionItem("Toggle Widgets", toggles)),
+		widget.NewSeparator(),
+		quit,
+	))
+	m.win.Show()
+	m.win.RequestFocus()
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+)
+
+// batteryGlob matches the sysfs directories of the laptop batteries; dual
+// battery ThinkPads have BAT0 and BAT1
+const batteryGlob = "/sys/class/power_supply/BAT*"
+
+// acGlob matches the AC adapters, whose online attribute is 1 while plugged in
+const acGlob = "/sys/class/power_supply/AC*"
+
+// batteryWidget shows the combined charge as text, a meter or both, as
+// BatteryStyle picks, with the details in a tooltip; it hides on desktops,
+// which have no battery
+type batteryWidget struct {
+	cfg     Config
+	label   *colorLabel
+	meter   *batteryMeter
+	area    *tooltipArea
+	metrics *metricsRegistry
+}
+
+// init registers the battery widget
+func init() {
+	registerWidget("battery", func(cfg Config) bool { return cfg.ShowBattery }, func(cfg Config) (Widget, error) {
+		return newBatteryWidget(cfg), nil
+	})
+}
+
+// newBatteryWidget creates the widget in the configured style
+func newBatteryWidget(cfg Config) *batteryWidget {
+	b := &batteryWidget{cfg: cfg, label: newColorLabel(cfg.prefix("battery")), meter: newBatteryMeter(cfg.iconSize())}
+	meterBox := container.NewCenter(b.meter)
+	b.area = newTooltipArea(container.NewHBox(meterBox, b.label))
+	if cfg.BatteryStyle == "text" {
+		meterBox.Hide()
+	} else if cfg.BatteryStyle == "meter" {
+		b.label.Hide()
+	}
+	return b
+}
+
+// Render returns the object to place in the bar
+func (b *batteryWidget) Render() fyne.CanvasObject {
+	return b.area
+}
+
+// Interval implements Widget; the battery never reports changes, so it is
+// polled
+func (b *batteryWidget) Interval() time.Duration {
+	return time.Duration(b.cfg.SysfsPollMs) * time.Millisecond
+}
+
+// SetMetrics implements widgetExporter
+func (b *batteryWidget) SetMetrics(r *metricsRegistry) {
+	b.metrics = r
+}
+
+// Update reads the batteries
+func (b *batteryWidget) Update(context.Context) {
+	batteries, err := readBatteries()
+	if err != nil {
+		b.area.Hide()
+		return
+	}
+	combined := combineBatteries(batteries)
+	b.label.SetText(b.cfg.formatBattery(batteries))
+	b.label.SetColor(b.cfg.colorFor("battery", combined.capacity))
+	// A full battery on AC is plugged in without charging; without an
+	// adapter in sysfs the charging state stands in for it
+	plugged, ok := acOnline()
+	if !ok {
+		plugged = combined.status == "Charging"
+	}
+	b.meter.Set(combined.capacity, plugged, b.cfg.colorFor("battery", combined.capacity))
+	b.area.SetTooltip(batteryDetails(batteries))
+	b.area.Show()
+	b.metrics.set("gobar_battery_percent", "gauge", "Combined charge of the batteries.", combined.capacity)
+}
+
+// batteryInfo is one sample of a battery's sysfs attributes. Drivers report
+// either energy (µWh) and power (µW) or charge (µAh) and current (µA);
+// readBattery converts both to Wh and W.
+type batteryInfo struct {
+	name     string  // BAT0, ...; empty for a combined sample
+	capacity float64 // percent
+	status   string  // Charging, Discharging, Full, ...
+	power    float64 // W
+	energy   float64 // Wh
+	full     float64 // Wh
+	cycles   int     // 0 when not reported
+}
+
+// readBattery samples the battery in dir
+func readBattery(dir string) (batteryInfo, error) {
+	capacity, err := sysfsFloat(dir, "capacity")
+	if err != nil {
+		return batteryInfo{}, err
+	}
+	b := batteryInfo{name: filepath.Base(dir), capacity: capacity, status: sysfsString(dir, "status")}
+	if power, err := sysfsFloat(dir, "power_now"); err == nil {
+		b.power = power / 1e6
+	} else if current, err := sysfsFloat(dir, "current_now"); err == nil {
+		voltage, _ := sysfsFloat(dir, "voltage_now")
+		b.power = current * voltage / 1e12
+	}
+	if energy, err := sysfsFloat(dir, "energy_now"); err == nil {
+		b.energy = energy / 1e6
+		full, _ := sysfsFloat(dir, "energy_full")
+		b.full = full / 1e6
+	} else if charge, err := sysfsFloat(dir, "charge_now"); err == nil {
+		// µAh times µV is 1e-12 Wh; the design voltage stands in for a mean
+		voltage, _ := sysfsFloat(dir, "voltage_min_design")
+		full, _ := sysfsFloat(dir, "charge_full")
+		b.energy, b.full = charge*voltage/1e12, full*voltage/1e12
+	}
+	if cycles, err := sysfsFloat(dir, "cycle_count"); err == nil {
+		b.cycles = int(cycles)
+	}
+	return b, nil
+}
+
+// acOnline reports whether an AC adapter is online; ok is false when sysfs
+// lists none
+func acOnline() (online, ok bool) {
+	dirs, _ := filepath.Glob(acGlob)
+	for _, dir := range dirs {
+		v, err := sysfsFloat(dir, "online")
+		if err != nil {
+			continue
+		}
+		ok = true
+		if v == 1 {
+			return true, true
+		}
+	}
+	return false, ok
+}
+
+// readBatteries samples every battery, in name order
+func readBatteries() ([]batteryInfo, error) {
+	dirs, err := filepath.Glob(batteryGlob)
+	if err != nil {
+		return nil, err
+	}
+	var batteries []batteryInfo
+	for _, dir := range dirs {
+		if b, err := readBattery(dir); err == nil {
+			batteries = append(batteries, b)
+		}
+	}
+	if len(batteries) == 0 {
+		return nil, errors.New("no battery found")
+	}
+	return batteries, nil
+}
+
+// combineBatteries treats several batteries as one. Capacity is weighted by
+// each battery's full energy when known, since the packs often differ in
+// size; otherwise it is the plain average.
+func combineBatteries(batteries []batteryInfo) batteryInfo {
+	if len(batteries) == 1 {
+		return batteries[0]
+	}
+	var total batteryInfo
+	var capacitySum float64
+	for _, b := range batteries {
+		total.power += b.power
+		total.energy += b.energy
+		total.full += b.full
+		capacitySum += b.capacity
+		// ThinkPads drain one pack at a time; any activity wins over Full or Unknown
+		switch {
+		case b.status == "Charging" || b.status == "Discharging" && total.status != "Charging":
+			total.status = b.status
+		case total.status == "":
+			total.status = b.status
+		}
+	}
+	if total.full > 0 {
+		total.capacity = total.energy / total.full * 100
+	} else {
+		total.capacity = capacitySum / float64(len(batteries))
+	}
+	return total
+}
+
+// timeLeft estimates the time to empty while discharging, or to full while
+// charging, from the current energy and power draw
+func (b batteryInfo) timeLeft() (time.Duration, bool) {
+	if b.power <= 0 {
+		return 0, false
+	}
+	var hours float64
+	switch b.status {
+	case "Discharging":
+		hours = b.energy / b.power
+	case "Charging":
+		hours = (b.full - b.energy) / b.power
+	default:
+		return 0, false
+	}
+	if hours <= 0 {
+		return 0, false
+	}
+	return time.Duration(hours * float64(time.Hour)), true
+}
+
+// details is the tooltip text: status, power draw, estimate and cycle count
+func (b batteryInfo) details() string {
+	lines := []string{fmt.Sprintf("%s, %.0f%%", b.status, b.capacity)}
+	if b.power > 0 {
+		lines = append(lines, fmt.Sprintf("Power: %.1f W", b.power))
+	}
+	if d, ok := b.timeLeft(); ok {
+		what := "empty"
+		if b.status == "Charging" {
+			what = "full"
+		}
+		lines = append(lines, fmt.Sprintf("Time to %s: %s", what, formatTimeLeft(d)))
+	}
+	if b.cycles > 0 {
+		lines = append(lines, fmt.Sprintf("Cycles: %d", b.cycles))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// batteryDetails is the tooltip for one or more batteries: the combined
+// details, then a line per battery
+func batteryDetails(batteries []batteryInfo) string {
+	text := combineBatteries(batteries).details()
+	if len(batteries) > 1 {
+		for _, b := range batteries {
+			text += fmt.Sprintf("\n%s: %.0f%% %s", b.name, b.capacity, strings.ToLower(b.status))
+			if b.cycles > 0 {
+				text += fmt.Sprintf(", %d cycles", b.cycles)
+			}
+		}
+	}
+	return text
+}
+
+// formatTimeLeft renders a battery estimate, e.g. "2h05m"
+func formatTimeLeft(d time.Duration) string {
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatBattery renders the battery widget, e.g. "Bat: 80%+" while charging.
+// With BatterySeparate each battery is listed, e.g. "Bat: 80% 95%".
+// BatteryShowTime appends the combined estimate, e.g. "Bat: 45% 2h05m".
+func (c Config) formatBattery(batteries []batteryInfo) string {
+	combined := combineBatteries(batteries)
+	shown := []batteryInfo{combined}
+	if c.BatterySeparate {
+		shown = batteries
+	}
+	var parts []string
+	for _, b := range shown {
+		part := c.percent(b.capacity)
+		if b.status == "Charging" {
+			part += "+"
+		}
+		parts = append(parts, part)
+	}
+	if d, ok := combined.timeLeft(); ok && c.BatteryShowTime {
+		parts = append(parts, formatTimeLeft(d))
+	}
+	return c.prefix("battery") + strings.Join(parts, " ")
+}
+
+// batteryAlarm decides when to warn about a critically low battery: once
+// per discharge below the critical charge, armed again by charging or by a
+// charge back above it
+type batteryAlarm struct {
+	fired bool
+}
+
+// check reports whether b calls for a warning at the critical charge crit
+func (a *batteryAlarm) check(b batteryInfo, crit float64) bool {
+	if b.status != "Discharging" || b.capacity > crit {
+		a.fired = false
+		return false
+	}
+	if a.fired {
+		return false
+	}
+	a.fired = true
+	return true
+}
+
+// sysfsString reads a sysfs attribute, trimmed; "" if it is missing
+func sysfsString(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// sysfsFloat reads a numeric sysfs attribute
+func sysfsFloat(dir, name string) (float64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// batteryMeter draws the charge as a small battery outline that fills from
+// the left, with a bolt over it while on AC power
+type batteryMeter struct {
+	widget.BaseWidget
+	size fyne.Size
+
+	mu       sync.Mutex
+	capacity float64     // percent
+	plugged  bool        // on AC power
+	color    color.Color // fill colour; nil is the theme foreground
+}
+
+// newBatteryMeter creates a meter fitting icons of iconSize pixels: as tall
+// as half the icon and twice as wide, plus the terminal nub
+func newBatteryMeter(iconSize int) *batteryMeter {
+	h := float32(max(iconSize/2, 6))
+	m := &batteryMeter{size: fyne.NewSize(2*h+h/4, h)}
+	m.ExtendBaseWidget(m)
+	return m
+}
+
+// Set updates the charge, AC state and fill colour, refreshing only
+// when something changed
+func (m *batteryMeter) Set(capacity float64, plugged bool, c color.Color) {
+	m.mu.Lock()
+	changed := m.capacity != capacity || m.plugged != plugged || m.color != c
+	m.capacity, m.plugged, m.color = capacity, plugged, c
+	m.mu.Unlock()
+	if changed {
+		m.Refresh()
+	}
+}
+
+// CreateRenderer builds the outline, fill, nub and bolt
+func (m *batteryMeter) CreateRenderer() fyne.WidgetRenderer {
+	r := &batteryMeterRenderer{
+		m:    m,
+		body: canvas.NewRectangle(color.Transparent),
+		fill: canvas.NewRectangle(color.Transparent),
+		nub:  canvas.NewRectangle(color.Transparent),
+		bolt: canvas.NewText("⚡", color.Transparent),
+	}
+	r.body.StrokeWidth = 1
+	r.body.CornerRadius = 2
+	r.bolt.TextStyle.Bold = true
+	r.Refresh()
+	return r
+}
+
+// batteryMeterRenderer lays the meter out inside the widget's size
+type batteryMeterRenderer struct {
+	m               *batteryMeter
+	body, fill, nub *canvas.Rectangle
+	bolt            *canvas.Text
+}
+
+// Layout centres the battery vertically and fills it by capacity
+func (r *batteryMeterRenderer) Layout(size fyne.Size) {
+	r.m.mu.Lock()
+	capacity := r.m.capacity
+	r.m.mu.Unlock()
+	h := r.m.size.Height
+	nubW := h / 4
+	bodyW := r.m.size.Width - nubW
+	top := (size.Height - h) / 2
+
+	r.body.Move(fyne.NewPos(0, top))
+	r.body.Resize(fyne.NewSize(bodyW, h))
+	r.nub.Move(fyne.NewPos(bodyW, top+h/4))
+	r.nub.Resize(fyne.NewSize(nubW, h/2))
+
+	const inset = 2
+	level := float32(min(max(capacity, 0), 100) / 100)
+	r.fill.Move(fyne.NewPos(inset, top+inset))
+	r.fill.Resize(fyne.NewSize((bodyW-2*inset)*level, h-2*inset))
+
+	r.bolt.TextSize = h
+	boltSize := r.bolt.MinSize()
+	r.bolt.Move(fyne.NewPos((bodyW-boltSize.Width)/2, top+(h-boltSize.Height)/2))
+	r.bolt.Resize(boltSize)
+}
+
+// MinSize is the size the meter was created with
+func (r *batteryMeterRenderer) MinSize() fyne.Size {
+	return r.m.size
+}
+
+// Refresh recolours the battery, shows the bolt while plugged in and lays it out
+func (r *batteryMeterRenderer) Refresh() {
+	r.m.mu.Lock()
+	fill, plugged := r.m.color, r.m.plugged
+	r.m.mu.Unlock()
+	fg := theme.Color(theme.ColorNameForeground)
+	if fill == nil {
+		fill = fg
+	}
+	r.body.StrokeColor = fg
+	r.nub.FillColor = fg
+	r.fill.FillColor = fill
+	r.bolt.Color = theme.Color(theme.ColorNameBackground)
+	if plugged {
+		r.bolt.Show()
+	} else {
+		r.bolt.Hide()
+	}
+	r.Layout(r.m.Size())
+	for _, o := range r.Objects() {
+		o.Refresh()
+	}
+}
+
+// Objects returns the body, fill, nub and bolt
+func (r *batteryMeterRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.body, r.fill, r.nub, r.bolt}
+}
+
+// Destroy has nothing to release
+func (r *batteryMeterRenderer) Destroy() {}
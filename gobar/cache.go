@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheState is runtime state persisted between runs in the cache file
+type cacheState struct {
+	// Last size of the Start Menu window
+	StartMenuWidth  float32 `json:",omitempty"`
+	StartMenuHeight float32 `json:",omitempty"`
+	// Last position of the Start Menu window on the screen
+	StartMenuX, StartMenuY *int `json:",omitempty"`
+	// Commands entered in the run dialog, oldest first
+	RunHistory []string `json:",omitempty"`
+	// Start Menu launches by entry Name, for the recent and frequency sorts
+	AppLaunches map[string]appLaunches `json:",omitempty"`
+	// The tray's Always on Top choice, overriding AlwaysOnTop once made
+	AlwaysOnTop *bool `json:",omitempty"`
+	// Apps pinned to the tray from the Start Menu, after the TrayLaunchers
+	PinnedLaunchers []TrayLauncher `json:",omitempty"`
+}
+
+// appLaunches counts how often an application was started from the Start Menu
+type appLaunches struct {
+	Count int
+	Last  time.Time
+}
+
+// applyTo adds the choices made in the bar to cfg. They are kept here
+// rather than written to the config file, which would lose its comments
+// and layout.
+func (s cacheState) applyTo(cfg *Config) {
+	if s.AlwaysOnTop != nil {
+		cfg.AlwaysOnTop = *s.AlwaysOnTop
+	}
+	for _, l := range s.PinnedLaunchers {
+		// An app since added to the config is listed there
+		if !hasLauncher(cfg.TrayLaunchers, l.Name) {
+			cfg.TrayLaunchers = append(cfg.TrayLaunchers, l)
+		}
+	}
+}
+
+// cachePath returns the location of the gobar cache file
+func cachePath() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "gobar", "state.json")
+}
+
+// loadCache reads the cache file, returning empty state if it is missing or corrupt
+func loadCache() cacheState {
+	var state cacheState
+	if data, err := os.ReadFile(cachePath()); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+// saveCache writes the state to the cache file, creating its directory
+func saveCache(state cacheState) error {
+	path := cachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
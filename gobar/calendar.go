@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// calendarPopup is a small window showing a month, the current one when
+// opened. Arrows or scrolling step through the months. It is a separate
+// window because dialogs would be clipped by the bar's height.
+type calendarPopup struct {
+	app   fyne.App
+	win   fyne.Window
+	shown bool
+	today time.Time
+	month time.Time // first of the month shown
+}
+
+// newCalendarPopup prepares the calendar window, created on first Show
+func newCalendarPopup(a fyne.App) *calendarPopup {
+	return &calendarPopup{app: a}
+}
+
+// Show opens the calendar at the month containing now
+func (c *calendarPopup) Show(now time.Time) {
+	if c.win == nil {
+		c.win = c.app.NewWindow("Calendar")
+		c.win.SetCloseIntercept(func() {
+			c.shown = false
+			c.win.Hide()
+		})
+	}
+	c.today = now
+	c.month = firstOfMonth(now)
+	c.draw()
+	c.shown = true
+	c.win.Show()
+	c.win.RequestFocus()
+}
+
+// Refresh redraws an open calendar for now, e.g. after midnight, keeping the
+// month shown unless it was the current one
+func (c *calendarPopup) Refresh(now time.Time) {
+	if c.win != nil && c.shown {
+		if c.month.Equal(firstOfMonth(c.today)) {
+			c.month = firstOfMonth(now)
+		}
+		c.today = now
+		c.draw()
+	}
+}
+
+// step moves the calendar by months, back for a negative count
+func (c *calendarPopup) step(months int) {
+	c.month = c.month.AddDate(0, months, 0)
+	c.draw()
+}
+
+// draw shows the current month between arrows stepping through the months
+func (c *calendarPopup) draw() {
+	prev := widget.NewButton("◀", func() { c.step(-1) })
+	next := widget.NewButton("▶", func() { c.step(1) })
+	prev.Importance, next.Importance = widget.LowImportance, widget.LowImportance
+	title := widget.NewLabelWithStyle(c.month.Format("January 2006"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	header := container.NewBorder(nil, nil, prev, next, title)
+	today := 0
+	if c.month.Equal(firstOfMonth(c.today)) {
+		today = c.today.Day()
+	}
+	grid := newScrollArea(monthGrid(c.month, today), func(ev *fyne.ScrollEvent) {
+		switch {
+		case ev.Scrolled.DY > 0:
+			c.step(-1)
+		case ev.Scrolled.DY < 0:
+			c.step(1)
+		}
+	})
+	c.win.SetContent(container.NewVBox(header, grid))
+}
+
+// firstOfMonth returns midnight on the first day of t's month
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// monthGrid lays out the month of t as a Monday-first grid, highlighting the
+// day today unless it is 0
+func monthGrid(t time.Time, today int) fyne.CanvasObject {
+	grid := container.NewGridWithColumns(7)
+	for _, day := range []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"} {
+		grid.Add(widget.NewLabelWithStyle(day, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}))
+	}
+
+	first := firstOfMonth(t)
+	// time.Weekday starts on Sunday; shift so Monday is column 0
+	offset := (int(first.Weekday()) + 6) % 7
+	for i := 0; i < offset; i++ {
+		grid.Add(widget.NewLabel(""))
+	}
+	days := first.AddDate(0, 1, -1).Day()
+	for day := 1; day <= days; day++ {
+		style := fyne.TextStyle{}
+		if day == today {
+			style.Bold = true
+		}
+		label := widget.NewLabelWithStyle(strconv.Itoa(day), fyne.TextAlignCenter, style)
+		if day == today {
+			label.Importance = widget.HighImportance
+		}
+		grid.Add(label)
+	}
+	return grid
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"github.com/BurntSushi/xgb/shape"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// inputShaper limits where the bar window takes pointer input with the X
+// Shape extension's input region. Clicks outside it reach the windows below,
+// which suits an overlay bar that doesn't reserve space.
+type inputShaper struct {
+	win  xproto.Window
+	last []xproto.Rectangle
+}
+
+// newInputShaper prepares shaping the window winID on the shared connection
+func newInputShaper(winID uint32) (*inputShaper, error) {
+	if _, err := barExtension("SHAPE", shape.Init); err != nil {
+		return nil, err
+	}
+	return &inputShaper{win: xproto.Window(winID)}, nil
+}
+
+// Set makes rects the window's input region, skipping the request when they
+// haven't changed
+func (s *inputShaper) Set(rects []xproto.Rectangle) error {
+	if slices.Equal(rects, s.last) {
+		return nil
+	}
+	X, err := barExtension("SHAPE", shape.Init)
+	if err != nil {
+		return err
+	}
+	err = shape.RectanglesChecked(X, shape.SoSet, shape.SkInput, xproto.ClipOrderingUnsorted,
+		s.win, 0, 0, rects).Check()
+	if err == nil {
+		s.last = rects
+	}
+	return err
+}
+
+// widgetRects returns the window pixel areas covered by the visible objects,
+// leaving out spacers and the gaps between widgets
+func widgetRects(c fyne.Canvas, objects []fyne.CanvasObject) []xproto.Rectangle {
+	driver := fyne.CurrentApp().Driver()
+	scale := c.Scale()
+	var rects []xproto.Rectangle
+	for _, o := range objects {
+		if _, spacer := o.(layout.SpacerObject); spacer || !o.Visible() {
+			continue
+		}
+		pos, size := driver.AbsolutePositionForObject(o), o.Size()
+		rects = append(rects, xproto.Rectangle{
+			X:      int16(pos.X * scale),
+			Y:      int16(pos.Y * scale),
+			Width:  uint16(size.Width*scale + 0.5),
+			Height: uint16(size.Height*scale + 0.5),
+		})
+	}
+	return rects
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// timeFormatter renders a time in a TimeFormat
+type timeFormatter func(time.Time) string
+
+// clockShowsSeconds reports whether a time format changes within a minute,
+// i.e. shows seconds or fractions of them
+func clockShowsSeconds(format timeFormatter) bool {
+	t := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	return format(t) != format(t.Add(time.Second+time.Millisecond))
+}
+
+// clockInterval is how often a clock in format needs updating: every
+// second when it shows seconds, else every minute
+func clockInterval(format timeFormatter) time.Duration {
+	if clockShowsSeconds(format) {
+		return time.Second
+	}
+	return time.Minute
+}
+
+// dayRollover reports whether now falls on a later day than last. The zero
+// last, before the first tick, never rolls over.
+func dayRollover(last, now time.Time) bool {
+	if last.IsZero() {
+		return false
+	}
+	ly, lm, ld := last.Date()
+	ny, nm, nd := now.Date()
+	return time.Date(ny, nm, nd, 0, 0, 0, 0, now.Location()).After(time.Date(ly, lm, ld, 0, 0, 0, 0, now.Location()))
+}
+
+// strftimeLayouts maps strftime conversions to Go layout elements
+var strftimeLayouts = map[byte]string{
+	'a': "Mon", 'A': "Monday", 'b': "Jan", 'h': "Jan", 'B': "January",
+	'd': "02", 'e': "_2", 'm': "01", 'y': "06", 'Y': "2006", 'j': "002",
+	'H': "15", 'I': "03", 'l': "3", 'M': "04", 'S': "05", 'p': "PM",
+	'Z': "MST", 'z': "-0700", 'T': "15:04:05", 'R': "15:04", 'D': "01/02/06",
+	'F': "2006-01-02", 'n': "\n", 't': "\t", '%': "%",
+}
+
+// parseTimeFormat reads a TimeFormat: one containing a % is taken as
+// strftime, e.g. "%a %d %b %H:%M", anything else is a Go layout. Each
+// strftime conversion is formatted on its own, so the text around it is
+// kept as written even where it looks like a Go layout element, as in
+// "Week 1" or "UTC+1". It fails on conversions Go can't express.
+func parseTimeFormat(format string) (timeFormatter, error) {
+	if !strings.Contains(format, "%") {
+		return func(t time.Time) string { return t.Format(format) }, nil
+	}
+	// Literal text, or a conversion's layout when layout is set
+	type part struct{ text, layout string }
+	var parts []part
+	start := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 == len(format) {
+			return nil, fmt.Errorf("%q ends in a lone %%", format)
+		}
+		layout, ok := strftimeLayouts[format[i+1]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported conversion %%%c", format[i+1])
+		}
+		parts = append(parts, part{text: format[start:i]}, part{layout: layout})
+		i++
+		start = i + 1
+	}
+	parts = append(parts, part{text: format[start:]})
+	return func(t time.Time) string {
+		var b strings.Builder
+		for _, p := range parts {
+			if p.layout != "" {
+				b.WriteString(t.Format(p.layout))
+			} else {
+				b.WriteString(p.text)
+			}
+		}
+		return b.String()
+	}, nil
+}
+
+// zoneName is the label of an IANA zone's clock, e.g. "New York" for
+// America/New_York
+func zoneName(zone string) string {
+	return strings.ReplaceAll(path.Base(zone), "_", " ")
+}
+
+// zoneClocks loads the IANA zones of the extra clocks
+func zoneClocks(zones []string) ([]*time.Location, error) {
+	locs := make([]*time.Location, 0, len(zones))
+	for _, zone := range zones {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// formatClock renders the clock text: now in format, followed by each extra
+// zone's name and time in zoneFormat, e.g. "Mon 02 Jan 15:04  New York 09:04"
+func formatClock(now time.Time, format, zoneFormat timeFormatter, zones []*time.Location) string {
+	text := format(now)
+	for _, loc := range zones {
+		text += "  " + zoneName(loc.String()) + " " + zoneFormat(now.In(loc))
+	}
+	return text
+}
+
+// clockWidget is the time button, one on every bar with AllOutputs, all
+// painted by the one clock task; clicking it opens the calendar
+type clockWidget struct {
+	cfg                Config
+	calendar           *calendarPopup
+	format, zoneFormat timeFormatter
+	zones              []*time.Location
+	views              viewSet[*clockView]
+}
+
+// clockView is the time button on one bar
+type clockView struct {
+	button *widget.Button
+	prefix string
+}
+
+// newClockWidget creates the clock for cfg's formats and zones, which
+// loadConfig has already checked
+func newClockWidget(cfg Config, calendar *calendarPopup) *clockWidget {
+	c := &clockWidget{cfg: cfg, calendar: calendar}
+	c.format, _ = parseTimeFormat(cfg.TimeFormat)
+	c.zoneFormat, _ = parseTimeFormat(cfg.TimeZoneFormat)
+	c.zones, _ = zoneClocks(cfg.TimeZones)
+	return c
+}
+
+// interval is how often the clock ticks, per minute when neither the local
+// nor the extra clocks show seconds
+func (c *clockWidget) interval() time.Duration {
+	tick := clockInterval(c.format)
+	if len(c.zones) > 0 {
+		tick = min(tick, clockInterval(c.zoneFormat))
+	}
+	return tick
+}
+
+// View returns a button to place in a bar, shown until ctx is done
+func (c *clockWidget) View(ctx context.Context, prefix string) fyne.CanvasObject {
+	v := &clockView{prefix: prefix}
+	v.button = widget.NewButton(prefix, func() {
+		if c.cfg.TimeClickCommand != "" {
+			launchCommand(c.cfg.TimeClickCommand)
+			return
+		}
+		c.calendar.Show(time.Now())
+	})
+	v.button.Importance = widget.LowImportance
+	c.views.add(ctx, v)
+	return v.button
+}
+
+// Update shows now on every bar
+func (c *clockWidget) Update(now time.Time) {
+	text := formatClock(now, c.format, c.zoneFormat, c.zones)
+	c.views.show(func(v *clockView) { setButtonText(v.button, v.prefix+text) })
+}
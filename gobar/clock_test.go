@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeFormat(t *testing.T) {
+	now := time.Date(2024, time.March, 4, 9, 5, 7, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "Go layout", format: "Mon 02 Jan 15:04", want: "Mon 04 Mar 09:05"},
+		{name: "strftime", format: "%a %d %b %H:%M", want: "Mon 04 Mar 09:05"},
+		{name: "digits after a conversion stay literal", format: "%H:%M UTC+1", want: "09:05 UTC+1"},
+		{name: "words before a conversion stay literal", format: "Week 1 %a", want: "Week 1 Mon"},
+		{name: "layout words stay literal", format: "Monday in Jan: %A %B", want: "Monday in Jan: Monday March"},
+		{name: "combined conversions", format: "%F %T %Z", want: "2024-03-04 09:05:07 CET"},
+		{name: "12-hour clock", format: "%l:%M %p", want: "9:05 AM"},
+		{name: "escaped percent", format: "100%% at %R", want: "100% at 09:05"},
+		{name: "lone percent", format: "%H:%M %", wantErr: true},
+		{name: "unsupported conversion", format: "%Q", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := parseTimeFormat(tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTimeFormat(%q) succeeded, want an error", tt.format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTimeFormat(%q) failed: %v", tt.format, err)
+			}
+			if got := format(now); got != tt.want {
+				t.Errorf("parseTimeFormat(%q) renders %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// collector is a metric source behind a widget
+type collector interface {
+	// Available reports whether the platform supports the metric
+	Available() bool
+}
+
+// probedCollector calls its metric once at startup. If gopsutil reports the
+// call as unimplemented on this platform the widget is disabled, logging
+// once, instead of failing silently on every tick.
+type probedCollector struct {
+	available bool
+}
+
+// newProbedCollector runs probe and records whether name is supported.
+// Other errors may be transient, so they leave the metric enabled.
+func newProbedCollector(name string, probe func() error) *probedCollector {
+	err := probe()
+	if unsupported(err) {
+		log.Printf("%s widget disabled, not supported on this platform: %v", name, err)
+		return &probedCollector{}
+	}
+	return &probedCollector{available: true}
+}
+
+// Available implements collector
+func (c *probedCollector) Available() bool {
+	return c.available
+}
+
+// unsupported matches gopsutil's ErrNotImplementedError, which lives in an
+// internal package and so can only be recognised by its message
+func unsupported(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not implemented yet")
+}
+
+// statCollectors probes the gopsutil metrics the bar shows
+type statCollectors struct {
+	cpu, mem, net, proc collector
+}
+
+// statSupport probes the metrics on the first call and then returns the
+// same result, so each bar's widgets share one probe
+var statSupport = sync.OnceValue(probeStatCollectors)
+
+// probeStatCollectors checks each metric once
+func probeStatCollectors() statCollectors {
+	return statCollectors{
+		cpu: newProbedCollector("CPU", func() error {
+			_, err := cpu.Percent(0, false)
+			return err
+		}),
+		mem: newProbedCollector("RAM", func() error {
+			_, err := mem.VirtualMemory()
+			return err
+		}),
+		net: newProbedCollector("Network", func() error {
+			_, err := net.IOCounters(false)
+			return err
+		}),
+		proc: newProbedCollector("Process", func() error {
+			_, err := process.Pids()
+			return err
+		}),
+	}
+}
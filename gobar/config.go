@@ -0,0 +1,937 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TrayLauncher is a tray menu item that runs a command
+type TrayLauncher struct {
+	Name    string
+	Command string
+	// Focus a running window of the app instead of starting another instance
+	FocusOrLaunch bool `json:",omitempty"`
+	// WM_CLASS used to find the running window; defaults to the command's basename
+	WMClass string `json:",omitempty"`
+	// Working directory for the command; empty keeps gobar's
+	Dir string `json:",omitempty"`
+	// Extra environment variables for the command, as "KEY=value"
+	Env []string `json:",omitempty"`
+	// PNG shown next to the tray menu entry
+	Icon string `json:",omitempty"`
+	// Entries of a submenu; an entry with Items runs no command
+	Items []TrayLauncher `json:",omitempty"`
+	// Start greyed out until enabled through the control socket
+	Disabled bool `json:",omitempty"`
+}
+
+// CustomWidget is a bar label showing the output of a shell command
+type CustomWidget struct {
+	// Unique name; the widget is "custom:" + Name in Spacers and the control socket
+	Name    string
+	Command string
+	// Seconds between runs
+	IntervalSec float64
+	// Text shown before the output
+	Prefix string `json:",omitempty"`
+	// Text colour as #RRGGBB; empty uses the theme foreground
+	Color string `json:",omitempty"`
+	ScriptActions
+}
+
+// ScriptActions are the commands a custom widget or plugin runs on clicks
+// and scrolling, like polybar's click-left and scroll-up; empty ones do
+// nothing
+type ScriptActions struct {
+	// Command run when the widget is clicked
+	ClickCommand       string `json:",omitempty"`
+	RightClickCommand  string `json:",omitempty"`
+	MiddleClickCommand string `json:",omitempty"`
+	ScrollUpCommand    string `json:",omitempty"`
+	ScrollDownCommand  string `json:",omitempty"`
+}
+
+// PluginWidget is a bar label fed by a long-running program printing one
+// JSON object per line
+type PluginWidget struct {
+	// Unique name; the widget is "plugin:" + Name in Spacers and the control socket
+	Name    string
+	Command string
+	ScriptActions
+}
+
+// WidgetColors overrides the colours of one bar widget
+type WidgetColors struct {
+	// Background behind the widget as #RRGGBB or #RRGGBBAA; empty draws none
+	Background string `json:",omitempty"`
+	// Text colour as #RRGGBB; empty uses the theme foreground
+	Foreground string `json:",omitempty"`
+}
+
+// Graph configures the sparkline of a stats widget's recent samples
+type Graph struct {
+	// Samples shown, one per second; 0 uses 30
+	History int `json:",omitempty"`
+	// Colour as #RRGGBB; empty follows the widget's threshold colour
+	Color string `json:",omitempty"`
+	// "line", the default, or "area" to fill below the line
+	Fill string `json:",omitempty"`
+	// Show only the graph, without the widget's text
+	HideText bool `json:",omitempty"`
+}
+
+// Threshold holds the warning and critical levels for a widget value
+type Threshold struct {
+	Warn float64
+	Crit float64
+}
+
+// Config holds user settings loaded from gobar.json or config.toml
+type Config struct {
+	// Reserve screen space with a strut; false lets the bar float as an overlay
+	ReserveSpace bool
+	// With ReserveSpace off, let clicks on the bar's empty areas reach the
+	// windows below
+	ClickThrough bool
+	// Keep the bar above other windows (_NET_WM_STATE_ABOVE); the tray's
+	// Always on Top item toggles and saves it
+	AlwaysOnTop bool
+
+	// RandR output (e.g. "HDMI-1") the bar is placed on; empty uses the whole screen
+	Output string
+	// Show a copy of the bar on every other active output
+	MirrorOutputs bool
+	// Run a clickable bar on every active output, all in this gobar process
+	AllOutputs bool
+
+	// Height of a horizontal bar, in pixels; 0 fits the tallest widget
+	BarHeight float32
+
+	// "horizontal" for a top or bottom bar or "vertical" for a side panel
+	Orientation string
+	// Screen edge of a horizontal bar: "top" or "bottom"
+	Position string
+	// Screen edge of a vertical bar: "left" or "right"
+	VerticalEdge string
+	// Width of a vertical bar, in pixels
+	BarWidth float32
+
+	// Start with the bar hidden; SIGUSR1 toggles it
+	StartHidden bool
+	// Hide the bar when the pointer leaves it, revealing it at its screen edge
+	AutoHide bool
+	// Milliseconds the pointer must be off the bar before it auto-hides
+	AutoHideDelayMs int
+
+	// Bar background as "#RRGGBB" or "#RRGGBBAA"; empty keeps the theme background
+	BackgroundColor string
+	// Whole-window opacity from 0 to 1, applied by a compositor such as picom
+	Opacity float64
+	// Seconds without input after which the bar fades to IdleDimOpacity
+	// until the next input; 0 disables dimming
+	IdleDimSec     int
+	IdleDimOpacity float64
+	// Corner radius of the background rectangle, for a rounded floating bar
+	CornerRadius float32
+	// Text colour as "#RRGGBB", over the theme's and pywal's; empty keeps it
+	ForegroundColor string
+	// Colours of individual widgets, by name as in Spacers
+	WidgetColors map[string]WidgetColors
+
+	// pywal colors.json supplying background, foreground and accent, followed
+	// live; empty keeps the theme colours
+	WalColorsPath string
+
+	// TTF/OTF font used for bar text, e.g. a Nerd Font; empty keeps the default
+	FontPath string
+	// Text size in points; 0 keeps the theme's
+	FontSize float32
+
+	// Image shown at the left end of the bar, e.g. a distro logo; empty hides it
+	LogoPath string
+	// Command run when the logo is clicked; empty makes the logo decorative
+	LogoClickCommand string
+
+	// Widgets (by name, e.g. "cpu", "net") that show a Nerd Font glyph instead of a text prefix
+	GlyphIcons map[string]bool
+
+	// Widgets (by name, as in Spacers) in bar order; empty keeps the built-in
+	// order. Widgets not listed are left off the bar, and listing a widget
+	// does not enable it.
+	Widgets []string
+
+	// Widgets (by name, e.g. "title") followed by a flexible spacer that
+	// pushes the rest of the bar towards the far end
+	Spacers []string
+	// Divider on both sides of each spacer, between widget groups: "none",
+	// "line" or a colour segment as #RRGGBB
+	GroupSeparator string
+
+	// Gaps at the bar's left/right edges and between widgets, in pixels
+	PaddingLeft  float32
+	PaddingRight float32
+	PaddingInner float32
+
+	// Directory the Start Menu lists .desktop files from; empty scans the XDG
+	// application directories
+	AppsDir string
+	// Icon theme for the Start Menu and tray icons, e.g. "Papirus-Dark";
+	// empty uses GTK's gtk-icon-theme-name
+	IconTheme string
+	// Default Start Menu window size, used until it has been resized
+	StartMenuWidth  float32
+	StartMenuHeight float32
+	// Start Menu entries to leave out, matched case-insensitively as a
+	// substring of the desktop-file ID (e.g. "org.gnome.Extensions") or Name
+	HiddenApps []string
+	// Start Menu order: "alpha", "recent" (last launched first) or
+	// "frequency" (most launched first)
+	StartMenuSort string
+
+	// Unix socket accepting JSON control commands, by default
+	// "$XDG_RUNTIME_DIR/gobar.sock"; empty disables it. A bar on an Output
+	// adds the output to the name.
+	ControlSocket string
+	// Address serving the sampled stats at /metrics in the Prometheus text
+	// format, e.g. ":9101"; empty disables it
+	MetricsAddr string
+
+	// Commands run detached once the bar is up, e.g. a compositor or wallpaper setter
+	StartupCommands []string
+
+	// Tray menu launchers, in menu order; apps pinned from the Start Menu are appended
+	TrayLaunchers []TrayLauncher
+
+	// Maximum characters per widget (title, taskbar, media, log); longer text
+	// is cut with an ellipsis and 0 means unlimited
+	MaxWidth map[string]int
+
+	// Colour thresholds keyed by widget name (cpu, ram, mempressure, temp, disk, battery)
+	Thresholds map[string]Threshold
+
+	// Clock format: a Go time layout, or strftime when it contains a %
+	TimeFormat string
+	// IANA zones of extra clocks shown after the local time, e.g. "Asia/Tokyo"
+	TimeZones []string
+	// Format of the extra clocks, like TimeFormat
+	TimeZoneFormat string
+	// Command run when the clock is clicked; empty opens the calendar
+	TimeClickCommand string
+	// Command run when the date changes, e.g. a daily script
+	OnDayChange string
+
+	// Show a button opening a dialog that runs a typed shell command
+	ShowRunButton bool
+
+	// Terminal emulator for the terminal button and anything else run in a
+	// terminal; it must accept -e. Empty uses $TERMINAL, then xterm
+	TerminalCommand string
+	// Show a button opening a terminal in the home directory
+	ShowTerminalButton bool
+
+	// Show a region-screenshot button
+	ShowScreenshot bool
+	// Screenshot command; {path} is replaced with the quoted target file
+	ScreenshotCommand string
+	// Target file template; {timestamp} is replaced using ScreenshotTimeFormat
+	ScreenshotPath       string
+	ScreenshotTimeFormat string
+	// Copy the saved screenshot's path to the clipboard
+	ScreenshotCopyPath bool
+
+	// Show a button that minimizes all windows and restores them on a second click
+	ShowDesktopButton bool
+
+	// Show a button that turns the displays off
+	ShowScreenOff bool
+	// Command to turn the displays off; empty uses the X DPMS extension directly
+	ScreenOffCommand string
+	// Screen locker command, e.g. "i3lock"
+	LockCommand string
+	// Run LockCommand before turning the displays off
+	LockBeforeScreenOff bool
+
+	// Show a badge counting desktop notifications, with a list to dismiss them
+	ShowNotifications bool
+	// Maximum notifications kept in the list
+	NotificationQueueMax int
+	// Be the session's notification daemon, showing popups below the bar,
+	// instead of watching another daemon such as dunst
+	NotificationDaemon bool
+	// Seconds a popup stays when the sender leaves it to the daemon
+	NotificationTimeoutSec int
+	// Lowest urgency ("low", "normal" or "critical") that pops up, by app
+	// name; "*" applies to other apps. Filtered notifications are only listed.
+	NotificationMinUrgency map[string]string
+	// Start in do-not-disturb mode, popping up only critical notifications
+	NotificationDND bool
+
+	// Weight of each new CPU sample in the displayed moving average, 0..1;
+	// 0 shows raw samples
+	CPUSmoothing float64
+	// Show a bar per CPU core next to the CPU percentage
+	PerCoreCPU bool
+	// Sparklines of recent samples, keyed by stats widget (cpu, ram, net)
+	Graphs map[string]Graph
+
+	// Show the used share of each of DiskMounts
+	ShowDisk bool
+	// Mount points shown by the disk widget
+	DiskMounts []string
+	// Show temperatures; each TempSensors entry is a sensor key prefix, e.g.
+	// "coretemp_package", and shows the hottest matching sensor
+	ShowTemp    bool
+	TempSensors []string
+	// Shell commands run when a widget (cpu, ram, disk, temp) is clicked, e.g.
+	// {"ram": "xterm -e htop"}
+	ClickCommands map[string]string
+
+	// Decimal places of CPU, RAM and disk percentages
+	Precision int
+	// Pad percentages and compact rates with figure spaces to a fixed width
+	// so the widgets after them don't shift as the numbers change
+	FixedWidthNumbers bool
+
+	// Terse widget text without prefixes, for narrow screens
+	Compact bool
+	// Enable Compact automatically when the screen is narrower than this many
+	// pixels; 0 disables the check
+	CompactBelowWidth int
+
+	// Network rate units: "bytes" (KB/s, MB/s) or "bits" (Kbps, Mbps)
+	NetUnit string
+	// Interface whose rates the network widget shows, e.g. "wlan0"; empty
+	// sums every interface
+	NetInterface string
+	// Show the Wi-Fi network and signal strength while the shown interface
+	// (NetInterface, else the default route's) is wireless; needs iw
+	NetShowWifi bool
+
+	// Show Qtile's groups with click and scroll switching
+	ShowGroups bool
+	// Let scrolling past the last group wrap around to the first
+	WrapGroups bool
+	// Show the current layout name; clicking cycles to the next layout
+	ShowLayout bool
+	// Qtile IPC socket path; empty uses $QTILE_SOCK or Qtile's default
+	QtileSocket string
+
+	// Show a button per open window
+	ShowTaskbar bool
+	// Show the focused window's icon and title
+	ShowActiveWindow bool
+	// Show the focused application's icon before its title
+	ActiveWindowIcon bool
+	// Marks a title cut at MaxWidth["title"]; empty cuts without a mark
+	ActiveWindowEllipsis string
+
+	// Show other apps' tray icons in the bar as a StatusNotifierHost
+	TrayHost bool
+	// Show TrayLaunchers as bar buttons when no tray host is running, except
+	// the Disabled ones and those turned off with tray-disable
+	TrayFallbackButtons bool
+	// Dock XEmbed tray icons (the _NET_SYSTEM_TRAY protocol) in the bar
+	XembedTray bool
+
+	// GUI editor for the tray's Edit Config item, e.g. "code"; empty runs
+	// $VISUAL or $EDITOR in the terminal
+	EditorCommand string
+
+	// PNG shown as the tray icon
+	IconPath string
+	// Tray icon shown while attention is requested with SIGUSR2
+	AttentionIconPath string
+	// Alternate between the normal and attention icons while attention is requested
+	AttentionBlink bool
+
+	// Show the running process count widget
+	ShowProcesses bool
+	// Append the thread count to the process widget
+	ShowThreads bool
+
+	// Show how long the session has been idle
+	ShowIdle bool
+
+	// File whose last line is shown in the bar; empty disables the widget
+	LogTailFile string
+	// Maximum characters of the tailed line before it is truncated
+	LogTailMaxLength int
+
+	// Labels showing shell command output, placed after the log widget in
+	// this order
+	CustomWidgets []CustomWidget
+	// Widgets fed by plugin processes, placed after the custom widgets
+	Plugins []PluginWidget
+
+	// Show the current MPRIS track with a seekable progress bar
+	ShowMedia bool
+	// Show the track's album art (mpris:artUrl) next to it
+	MediaShowArt bool
+	// Add previous, play/pause and next buttons after the track
+	MediaControls bool
+
+	// Show output and input mute glyphs that toggle mute when clicked
+	ShowAudioMute bool
+
+	// Show the default output volume; scroll to change it, click to mute
+	ShowVolume bool
+	// Percent the volume changes per scroll notch
+	VolumeStep int
+
+	// Show the battery charge, with power draw and time estimate in a tooltip
+	ShowBattery bool
+	// List each battery's charge instead of one combined value
+	BatterySeparate bool
+	// "text" (e.g. "Bat: 80%"), "meter" (a battery glyph filling with the
+	// charge) or "both"
+	BatteryStyle string
+	// Append the estimated time to empty (or to full) to the battery text
+	BatteryShowTime bool
+	// Send a desktop notification when the battery discharges to its
+	// critical threshold
+	BatteryNotify bool
+	// How often sysfs files that report no changes, such as the battery's,
+	// are read, in milliseconds
+	SysfsPollMs int
+
+	// Show the keyboard backlight level; scroll over it to adjust
+	ShowKbdBacklight bool
+
+	// Show the keyboard layout and lock states in one indicator, e.g. "US ⇪"
+	ShowKeyboard bool
+	// Elements of the keyboard indicator
+	KeyboardShowLayout bool
+	KeyboardShowCaps   bool
+	KeyboardShowNum    bool
+
+	// Show a red dot while an application records from the microphone
+	ShowMicIndicator bool
+	// Show a camera icon while a video device is open
+	ShowCameraIndicator bool
+	// Glob of the video devices to watch
+	CameraDevices string
+	// How to detect open devices: "fuser", "lsof" or "proc"
+	CameraDetection string
+
+	// Show the power-profiles-daemon profile with a popup to switch it
+	ShowPowerProfile bool
+	// Show a power menu (lock, suspend, hibernate, reboot, power off) and a
+	// sign while sleep is inhibited, through logind
+	ShowSessionMenu bool
+
+	// Show the public IP address; clicking copies it
+	ShowPublicIP bool
+	// Plain-text service returning the public IP
+	PublicIPURL string
+	// Seconds between public IP lookups
+	PublicIPIntervalSec int
+
+	// Show a lock indicator while a VPN is connected
+	ShowVPN bool
+	// Interface name prefixes treated as VPN tunnels
+	VPNInterfaces []string
+	// Also check NetworkManager for active VPN connections
+	VPNUseNetworkManager bool
+	// Command run when the VPN indicator is clicked
+	VPNToggleCommand string
+
+	// Show NetworkManager's primary connection name, or "Offline"
+	ShowNMConnection bool
+
+	// Show the bar output's resolution and refresh rate, e.g. "2560x1440@144"
+	ShowDisplayMode bool
+	// Command run when the display mode is clicked, e.g. "arandr"
+	DisplayModeCommand string
+}
+
+// defaultConfig returns the settings used when no config file exists
+func defaultConfig() Config {
+	return Config{
+		ReserveSpace:         true,
+		Orientation:          "horizontal",
+		Position:             "top",
+		VerticalEdge:         "left",
+		BarWidth:             200,
+		AlwaysOnTop:          true,
+		Opacity:              1,
+		IdleDimOpacity:       0.4,
+		AutoHideDelayMs:      800,
+		SysfsPollMs:          1000,
+		VolumeStep:           5,
+		CompactBelowWidth:    1366,
+		CameraDevices:        "/dev/video*",
+		CameraDetection:      "fuser",
+		PaddingInner:         4,
+		TimeFormat:           "Mon 02 Jan 15:04",
+		TimeZoneFormat:       "15:04",
+		KeyboardShowLayout:   true,
+		KeyboardShowCaps:     true,
+		ActiveWindowIcon:     true,
+		ActiveWindowEllipsis: "…",
+		StartMenuWidth:       400,
+		StartMenuHeight:      500,
+		StartMenuSort:        "alpha",
+		ControlSocket:        defaultControlSocket,
+		IconPath:             "~/.config/qtile/icon.png",
+		BatteryStyle:         "text",
+		TrayLaunchers: []TrayLauncher{
+			{Name: "Steam", Command: "/usr/bin/steam"},
+			{Name: "Flameshot", Command: "/usr/bin/flameshot gui"},
+		},
+		MaxWidth: map[string]int{
+			"title":   60,
+			"taskbar": 30,
+			"media":   40,
+		},
+		Thresholds: map[string]Threshold{
+			"cpu":         {Warn: 70, Crit: 90},
+			"ram":         {Warn: 75, Crit: 90},
+			"mempressure": {Warn: 10, Crit: 30},
+			"temp":        {Warn: 70, Crit: 85},
+			"disk":        {Warn: 80, Crit: 95},
+			"battery":     {Warn: 20, Crit: 10},
+		},
+		ScreenshotCommand:      "maim -s {path}",
+		ScreenshotPath:         "~/Pictures/Screenshots/{timestamp}.png",
+		ScreenshotTimeFormat:   "2006-01-02_15-04-05",
+		NotificationQueueMax:   50,
+		NotificationTimeoutSec: 5,
+		NetUnit:                "bytes",
+		LogTailMaxLength:       60,
+		VPNInterfaces:          []string{"tun", "wg"},
+		PublicIPURL:            "https://api.ipify.org",
+		PublicIPIntervalSec:    600,
+		DiskMounts:             []string{"/"},
+		TempSensors:            []string{"coretemp_package", "k10temp_tctl", "cpu_thermal", "amdgpu_edge"},
+	}
+}
+
+// colorFor returns the threshold colour of a widget value, or nil when the
+// widget has no thresholds configured
+func (c Config) colorFor(widget string, v float64) color.Color {
+	t, ok := c.Thresholds[widget]
+	if !ok {
+		return nil
+	}
+	return colorForValue(v, t.Warn, t.Crit)
+}
+
+// parseHexColor parses "#RRGGBB" or "#RRGGBBAA" (the leading # is optional)
+func parseHexColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q: want #RRGGBB or #RRGGBBAA", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q: %w", s, err)
+	}
+	if len(s) == 6 {
+		v = v<<8 | 0xff
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// expandPath expands a leading "~/" and environment variables in a config path
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return os.ExpandEnv(path)
+}
+
+// configPath returns the location of the gobar config file: the TOML file
+// if it exists, the JSON file otherwise
+func configPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	path := filepath.Join(home, ".config", "gobar", "config.toml")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return filepath.Join(home, ".config", "qtile", "gobar.json")
+}
+
+// isTOML reports whether the config file at path is TOML rather than JSON
+func isTOML(path string) bool {
+	return filepath.Ext(path) == ".toml"
+}
+
+// errInvalidConfig marks loadConfig errors from validate, which are fatal
+var errInvalidConfig = errors.New("invalid config")
+
+// defaultBarHeight is the smallest automatic bar height, in pixels
+const defaultBarHeight = 30
+
+// clickWidgets are the widgets ClickCommands may be set for
+var clickWidgets = []string{"cpu", "ram", "disk", "temp"}
+
+// thresholdWidgets are the widgets colorFor is consulted for
+var thresholdWidgets = []string{"cpu", "ram", "mempressure", "temp", "disk", "battery"}
+
+// maxWidthWidgets are the widgets whose text MaxWidth can clamp
+var maxWidthWidgets = []string{"title", "taskbar", "media", "log"}
+
+// maxWidth returns the character limit of a widget's text. The log widget
+// falls back to LogTailMaxLength.
+func (c Config) maxWidth(widget string) int {
+	if n, ok := c.MaxWidth[widget]; ok {
+		return n
+	}
+	if widget == "log" {
+		return c.LogTailMaxLength
+	}
+	return 0
+}
+
+// terminal returns the terminal command: TerminalCommand, $TERMINAL or xterm
+func (c Config) terminal() string {
+	if c.TerminalCommand != "" {
+		return c.TerminalCommand
+	}
+	if term := os.Getenv("TERMINAL"); term != "" {
+		return term
+	}
+	return "xterm"
+}
+
+// validate checks enum values, ranges and referenced files, returning every
+// problem found as one error
+func (c Config) validate() error {
+	var errs []error
+	bad := func(field string, value any, problem string) {
+		errs = append(errs, fmt.Errorf("%s = %#v: %s", field, value, problem))
+	}
+
+	if c.Orientation != "horizontal" && c.Orientation != "vertical" {
+		bad("Orientation", c.Orientation, `must be "horizontal" or "vertical"`)
+	}
+	if c.Position != "top" && c.Position != "bottom" {
+		bad("Position", c.Position, `must be "top" or "bottom"`)
+	}
+	if c.VerticalEdge != "left" && c.VerticalEdge != "right" {
+		bad("VerticalEdge", c.VerticalEdge, `must be "left" or "right"`)
+	}
+	if c.BarHeight < 0 {
+		bad("BarHeight", c.BarHeight, "must not be negative")
+	}
+	if c.BarWidth <= 0 {
+		bad("BarWidth", c.BarWidth, "must be positive")
+	}
+	if c.NetUnit != "bytes" && c.NetUnit != "bits" {
+		bad("NetUnit", c.NetUnit, `must be "bytes" or "bits"`)
+	}
+	if !slices.Contains([]string{"fuser", "lsof", "proc"}, c.CameraDetection) {
+		bad("CameraDetection", c.CameraDetection, `must be "fuser", "lsof" or "proc"`)
+	}
+	if !slices.Contains([]string{"text", "meter", "both"}, c.BatteryStyle) {
+		bad("BatteryStyle", c.BatteryStyle, `must be "text", "meter" or "both"`)
+	}
+	if _, ok := c.Thresholds["battery"]; c.BatteryNotify && !ok {
+		bad("BatteryNotify", c.BatteryNotify, "needs a battery entry in Thresholds for the critical charge")
+	}
+	if !slices.Contains([]string{"alpha", "recent", "frequency"}, c.StartMenuSort) {
+		bad("StartMenuSort", c.StartMenuSort, `must be "alpha", "recent" or "frequency"`)
+	}
+	if _, err := filepath.Match(c.CameraDevices, ""); err != nil {
+		bad("CameraDevices", c.CameraDevices, "invalid glob")
+	}
+	for field, hex := range map[string]string{
+		"BackgroundColor": c.BackgroundColor,
+		"ForegroundColor": c.ForegroundColor,
+	} {
+		if hex == "" {
+			continue
+		}
+		if _, err := parseHexColor(hex); err != nil {
+			bad(field, hex, err.Error())
+		}
+	}
+	for field, v := range map[string]float32{
+		"FontSize":     c.FontSize,
+		"CornerRadius": c.CornerRadius,
+		"PaddingLeft":  c.PaddingLeft,
+		"PaddingRight": c.PaddingRight,
+		"PaddingInner": c.PaddingInner,
+	} {
+		if v < 0 {
+			bad(field, v, "must not be negative")
+		}
+	}
+	if c.TimeFormat == "" {
+		bad("TimeFormat", c.TimeFormat, "must not be empty")
+	} else if _, err := parseTimeFormat(c.TimeFormat); err != nil {
+		bad("TimeFormat", c.TimeFormat, err.Error())
+	}
+	if _, err := parseTimeFormat(c.TimeZoneFormat); err != nil {
+		bad("TimeZoneFormat", c.TimeZoneFormat, err.Error())
+	}
+	for _, zone := range c.TimeZones {
+		if _, err := time.LoadLocation(zone); err != nil {
+			bad("TimeZones", zone, "is not a known time zone")
+		}
+	}
+	if c.StartMenuWidth <= 0 {
+		bad("StartMenuWidth", c.StartMenuWidth, "must be positive")
+	}
+	if c.StartMenuHeight <= 0 {
+		bad("StartMenuHeight", c.StartMenuHeight, "must be positive")
+	}
+	if c.NotificationQueueMax < 0 {
+		bad("NotificationQueueMax", c.NotificationQueueMax, "must not be negative")
+	}
+	if c.NotificationTimeoutSec <= 0 {
+		bad("NotificationTimeoutSec", c.NotificationTimeoutSec, "must be positive")
+	}
+	for app, urgency := range c.NotificationMinUrgency {
+		if _, ok := urgencyLevels[urgency]; !ok {
+			bad("NotificationMinUrgency", app+": "+urgency, `must be "low", "normal" or "critical"`)
+		}
+	}
+	if c.Opacity < 0 || c.Opacity > 1 {
+		bad("Opacity", c.Opacity, "must be between 0 and 1")
+	}
+	if c.AllOutputs && c.MirrorOutputs {
+		bad("MirrorOutputs", c.MirrorOutputs, "cannot be combined with AllOutputs")
+	}
+	if c.ClickThrough && c.ReserveSpace {
+		bad("ClickThrough", c.ClickThrough, "needs ReserveSpace set to false")
+	}
+	if c.IdleDimSec < 0 {
+		bad("IdleDimSec", c.IdleDimSec, "must not be negative")
+	}
+	if c.IdleDimOpacity < 0 || c.IdleDimOpacity > 1 {
+		bad("IdleDimOpacity", c.IdleDimOpacity, "must be between 0 and 1")
+	}
+	if c.CPUSmoothing < 0 || c.CPUSmoothing > 1 {
+		bad("CPUSmoothing", c.CPUSmoothing, "must be between 0 and 1")
+	}
+	if c.Precision < 0 || c.Precision > 3 {
+		bad("Precision", c.Precision, "must be between 0 and 3")
+	}
+	if c.PublicIPIntervalSec <= 0 {
+		bad("PublicIPIntervalSec", c.PublicIPIntervalSec, "must be positive")
+	}
+	if c.CompactBelowWidth < 0 {
+		bad("CompactBelowWidth", c.CompactBelowWidth, "must not be negative")
+	}
+	if c.SysfsPollMs <= 0 {
+		bad("SysfsPollMs", c.SysfsPollMs, "must be positive")
+	}
+	if c.VolumeStep <= 0 || c.VolumeStep > 100 {
+		bad("VolumeStep", c.VolumeStep, "must be between 1 and 100")
+	}
+	if c.AutoHideDelayMs < 0 {
+		bad("AutoHideDelayMs", c.AutoHideDelayMs, "must not be negative")
+	}
+	if c.LogTailMaxLength < 0 {
+		bad("LogTailMaxLength", c.LogTailMaxLength, "must not be negative")
+	}
+	launcherNames := map[string]bool{}
+	var checkLaunchers func(field string, launchers []TrayLauncher)
+	checkLaunchers = func(field string, launchers []TrayLauncher) {
+		for i, l := range launchers {
+			field := fmt.Sprintf("%s[%d]", field, i)
+			switch {
+			case l.Name == "" || (l.Command == "" && len(l.Items) == 0):
+				bad(field, l, "needs a Name and a Command or Items")
+			case launcherNames[l.Name]:
+				bad(field+".Name", l.Name, "is used by another tray launcher")
+			}
+			launcherNames[l.Name] = true
+			for _, env := range l.Env {
+				if !strings.Contains(env, "=") {
+					bad(field+".Env", env, `must be "KEY=value"`)
+				}
+			}
+			if l.Icon != "" {
+				if _, err := os.Stat(expandPath(l.Icon)); err != nil {
+					bad(field+".Icon", l.Icon, "file not found")
+				}
+			}
+			checkLaunchers(field+".Items", l.Items)
+		}
+	}
+	checkLaunchers("TrayLaunchers", c.TrayLaunchers)
+	customNames := map[string]bool{}
+	for i, w := range c.CustomWidgets {
+		field := fmt.Sprintf("CustomWidgets[%d]", i)
+		switch {
+		case w.Name == "" || w.Command == "":
+			bad(field, w, "needs a Name and a Command")
+		case customNames[w.Name]:
+			bad(field+".Name", w.Name, "is used by another custom widget")
+		case w.IntervalSec <= 0:
+			bad(field+".IntervalSec", w.IntervalSec, "must be positive")
+		}
+		customNames[w.Name] = true
+	}
+	pluginNames := map[string]bool{}
+	for i, p := range c.Plugins {
+		field := fmt.Sprintf("Plugins[%d]", i)
+		switch {
+		case p.Name == "" || p.Command == "":
+			bad(field, p, "needs a Name and a Command")
+		case pluginNames[p.Name]:
+			bad(field+".Name", p.Name, "is used by another plugin")
+		}
+		pluginNames[p.Name] = true
+	}
+	for name := range c.Thresholds {
+		if !slices.Contains(thresholdWidgets, name) {
+			bad("Thresholds", name, "unknown widget, expected one of "+strings.Join(thresholdWidgets, ", "))
+		}
+	}
+	for name := range c.ClickCommands {
+		if !slices.Contains(clickWidgets, name) {
+			bad("ClickCommands", name, "unknown widget, expected one of "+strings.Join(clickWidgets, ", "))
+		}
+	}
+	for i, mount := range c.DiskMounts {
+		if !filepath.IsAbs(mount) {
+			bad(fmt.Sprintf("DiskMounts[%d]", i), mount, "must be an absolute path")
+		}
+	}
+	if c.ShowDisk && len(c.DiskMounts) == 0 {
+		bad("DiskMounts", c.DiskMounts, "must list a mount point for ShowDisk")
+	}
+	for name, n := range c.MaxWidth {
+		if !slices.Contains(maxWidthWidgets, name) {
+			bad("MaxWidth", name, "unknown widget, expected one of "+strings.Join(maxWidthWidgets, ", "))
+		} else if n < 0 {
+			bad("MaxWidth."+name, n, "must not be negative")
+		}
+	}
+	if c.GroupSeparator != "" && c.GroupSeparator != "none" && c.GroupSeparator != "line" {
+		if _, err := parseHexColor(c.GroupSeparator); err != nil {
+			bad("GroupSeparator", c.GroupSeparator, `must be "none", "line" or a colour: `+err.Error())
+		}
+	}
+	knownWidget := func(name string) bool {
+		if custom, ok := strings.CutPrefix(name, "custom:"); ok {
+			return customNames[custom]
+		}
+		if plugin, ok := strings.CutPrefix(name, "plugin:"); ok {
+			return pluginNames[plugin]
+		}
+		return slices.Contains(barWidgets, name)
+	}
+	for _, name := range c.Spacers {
+		if !knownWidget(name) {
+			bad("Spacers", name, "unknown widget, expected one of "+strings.Join(barWidgets, ", "))
+		}
+	}
+	listed := map[string]bool{}
+	for _, name := range c.Widgets {
+		switch {
+		case !knownWidget(name):
+			bad("Widgets", name, "unknown widget, expected one of "+strings.Join(barWidgets, ", "))
+		case listed[name]:
+			bad("Widgets", name, "is listed twice")
+		}
+		listed[name] = true
+	}
+	for name, colors := range c.WidgetColors {
+		if !knownWidget(name) {
+			bad("WidgetColors", name, "unknown widget, expected one of "+strings.Join(barWidgets, ", "))
+		}
+		for _, hex := range []string{colors.Background, colors.Foreground} {
+			if hex == "" {
+				continue
+			}
+			if _, err := parseHexColor(hex); err != nil {
+				bad("WidgetColors", name+": "+hex, err.Error())
+			}
+		}
+	}
+	for name, g := range c.Graphs {
+		switch {
+		case name != "cpu" && name != "ram" && name != "net":
+			bad("Graphs", name, "unknown widget, expected cpu, ram or net")
+		case g.History < 0:
+			bad("Graphs", fmt.Sprintf("%s: History %d", name, g.History), "must not be negative")
+		case g.Fill != "" && g.Fill != "line" && g.Fill != "area":
+			bad("Graphs", name+": "+g.Fill, `unknown fill, expected "line" or "area"`)
+		}
+		if g.Color != "" {
+			if _, err := parseHexColor(g.Color); err != nil {
+				bad("Graphs", name+": "+g.Color, err.Error())
+			}
+		}
+	}
+	for name := range c.GlyphIcons {
+		if _, ok := widgetIcons[name]; !ok {
+			bad("GlyphIcons", name, "unknown widget")
+		}
+	}
+	for field, path := range map[string]string{
+		"FontPath":          c.FontPath,
+		"AttentionIconPath": c.AttentionIconPath,
+		"LogoPath":          c.LogoPath,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(expandPath(path)); err != nil {
+			bad(field, path, "file not found")
+		}
+	}
+
+	// Map iteration order varies; keep the report stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// loadConfig reads the config file over the defaults; a missing file is not
+// an error. Values failing validate are reported wrapping errInvalidConfig.
+func loadConfig() (Config, error) {
+	path := configPath()
+	cfg, err := readConfig(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("No config at %s, using defaults", path)
+		return cfg, nil
+	}
+	if err == nil {
+		log.Println("Using config", path)
+	}
+	return cfg, err
+}
+
+// readConfig is loadConfig without the logging, e.g. for gobar ctl: a
+// missing file gives the defaults with an fs.ErrNotExist error
+func readConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if isTOML(path) {
+		_, err = toml.Decode(string(data), &cfg)
+	} else {
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return defaultConfig(), err
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("%w %s:\n%w", errInvalidConfig, path, err)
+	}
+	return cfg, nil
+}
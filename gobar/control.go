@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+)
+
+// defaultControlSocket is ControlSocket unless the config changes it
+const defaultControlSocket = "$XDG_RUNTIME_DIR/gobar.sock"
+
+// controlRequest is one line sent to the control socket, e.g.
+// {"cmd": "set-widget-text", "widget": "log", "text": "building…"}
+type controlRequest struct {
+	Cmd    string `json:"cmd"`
+	Widget string `json:"widget,omitempty"`
+	Text   string `json:"text,omitempty"`
+	// Tray launcher name for tray-enable and tray-disable
+	Item string `json:"item,omitempty"`
+}
+
+// controlReply answers each request on its own line
+type controlReply struct {
+	OK    bool         `json:"ok"`
+	Error string       `json:"error,omitempty"`
+	Tasks []taskStatus `json:"tasks,omitempty"`
+}
+
+// controlServer accepts JSON commands on a Unix socket so keybindings and
+// scripts can drive the bar
+type controlServer struct {
+	bars     func() []barBox // the main bar's first, then those of AllOutputs
+	sched    *scheduler
+	visible  chan bool // buffered; holds the latest show or hide
+	toggle   chan<- os.Signal
+	launcher func() // opens the Start Menu
+}
+
+// listenControl creates the socket at path, replacing a stale one left by a
+// previous run, and serves it in the background. Closing the returned
+// listener removes the socket.
+func listenControl(path string, s *controlServer) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another gobar", path)
+	}
+	if dir := filepath.Dir(path); dir == fallbackRuntimeDir() {
+		if err := privateDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Only the user may control the bar
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			} else if err != nil {
+				log.Println("Control socket closed:", err)
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return l, nil
+}
+
+// removeStaleSocket removes the socket at path left by a previous run,
+// refusing anything else found there, like another user's file
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 || !ownedByUser(fi) {
+		return fmt.Errorf("%s is not a socket of this user, not replacing it", path)
+	}
+	return os.Remove(path)
+}
+
+// fallbackRuntimeDir stands in for XDG_RUNTIME_DIR when the session has
+// none: a directory of this user's in the temporary directory
+func fallbackRuntimeDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gobar-%d", os.Getuid()))
+}
+
+// privateDir creates dir readable only by the user, and checks that one
+// found there, e.g. in a shared /tmp, is the user's and no one else's
+func privateDir(dir string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() || !ownedByUser(fi) || fi.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is not a private directory of this user", dir)
+	}
+	return nil
+}
+
+// ownedByUser reports whether fi belongs to the user running gobar
+func ownedByUser(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid()
+}
+
+// serve handles the requests of one client until it disconnects
+func (s *controlServer) serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req controlRequest
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err == nil {
+			err = s.handle(req)
+		}
+		reply := controlReply{OK: err == nil}
+		if err != nil {
+			reply.Error = err.Error()
+		} else if req.Cmd == "tasks" {
+			reply.Tasks = s.sched.Tasks()
+		}
+		if enc.Encode(reply) != nil {
+			return
+		}
+		// The client has its answer before the reload replaces the process
+		if req.Cmd == "reload" && err == nil {
+			conn.Close()
+			if err := restartGobar(); err != nil {
+				log.Println("Config reload failed:", err)
+			}
+			return
+		}
+	}
+}
+
+// handle runs one command
+func (s *controlServer) handle(req controlRequest) error {
+	switch req.Cmd {
+	case "show", "hide":
+		s.setVisible(req.Cmd == "show")
+	case "toggle":
+		// Like a SIGUSR1, a toggle already pending takes this one in
+		select {
+		case s.toggle <- syscall.SIGUSR1:
+		default:
+		}
+	case "reload":
+		// serve restarts once the reply is sent
+		_, err := loadConfig()
+		return err
+	case "tasks":
+		// The reply lists the scheduler's tasks
+	case "set-widget-text":
+		bars, err := s.barsWith(req.Widget)
+		if err != nil {
+			return err
+		}
+		for _, bar := range bars {
+			text, ok := findTextSetter(bar.widgets[req.Widget][0])
+			if !ok {
+				return fmt.Errorf("widget %s has no text", req.Widget)
+			}
+			text.SetText(req.Text)
+		}
+	case "toggle-widget":
+		bars, err := s.barsWith(req.Widget)
+		if err != nil {
+			return err
+		}
+		// The other bars follow the first one showing the widget
+		show := !bars[0].widgets[req.Widget][0].Visible()
+		for _, bar := range bars {
+			bar.setVisible(req.Widget, show)
+		}
+	case "open-launcher":
+		s.launcher()
+	case "tray-enable", "tray-disable":
+		if req.Item == "" {
+			return errors.New("missing tray launcher name")
+		}
+		return tray.setLauncherEnabled(req.Item, req.Cmd == "tray-enable")
+	default:
+		return fmt.Errorf("unknown command %q", req.Cmd)
+	}
+	return nil
+}
+
+// setVisible asks for the bar to be shown or hidden without waiting for the
+// dock loop, replacing a request it has not taken yet
+func (s *controlServer) setVisible(visible bool) {
+	for {
+		select {
+		case s.visible <- visible:
+			return
+		default:
+		}
+		select {
+		case <-s.visible:
+		default:
+		}
+	}
+}
+
+// barsWith looks up the bars a widget is placed on
+func (s *controlServer) barsWith(name string) ([]barBox, error) {
+	if name == "" {
+		return nil, errors.New("missing widget name")
+	}
+	var bars []barBox
+	for _, bar := range s.bars() {
+		if len(bar.widgets[name]) > 0 {
+			bars = append(bars, bar)
+		}
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("widget %s is not on the bar", name)
+	}
+	return bars, nil
+}
+
+// textSetter is a widget whose text can be replaced, like a label or button
+type textSetter interface {
+	SetText(string)
+}
+
+// findTextSetter finds the text of a widget inside the wrappers that make
+// it clickable, scrollable or coloured, e.g. a custom widget's label
+func findTextSetter(obj fyne.CanvasObject) (textSetter, bool) {
+	switch o := obj.(type) {
+	case textSetter:
+		return o, true
+	case *tapArea:
+		return findTextSetter(o.content)
+	case *clickArea:
+		return findTextSetter(o.content)
+	case *scrollArea:
+		return findTextSetter(o.content)
+	case *tooltipArea:
+		return findTextSetter(o.content)
+	case *container.ThemeOverride:
+		return findTextSetter(o.Content)
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			if t, ok := findTextSetter(child); ok {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// controlSocketPath is where the bar listens and gobar ctl connects: the
+// expanded ControlSocket, or "" when the config turns the socket off. Like
+// the instance lock it is per output, so bars on different outputs each
+// have their own; "gobar.sock" becomes "gobar-HDMI-1.sock" for Output
+// HDMI-1.
+func controlSocketPath(cfg Config) string {
+	if cfg.ControlSocket == "" {
+		return ""
+	}
+	// Without XDG_RUNTIME_DIR the default would be /gobar.sock
+	path := os.Expand(cfg.ControlSocket, func(key string) string {
+		if v := os.Getenv(key); v != "" || key != "XDG_RUNTIME_DIR" {
+			return v
+		}
+		return fallbackRuntimeDir()
+	})
+	path = expandPath(path)
+	if cfg.Output != "" {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-" + cfg.Output + ext
+	}
+	return path
+}
+
+// runCtl implements "gobar ctl" (or gobar-ctl through a symlink): it sends
+// one command to the running bar's control socket and prints the reply. The
+// arguments are a JSON request, or a command followed by the widget name
+// and text, e.g. "gobar ctl set-widget-text custom:build ok". It returns
+// the exit status.
+func runCtl(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gobar ctl <command> [widget] [text...] | gobar ctl '<json>'")
+		return 2
+	}
+	line := []byte(args[0])
+	if !strings.HasPrefix(args[0], "{") {
+		req := controlRequest{Cmd: args[0]}
+		if len(args) > 1 {
+			if strings.HasPrefix(req.Cmd, "tray-") {
+				req.Item = strings.Join(args[1:], " ")
+			} else {
+				req.Widget = args[1]
+			}
+		}
+		if len(args) > 2 {
+			req.Text = strings.Join(args[2:], " ")
+		}
+		line, _ = json.Marshal(req)
+	}
+	// A broken config still names the socket, and stdout is for the reply
+	cfg, _ := readConfig(configPath())
+	path := controlSocketPath(cfg)
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "gobar ctl: the config turns the control socket off (ControlSocket is empty)")
+		return 1
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gobar ctl: is gobar running?", err)
+		return 1
+	}
+	defer conn.Close()
+	if _, err := conn.Write(append(line, '\n')); err != nil {
+		fmt.Fprintln(os.Stderr, "gobar ctl:", err)
+		return 1
+	}
+	reply, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gobar ctl:", err)
+		return 1
+	}
+	os.Stdout.Write(reply)
+	var r controlReply
+	if json.Unmarshal(reply, &r) != nil || !r.OK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Core bar sizes, in pixels
+const (
+	coreBarWidth = 3
+	coreBarGap   = 1
+)
+
+// coreGraph draws each CPU core's usage as a thin vertical bar filling from
+// the bottom, coloured by the cpu thresholds
+type coreGraph struct {
+	widget.BaseWidget
+	height float32
+	colors func(float64) color.Color // threshold colour of a percentage, or nil
+
+	mu       sync.Mutex
+	percents []float64
+}
+
+// newCoreGraph creates a graph of cores bars, as tall as icons of iconSize
+// pixels; colors picks a bar's colour from its percentage
+func newCoreGraph(cores, iconSize int, colors func(float64) color.Color) *coreGraph {
+	g := &coreGraph{height: float32(iconSize), colors: colors, percents: make([]float64, cores)}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// Set updates the per-core percentages; the bar count follows their number
+func (g *coreGraph) Set(percents []float64) {
+	g.mu.Lock()
+	g.percents = append(g.percents[:0], percents...)
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+// CreateRenderer builds the bars, which are reused across updates
+func (g *coreGraph) CreateRenderer() fyne.WidgetRenderer {
+	r := &coreGraphRenderer{g: g}
+	r.Refresh()
+	return r
+}
+
+// coreGraphRenderer keeps a track and a level rectangle per core
+type coreGraphRenderer struct {
+	g              *coreGraph
+	tracks, levels []*canvas.Rectangle
+	objects        []fyne.CanvasObject
+}
+
+// Layout places the bars side by side, centred vertically
+func (r *coreGraphRenderer) Layout(size fyne.Size) {
+	r.g.mu.Lock()
+	percents := append([]float64(nil), r.g.percents...)
+	r.g.mu.Unlock()
+	h := r.g.height
+	top := (size.Height - h) / 2
+	for i, level := range r.levels {
+		if i >= len(percents) {
+			break
+		}
+		x := float32(i * (coreBarWidth + coreBarGap))
+		r.tracks[i].Move(fyne.NewPos(x, top))
+		r.tracks[i].Resize(fyne.NewSize(coreBarWidth, h))
+		fill := h * float32(min(max(percents[i], 0), 100)/100)
+		level.Move(fyne.NewPos(x, top+h-fill))
+		level.Resize(fyne.NewSize(coreBarWidth, fill))
+	}
+}
+
+// MinSize fits a bar per core at the icon height
+func (r *coreGraphRenderer) MinSize() fyne.Size {
+	r.g.mu.Lock()
+	n := len(r.g.percents)
+	r.g.mu.Unlock()
+	if n == 0 {
+		return fyne.NewSize(0, r.g.height)
+	}
+	return fyne.NewSize(float32(n*coreBarWidth+(n-1)*coreBarGap), r.g.height)
+}
+
+// Refresh adds or drops bars to match the cores, and recolours and lays them out
+func (r *coreGraphRenderer) Refresh() {
+	r.g.mu.Lock()
+	percents := append([]float64(nil), r.g.percents...)
+	r.g.mu.Unlock()
+	// Bars are only added or dropped when the core count changes
+	for len(r.levels) < len(percents) {
+		track, level := canvas.NewRectangle(color.Transparent), canvas.NewRectangle(color.Transparent)
+		r.tracks, r.levels = append(r.tracks, track), append(r.levels, level)
+		r.objects = append(r.objects, track, level)
+	}
+	r.tracks, r.levels = r.tracks[:len(percents)], r.levels[:len(percents)]
+	r.objects = r.objects[:2*len(percents)]
+
+	fg := theme.Color(theme.ColorNameForeground)
+	track := theme.Color(theme.ColorNameInputBackground)
+	for i, level := range r.levels {
+		c := r.g.colors(percents[i])
+		if c == nil {
+			c = fg
+		}
+		r.tracks[i].FillColor = track
+		level.FillColor = c
+	}
+	r.Layout(r.g.Size())
+	for _, o := range r.objects {
+		o.Refresh()
+	}
+}
+
+// Objects returns the tracks and levels of the bars
+func (r *coreGraphRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+// Destroy has nothing to release
+func (r *coreGraphRenderer) Destroy() {}
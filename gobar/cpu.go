@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"runtime"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// cpuSampleAge is how long a CPU sample is reused; readers ticking every
+// second within it share one reading
+const cpuSampleAge = 900 * time.Millisecond
+
+// cpuSampler shares CPU readings between the widgets and the tray tooltip.
+// cpu.Percent(0, ...) measures since its previous call, so readers a moment
+// apart would otherwise see an almost empty interval.
+type cpuSampler struct {
+	mu    sync.Mutex
+	at    time.Time
+	total float64
+	cores []float64
+	err   error
+}
+
+// cpuUsage is the process-wide CPU sampler
+var cpuUsage cpuSampler
+
+// sample returns the total and per-core usage, reading them again once the
+// last reading is older than cpuSampleAge
+func (s *cpuSampler) sample() (float64, []float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := time.Now(); now.Sub(s.at) >= cpuSampleAge {
+		s.at = now
+		var percents []float64
+		if percents, s.err = cpu.Percent(0, false); s.err == nil && len(percents) > 0 {
+			s.total = percents[0]
+		}
+		if cores, err := cpu.Percent(0, true); err == nil {
+			s.cores = cores
+		}
+	}
+	return s.total, s.cores, s.err
+}
+
+// cpuWidget shows the CPU usage, smoothed with CPUSmoothing, with an
+// optional sparkline and per-core bars
+type cpuWidget struct {
+	cfg     Config
+	label   *colorLabel
+	graph   *sparkline
+	cores   *coreGraph
+	view    fyne.CanvasObject
+	metrics *metricsRegistry
+
+	smoothed float64
+	sampled  bool
+}
+
+// init registers the CPU widget, shown wherever gopsutil can read the usage
+func init() {
+	registerWidget("cpu", func(Config) bool { return statSupport().cpu.Available() }, func(cfg Config) (Widget, error) {
+		return newCPUWidget(cfg), nil
+	})
+}
+
+// newCPUWidget creates the widget with the graphs cfg asks for
+func newCPUWidget(cfg Config) *cpuWidget {
+	c := &cpuWidget{cfg: cfg, label: newColorLabel(cfg.prefix("cpu"))}
+	colors := func(v float64) color.Color { return cfg.colorFor("cpu", v) }
+	c.view, c.graph = statGraph(cfg, "cpu", c.label, cfg.iconSize(), 100, colors)
+	if cfg.PerCoreCPU {
+		c.cores = newCoreGraph(runtime.NumCPU(), cfg.iconSize(), colors)
+		c.view = container.NewHBox(c.view, container.NewCenter(c.cores))
+	}
+	return c
+}
+
+// Render returns the object to place in the bar
+func (c *cpuWidget) Render() fyne.CanvasObject {
+	return c.view
+}
+
+// Interval implements Widget
+func (c *cpuWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// SetMetrics implements widgetExporter
+func (c *cpuWidget) SetMetrics(r *metricsRegistry) {
+	c.metrics = r
+}
+
+// Update shows the latest sample; the exported value stays unsmoothed
+func (c *cpuWidget) Update(context.Context) {
+	total, cores, err := cpuUsage.sample()
+	if err != nil {
+		return
+	}
+	// Exponential moving average for display
+	if c.cfg.CPUSmoothing > 0 && c.sampled {
+		c.smoothed = c.cfg.CPUSmoothing*total + (1-c.cfg.CPUSmoothing)*c.smoothed
+	} else {
+		c.smoothed, c.sampled = total, true
+	}
+	c.label.SetText(c.cfg.formatCPU(c.smoothed))
+	c.label.SetColor(c.cfg.colorFor("cpu", c.smoothed))
+	if c.graph != nil {
+		c.graph.Push(c.smoothed)
+	}
+	if c.cores != nil && cores != nil {
+		c.cores.Set(cores)
+	}
+	c.metrics.set("gobar_cpu_percent", "gauge", "CPU usage over the last second.", total)
+}
+
+// OnClick runs the cpu click command, if any
+func (c *cpuWidget) OnClick(button desktop.MouseButton) {
+	if button == desktop.MouseButtonPrimary {
+		launchCommand(c.cfg.ClickCommands["cpu"])
+	}
+}
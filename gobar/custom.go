@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"log"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// customWidget shows the first line printed by a user command, re-run every
+// interval, like polybar's custom/script modules. With AllOutputs the
+// command runs once for the labels on every bar.
+type customWidget struct {
+	spec  CustomWidget
+	color color.Color // nil for the theme foreground
+	views viewSet[*colorLabel]
+}
+
+// newCustomWidget creates the widget for spec, coloured by its Color if set
+func newCustomWidget(spec CustomWidget) *customWidget {
+	c := &customWidget{spec: spec}
+	if spec.Color != "" {
+		if col, err := parseHexColor(spec.Color); err != nil {
+			log.Printf("Ignoring Color of custom widget %s: %v", spec.Name, err)
+		} else {
+			c.color = col
+		}
+	}
+	return c
+}
+
+// View returns a label to place in a bar, shown until ctx is done
+func (c *customWidget) View(ctx context.Context) fyne.CanvasObject {
+	label := newColorLabel(c.spec.Prefix)
+	if c.color != nil {
+		label.SetColor(c.color)
+	}
+	c.views.add(ctx, label)
+	return scriptArea(label, c.spec.ScriptActions)
+}
+
+// interval is how often the command runs
+func (c *customWidget) interval() time.Duration {
+	return time.Duration(c.spec.IntervalSec * float64(time.Second))
+}
+
+// Update runs the command, killing it if it outlasts its interval, and shows
+// the first line of its output. A failing command keeps the last text.
+func (c *customWidget) Update() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.spec.Command)
+	// The command runs in a process group of its own so a timeout kills what
+	// the shell started too; WaitDelay stops Output waiting on a pipe still
+	// held open by anything that escaped the group
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Custom widget %s: %v", c.spec.Name, err)
+		return
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	text := c.spec.Prefix + line
+	c.views.show(func(l *colorLabel) { l.SetText(text) })
+}
+
+// scriptArea wraps a custom widget's or plugin's label to run its actions.
+// Only the primary click is taken unless other buttons have commands, so a
+// right click still opens the bar menu.
+func scriptArea(obj fyne.CanvasObject, a ScriptActions) fyne.CanvasObject {
+	if a.RightClickCommand == "" && a.MiddleClickCommand == "" {
+		obj = newTapArea(obj, func() { launchCommand(a.ClickCommand) })
+	} else {
+		obj = newClickArea(obj, func(button desktop.MouseButton) {
+			switch button {
+			case desktop.MouseButtonPrimary:
+				launchCommand(a.ClickCommand)
+			case desktop.MouseButtonSecondary:
+				launchCommand(a.RightClickCommand)
+			case desktop.MouseButtonTertiary:
+				launchCommand(a.MiddleClickCommand)
+			}
+		})
+	}
+	if a.ScrollUpCommand == "" && a.ScrollDownCommand == "" {
+		return obj
+	}
+	return newScrollArea(obj, func(ev *fyne.ScrollEvent) {
+		switch {
+		case ev.Scrolled.DY > 0:
+			launchCommand(a.ScrollUpCommand)
+		case ev.Scrolled.DY < 0:
+			launchCommand(a.ScrollDownCommand)
+		}
+	})
+}
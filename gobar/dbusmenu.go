@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/godbus/dbus/v5"
+)
+
+// The interface tray items export their menus with, named by the item's
+// Menu property
+const dbusmenuIface = "com.canonical.dbusmenu"
+
+// dbusmenuLayout is a menu item from GetLayout: its ID, properties such as
+// "label" and "enabled", and its children, each itself a layout in a variant
+type dbusmenuLayout struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+	Children   []dbus.Variant
+}
+
+// items returns the visible children of l, dropping those that don't parse
+func (l dbusmenuLayout) items() []dbusmenuLayout {
+	var items []dbusmenuLayout
+	for _, v := range l.Children {
+		var child dbusmenuLayout
+		if v.Store(&child) != nil {
+			continue
+		}
+		if visible, ok := child.Properties["visible"].Value().(bool); ok && !visible {
+			continue
+		}
+		items = append(items, child)
+	}
+	return items
+}
+
+// property returns a string property, or "" if unset
+func (l dbusmenuLayout) property(name string) string {
+	s, _ := l.Properties[name].Value().(string)
+	return s
+}
+
+// label renders the item's label for a button: mnemonic underscores are
+// dropped, "__" stands for a literal one, and toggles show their state
+func (l dbusmenuLayout) label() string {
+	parts := strings.Split(l.property("label"), "__")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(p, "_", "")
+	}
+	label := strings.Join(parts, "_")
+	if l.property("toggle-type") != "" {
+		if state, _ := l.Properties["toggle-state"].Value().(int32); state == 1 {
+			return "✓ " + label
+		}
+		return "    " + label
+	}
+	if l.property("children-display") == "submenu" || len(l.Children) > 0 {
+		return label + " ▸"
+	}
+	return label
+}
+
+// How long a tray item may take to hand over its menu or take a click, and
+// how often the pointer buttons are polled for a click outside the menu
+const (
+	dbusmenuTimeout   = 2 * time.Second
+	dbusmenuClickPoll = 50 * time.Millisecond
+)
+
+// fetchMenuLayout asks the application to fill in submenu id, 0 for the
+// root, and reads its items
+func fetchMenuLayout(conn *dbus.Conn, bus string, path dbus.ObjectPath, id int32) (dbusmenuLayout, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbusmenuTimeout)
+	defer cancel()
+	obj := conn.Object(bus, path)
+	// Lets the application fill the menu in; failures are harmless
+	obj.CallWithContext(ctx, dbusmenuIface+".AboutToShow", 0, id)
+	var revision uint32
+	var layout dbusmenuLayout
+	err := obj.CallWithContext(ctx, dbusmenuIface+".GetLayout", 0, id, int32(1), []string{}).Store(&revision, &layout)
+	return layout, err
+}
+
+// trayItemMenu shows a tray item's dbusmenu in a borderless
+// override-redirect window beside the pointer, like the tooltip, since the
+// bar is too short for a popup menu. Submenus replace the shown items, with
+// a Back button; the menu closes when an item is clicked or on a click
+// outside it. The items are fetched in the background, so a slow
+// application never blocks the bar.
+type trayItemMenu struct {
+	mu   sync.Mutex
+	win  fyne.Window
+	box  *fyne.Container
+	X    *xgb.Conn
+	root xproto.Window
+	xid  xproto.Window
+
+	conn     *dbus.Conn
+	bus      string
+	path     dbus.ObjectPath
+	parent   []int32 // the submenus entered, innermost last
+	shown    bool
+	rect     screenRect // where the menu is mapped, in root coordinates
+	request  int        // bumped by each load, so only the latest is shown
+	opened   int        // bumped each time the menu opens, ending the last click watch
+	closedAt time.Time  // when a click outside last closed the menu
+}
+
+// Show opens the menu exported at path by bus, or closes it if it is
+// already open for that item
+func (m *trayItemMenu) Show(conn *dbus.Conn, bus string, path dbus.ObjectPath) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	same := m.bus == bus && m.path == path
+	// The click on the item that closed the menu must not reopen it
+	if same && (m.shown || time.Since(m.closedAt) < 4*dbusmenuClickPoll) {
+		m.hide()
+		return
+	}
+	if m.win == nil && !m.create() {
+		return
+	}
+	m.hide()
+	m.conn, m.bus, m.path = conn, bus, path
+	m.load(nil, nil)
+}
+
+// Hide closes the menu
+func (m *trayItemMenu) Hide() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hide()
+}
+
+// create builds the menu window; the X11 setup finishes asynchronously as
+// in tooltipManager.create. Callers hold mu.
+func (m *trayItemMenu) create() bool {
+	drv, ok := fyne.CurrentApp().Driver().(desktop.Driver)
+	if !ok {
+		return false
+	}
+	X, err := barConn()
+	if err != nil {
+		return false
+	}
+	m.X, m.root = X, xproto.Setup(X).DefaultScreen(X).Root
+	m.win = drv.CreateSplashWindow()
+	m.box = container.NewVBox()
+	m.win.SetContent(m.box)
+	m.win.Show()
+	go func() {
+		id, ok := x11WindowID(m.win, 5*time.Second)
+		if !ok {
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		win := xproto.Window(id)
+		xproto.UnmapWindow(m.X, win)
+		xproto.ChangeWindowAttributes(m.X, win, xproto.CwOverrideRedirect, []uint32{1})
+		m.xid = win
+		if m.shown {
+			m.place()
+		}
+	}()
+	return true
+}
+
+// load fetches the submenu innermost in parent, or the root menu, in the
+// background and shows it. When it has no items, empty is called instead,
+// if set; a menu opened or entered meanwhile wins. Callers hold mu.
+func (m *trayItemMenu) load(parent []int32, empty func()) {
+	m.request++
+	request, conn, bus, path := m.request, m.conn, m.bus, m.path
+	var id int32
+	if len(parent) > 0 {
+		id = parent[len(parent)-1]
+	}
+	go func() {
+		layout, err := fetchMenuLayout(conn, bus, path, id)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if request != m.request {
+			return
+		}
+		if err != nil {
+			log.Println("Failed to read the tray item's menu:", err)
+			return
+		}
+		if !m.render(layout, parent) {
+			if empty != nil {
+				empty()
+			}
+			return
+		}
+		if m.shown {
+			// A submenu opens where the menu already is
+			m.rect.width, m.rect.height = m.pixelSize()
+			return
+		}
+		m.shown = true
+		m.opened++
+		go m.watchClicks(m.opened)
+		m.place()
+	}()
+}
+
+// render lays out the items of layout, the submenu innermost in parent,
+// reporting whether there is anything to show; callers hold mu
+func (m *trayItemMenu) render(layout dbusmenuLayout, parent []int32) bool {
+	var rows []fyne.CanvasObject
+	if len(parent) > 0 {
+		back := widget.NewButton("◀ Back", func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.load(m.parent[:len(m.parent)-1], nil)
+		})
+		back.Importance = widget.LowImportance
+		back.Alignment = widget.ButtonAlignLeading
+		rows = append(rows, back, widget.NewSeparator())
+	}
+	for _, item := range layout.items() {
+		if item.property("type") == "separator" {
+			rows = append(rows, widget.NewSeparator())
+			continue
+		}
+		item := item
+		b := widget.NewButton(item.label(), func() { m.choose(item) })
+		b.Importance = widget.LowImportance
+		b.Alignment = widget.ButtonAlignLeading
+		if enabled, ok := item.Properties["enabled"].Value().(bool); ok && !enabled {
+			b.Disable()
+		}
+		rows = append(rows, b)
+	}
+	if len(rows) == 0 {
+		return false
+	}
+	m.parent = parent
+	m.box.Objects = rows
+	m.box.Refresh()
+	m.win.Resize(m.box.MinSize())
+	return true
+}
+
+// choose opens a submenu, or sends a click to the application and closes
+// the menu; an empty submenu takes the click itself
+func (m *trayItemMenu) choose(item dbusmenuLayout) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	click := func() {
+		go clickMenuItem(m.conn, m.bus, m.path, item.ID)
+		m.hide()
+	}
+	if item.property("children-display") == "submenu" || len(item.Children) > 0 {
+		m.load(append(slices.Clone(m.parent), item.ID), click)
+		return
+	}
+	click()
+}
+
+// clickMenuItem tells the application that item id was clicked
+func clickMenuItem(conn *dbus.Conn, bus string, path dbus.ObjectPath, id int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbusmenuTimeout)
+	defer cancel()
+	obj := conn.Object(bus, path)
+	if call := obj.CallWithContext(ctx, dbusmenuIface+".Event", 0, id, "clicked", dbus.MakeVariant(int32(0)), uint32(time.Now().Unix())); call.Err != nil {
+		log.Println("Tray item menu click failed:", call.Err)
+	}
+}
+
+// watchClicks closes the menu opened as opened on a press outside it. The
+// bar's X connection sees no clicks on other clients' windows, and a pointer
+// grab would take the clicks from the menu's own buttons too, so it polls
+// the pointer buttons while the menu is open.
+func (m *trayItemMenu) watchClicks(opened int) {
+	ticker := time.NewTicker(dbusmenuClickPoll)
+	defer ticker.Stop()
+	pressed := true // a button held from the click that opened the menu
+	for range ticker.C {
+		m.mu.Lock()
+		if !m.shown || m.opened != opened {
+			m.mu.Unlock()
+			return
+		}
+		X, root, rect := m.X, m.root, m.rect
+		m.mu.Unlock()
+		pointer, err := xproto.QueryPointer(X, root).Reply()
+		if err != nil {
+			continue
+		}
+		down := pointer.Mask&(xproto.KeyButMaskButton1|xproto.KeyButMaskButton2|xproto.KeyButMaskButton3) != 0
+		x, y := int(pointer.RootX), int(pointer.RootY)
+		inside := x >= rect.x && x < rect.x+rect.width && y >= rect.y && y < rect.y+rect.height
+		if down && !pressed && !inside {
+			m.mu.Lock()
+			if m.opened == opened {
+				m.hide()
+				m.closedAt = time.Now()
+			}
+			m.mu.Unlock()
+			return
+		}
+		pressed = down
+	}
+}
+
+// place moves the menu beside the pointer, keeping it on screen, and maps
+// it; callers hold mu
+func (m *trayItemMenu) place() {
+	if m.xid == 0 {
+		return
+	}
+	screen := xproto.Setup(m.X).DefaultScreen(m.X)
+	pointer, err := xproto.QueryPointer(m.X, screen.Root).Reply()
+	if err != nil {
+		return
+	}
+	w, h := m.pixelSize()
+	x, y := int(pointer.RootX), int(pointer.RootY)+10
+	x = max(min(x, int(screen.WidthInPixels)-w), 0)
+	if y+h > int(screen.HeightInPixels) {
+		// Bottom bar: open upwards
+		y = int(pointer.RootY) - h - 10
+	}
+	m.rect = screenRect{x: x, y: y, width: w, height: h}
+	xproto.ConfigureWindow(m.X, m.xid, xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(int32(x)), uint32(int32(y)), xproto.StackModeAbove})
+	xproto.MapWindow(m.X, m.xid)
+	m.X.Sync()
+}
+
+// pixelSize is the size of the menu's items on screen; callers hold mu
+func (m *trayItemMenu) pixelSize() (int, int) {
+	scale := m.win.Canvas().Scale()
+	size := m.box.MinSize()
+	return int(size.Width * scale), int(size.Height * scale)
+}
+
+// hide unmaps the menu; callers hold mu
+func (m *trayItemMenu) hide() {
+	m.shown = false
+	if m.xid != 0 {
+		xproto.UnmapWindow(m.X, m.xid)
+		m.X.Sync()
+	}
+}
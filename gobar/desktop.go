@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DesktopEntry is an application parsed from a .desktop file
+type DesktopEntry struct {
+	Name string
+	Exec string
+	// Terminal=true: the program needs to run inside a terminal
+	Terminal bool
+	// Path= working directory; empty means $HOME
+	Path string
+	// Icon= theme icon name or absolute path
+	Icon string
+	// Categories= menu categories, e.g. Network and WebBrowser
+	Categories []string
+	// TryExec= program that must be installed for the entry to be shown
+	TryExec string
+}
+
+// applicationDirs lists the XDG application directories by precedence:
+// $XDG_DATA_HOME (~/.local/share), then each of $XDG_DATA_DIRS
+// (/usr/local/share:/usr/share), followed by the Flatpak exports
+func applicationDirs() []string {
+	var dirs []string
+	for _, d := range dataDirs() {
+		dirs = append(dirs, filepath.Join(d, "applications"))
+	}
+	return dirs
+}
+
+// dataDirs lists the XDG data directories by precedence, as applicationDirs
+// describes
+func dataDirs() []string {
+	home, _ := os.UserHomeDir()
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	var dirs []string
+	for _, d := range append([]string{dataHome}, filepath.SplitList(dataDirs)...) {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	for _, d := range []string{filepath.Join(dataHome, "flatpak"), "/var/lib/flatpak"} {
+		dirs = append(dirs, filepath.Join(d, "exports", "share"))
+	}
+	return slices.Compact(dirs)
+}
+
+// scanApplications gets the .desktop applications of one directory; see
+// scanApplicationDirs
+func scanApplications(dir string, hidden []string) ([]DesktopEntry, error) {
+	return scanApplicationDirs([]string{dir}, hidden)
+}
+
+// scanApplicationDirs gets the .desktop applications of dirs, sorted by
+// Name. Entries are told apart by their desktop-file ID, the path below the
+// directory with "/" turned into "-" (kde/konsole.desktop is kde-konsole),
+// and one in an earlier directory overrides one with the same ID in a later
+// one, so user copies win over system entries. Directories that don't exist
+// are skipped; it fails only when none could be read. Files are read and
+// parsed by a pool of GOMAXPROCS workers. Entries whose desktop-file ID or
+// Name contains one of hidden, ignoring case, are left out.
+func scanApplicationDirs(dirs []string, hidden []string) ([]DesktopEntry, error) {
+	var apps []DesktopEntry
+	var paths, ids []string
+	seen := map[string]bool{}
+	var errs []error
+	read := 0
+	for _, dir := range dirs {
+		// WalkDir doesn't follow a symlinked root, common with Nix profiles
+		root, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					if path == root {
+						return err
+					}
+					// Skip an unreadable subdirectory rather than the whole tree
+					return nil
+				}
+				if d.IsDir() || !strings.HasSuffix(path, ".desktop") {
+					return nil
+				}
+				rel, _ := filepath.Rel(root, path)
+				id := strings.TrimSuffix(strings.ReplaceAll(filepath.ToSlash(rel), "/", "-"), ".desktop")
+				if !seen[id] {
+					seen[id] = true
+					paths, ids = append(paths, path), append(ids, id)
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		read++
+	}
+	if read == 0 && len(errs) > 0 {
+		return apps, errors.Join(errs...)
+	}
+
+	type result struct {
+		entry DesktopEntry
+		ok    bool
+	}
+	results := make([]result, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				content, err := os.ReadFile(paths[i])
+				if err != nil {
+					continue
+				}
+				entry, ok := parseDesktopEntry(string(content))
+				results[i] = result{entry, ok}
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i, r := range results {
+		if r.ok && r.entry.installed() && !hiddenApp(hidden, ids[i], r.entry.Name) {
+			apps = append(apps, r.entry)
+		}
+	}
+	sort.SliceStable(apps, func(i, j int) bool {
+		return strings.ToLower(apps[i].Name) < strings.ToLower(apps[j].Name)
+	})
+	return apps, nil
+}
+
+// hiddenApp reports whether any of hidden is a case-insensitive substring of
+// the entry's desktop-file ID or Name
+func hiddenApp(hidden []string, id, name string) bool {
+	id, name = strings.ToLower(id), strings.ToLower(name)
+	for _, h := range hidden {
+		h = strings.ToLower(h)
+		if h != "" && (strings.Contains(id, h) || strings.Contains(name, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDesktopEntry reads the keys of the main [Desktop Entry] group,
+// ignoring action groups that carry their own Name and Exec. Entries without
+// a Name, marked NoDisplay or Hidden, or of a Type other than Application
+// (Link, Directory) are not shown in menus.
+func parseDesktopEntry(content string) (DesktopEntry, bool) {
+	var entry DesktopEntry
+	hidden := false
+	inMain := true
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inMain = line == "[Desktop Entry]"
+			continue
+		}
+		if !inMain {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "Name=") && entry.Name == "":
+			entry.Name = strings.TrimPrefix(line, "Name=")
+		case strings.HasPrefix(line, "Exec=") && entry.Exec == "":
+			entry.Exec = strings.TrimPrefix(line, "Exec=")
+		case strings.HasPrefix(line, "Path=") && entry.Path == "":
+			entry.Path = strings.TrimPrefix(line, "Path=")
+		case strings.HasPrefix(line, "Icon=") && entry.Icon == "":
+			entry.Icon = strings.TrimPrefix(line, "Icon=")
+		case strings.HasPrefix(line, "Categories=") && entry.Categories == nil:
+			entry.Categories = strings.FieldsFunc(strings.TrimPrefix(line, "Categories="), func(r rune) bool { return r == ';' })
+		case strings.HasPrefix(line, "TryExec=") && entry.TryExec == "":
+			entry.TryExec = strings.TrimPrefix(line, "TryExec=")
+		case line == "Terminal=true":
+			entry.Terminal = true
+		case line == "NoDisplay=true" || line == "Hidden=true":
+			hidden = true
+		case strings.HasPrefix(line, "Type=") && line != "Type=Application":
+			hidden = true
+		}
+	}
+	return entry, entry.Name != "" && !hidden
+}
+
+// installed reports whether the entry's TryExec program, if any, exists: an
+// absolute path must be executable, a bare name must be found in $PATH
+func (e DesktopEntry) installed() bool {
+	if e.TryExec == "" {
+		return true
+	}
+	_, err := exec.LookPath(e.TryExec)
+	return err == nil
+}
+
+// match scores how well query matches the entry for the search box, higher
+// being better. Every query character must appear in the Name in order,
+// ignoring case, so "ffx" finds Firefox; consecutive characters and matches at
+// word starts score more. A query that misses the Name may still match a
+// category, with the lowest score. An empty query matches everything.
+func (e DesktopEntry) match(query string) (int, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0, true
+	}
+	if score, ok := fuzzyScore(query, strings.ToLower(e.Name)); ok {
+		return score + 1, true
+	}
+	for _, c := range e.Categories {
+		if strings.Contains(strings.ToLower(c), query) {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// fuzzyScore matches the characters of query in order within text, both
+// lowercase. Each match scores a point, plus two when it follows the previous
+// match directly and one at the start of a word.
+func fuzzyScore(query, text string) (int, bool) {
+	score, last := 0, -2
+	runes := []rune(text)
+	j := 0
+	for _, q := range query {
+		for j < len(runes) && runes[j] != q {
+			j++
+		}
+		if j == len(runes) {
+			return 0, false
+		}
+		score++
+		if j == last+1 {
+			score += 2
+		}
+		if j == 0 || runes[j-1] == ' ' || runes[j-1] == '-' {
+			score++
+		}
+		last = j
+		j++
+	}
+	return score, true
+}
+
+// command is the shell command line starting the entry, with field codes
+// removed and wrapped in the configured terminal for Terminal=true
+func (e DesktopEntry) command(cfg Config) string {
+	args := execArgs(e.Exec)
+	for i, arg := range args {
+		args[i] = shellWord(arg)
+	}
+	cmdline := strings.Join(args, " ")
+	if e.Terminal && cmdline != "" {
+		cmdline = cfg.terminal() + " -e " + cmdline
+	}
+	return cmdline
+}
+
+// workDir is the directory the entry runs in: Path= if set, else $HOME
+func (e DesktopEntry) workDir() string {
+	if e.Path != "" {
+		return e.Path
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// launchEntry starts a desktop entry in its working directory
+func launchEntry(cfg Config, e DesktopEntry) {
+	launchCommandIn(e.command(cfg), e.workDir())
+}
+
+// execArgs splits an Exec value into arguments as the desktop entry spec
+// describes: arguments are separated by spaces, a double-quoted argument may
+// contain spaces and backslash-escaped ", `, $ and \, and "%%" is a literal
+// percent sign. Field codes (%f, %F, %u, %U, %i, %c, %k and the deprecated
+// ones) are removed, and an argument that was only a field code is dropped,
+// since the menu launches without files or URLs. An unterminated quote runs
+// to the end of the line.
+func execArgs(exec string) []string {
+	var args []string
+	var arg strings.Builder
+	inArg, quoted, onlyCodes := false, false, true
+	end := func() {
+		if inArg && (!onlyCodes || arg.Len() > 0) {
+			args = append(args, arg.String())
+		}
+		arg.Reset()
+		inArg, onlyCodes = false, true
+	}
+	for i := 0; i < len(exec); i++ {
+		c := exec[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(exec) && strings.IndexByte("\"`$\\", exec[i+1]) >= 0:
+			i++
+			arg.WriteByte(exec[i])
+			onlyCodes = false
+		case c == '"':
+			quoted = !quoted
+			inArg = true
+			onlyCodes = false
+		case !quoted && (c == ' ' || c == '\t'):
+			end()
+		case c == '%' && i+1 < len(exec):
+			i++
+			inArg = true
+			if exec[i] == '%' {
+				arg.WriteByte('%')
+				onlyCodes = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg, onlyCodes = true, false
+		}
+	}
+	end()
+	return args
+}
+
+// shellWord quotes arg for sh unless it consists of characters sh leaves alone
+func shellWord(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return arg
+	}
+	return shellQuote(arg)
+}
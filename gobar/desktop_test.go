@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanApplications(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		hidden []string
+		want   []DesktopEntry
+	}{
+		{
+			name: "valid entry",
+			files: map[string]string{
+				"firefox.desktop": "[Desktop Entry]\nName=Firefox\nExec=firefox %u\n",
+			},
+			want: []DesktopEntry{{Name: "Firefox", Exec: "firefox %u"}},
+		},
+		{
+			name: "NoDisplay is skipped",
+			files: map[string]string{
+				"helper.desktop": "[Desktop Entry]\nName=Helper\nExec=helper\nNoDisplay=true\n",
+			},
+			want: nil,
+		},
+		{
+			name: "non-Application types are skipped",
+			files: map[string]string{
+				"site.desktop": "[Desktop Entry]\nType=Link\nName=Site\nURL=https://example.com\n",
+				"app.desktop":  "[Desktop Entry]\nType=Application\nName=App\nExec=app\n",
+			},
+			want: []DesktopEntry{{Name: "App", Exec: "app"}},
+		},
+		{
+			name: "missing Name is skipped",
+			files: map[string]string{
+				"noname.desktop": "[Desktop Entry]\nExec=noname\n",
+			},
+			want: nil,
+		},
+		{
+			name: "localized names keep the default Name",
+			files: map[string]string{
+				"files.desktop": "[Desktop Entry]\nName[de]=Dateien\nName=Files\nName[fr]=Fichiers\nExec=nautilus\n",
+			},
+			want: []DesktopEntry{{Name: "Files", Exec: "nautilus"}},
+		},
+		{
+			name: "action groups do not override the main entry",
+			files: map[string]string{
+				"term.desktop": "[Desktop Entry]\nName=Terminal\nExec=xterm\n\n[Desktop Action new]\nName=New Window\nExec=xterm -new\n",
+			},
+			want: []DesktopEntry{{Name: "Terminal", Exec: "xterm"}},
+		},
+		{
+			name: "Terminal and Path are read",
+			files: map[string]string{
+				"htop.desktop": "[Desktop Entry]\nName=Htop\nExec=htop\nTerminal=true\nPath=/tmp\n",
+			},
+			want: []DesktopEntry{{Name: "Htop", Exec: "htop", Terminal: true, Path: "/tmp"}},
+		},
+		{
+			name: "Icon and Categories are read",
+			files: map[string]string{
+				"firefox.desktop": "[Desktop Entry]\nName=Firefox\nExec=firefox %u\nIcon=firefox\nCategories=Network;WebBrowser;\n",
+			},
+			want: []DesktopEntry{{Name: "Firefox", Exec: "firefox %u", Icon: "firefox", Categories: []string{"Network", "WebBrowser"}}},
+		},
+		{
+			name: "TryExec must be installed",
+			files: map[string]string{
+				"gone.desktop": "[Desktop Entry]\nName=Gone\nExec=gone\nTryExec=/nonexistent/gobar-test-binary\n",
+				"sh.desktop":   "[Desktop Entry]\nName=Shell\nExec=sh\nTryExec=sh\n",
+			},
+			want: []DesktopEntry{{Name: "Shell", Exec: "sh", TryExec: "sh"}},
+		},
+		{
+			name: "hidden apps match ID or Name ignoring case",
+			files: map[string]string{
+				"org.example.Uninstall.desktop": "[Desktop Entry]\nName=Remove Example\nExec=uninstall\n",
+				"helper.desktop":                "[Desktop Entry]\nName=Internal Helper\nExec=helper\n",
+				"editor.desktop":                "[Desktop Entry]\nName=Editor\nExec=vim\n",
+			},
+			hidden: []string{"uninstall", "INTERNAL"},
+			want:   []DesktopEntry{{Name: "Editor", Exec: "vim"}},
+		},
+		{
+			name: "non-desktop files are ignored",
+			files: map[string]string{
+				"readme.txt":      "[Desktop Entry]\nName=Readme\nExec=cat\n",
+				"mimeinfo.cache":  "[MIME Cache]\n",
+				"editor.desktop":  "[Desktop Entry]\nName=Editor\nExec=vim\n",
+				"subdir.desktop/": "",
+			},
+			want: []DesktopEntry{{Name: "Editor", Exec: "vim"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if strings.HasSuffix(name, "/") {
+					if err := os.Mkdir(path, 0o755); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := scanApplications(dir, tt.hidden)
+			if err != nil {
+				t.Fatalf("scanApplications: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanApplications = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanApplicationDirs(t *testing.T) {
+	user, system := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(user, "editor.desktop"):    "[Desktop Entry]\nName=Editor\nExec=vim -u NONE\n",
+		filepath.Join(system, "editor.desktop"):  "[Desktop Entry]\nName=Editor\nExec=vim\n",
+		filepath.Join(system, "browser.desktop"): "[Desktop Entry]\nName=browser\nExec=firefox\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	got, err := scanApplicationDirs([]string{user, missing, system}, nil)
+	if err != nil {
+		t.Fatalf("scanApplicationDirs: %v", err)
+	}
+	want := []DesktopEntry{{Name: "browser", Exec: "firefox"}, {Name: "Editor", Exec: "vim -u NONE"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanApplicationDirs = %#v, want %#v", got, want)
+	}
+}
+
+func TestScanApplicationDirsSubdirectories(t *testing.T) {
+	user, system := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(user, "kde-konsole.desktop"):      "[Desktop Entry]\nName=Konsole\nExec=konsole --profile mine\n",
+		filepath.Join(system, "kde", "konsole.desktop"): "[Desktop Entry]\nName=Konsole\nExec=konsole\n",
+		filepath.Join(system, "kde", "kate.desktop"):    "[Desktop Entry]\nName=Kate\nExec=kate\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The user's kde-konsole overrides kde/konsole, which has the same ID
+	got, err := scanApplicationDirs([]string{user, system}, []string{"kde-kate"})
+	if err != nil {
+		t.Fatalf("scanApplicationDirs: %v", err)
+	}
+	want := []DesktopEntry{{Name: "Konsole", Exec: "konsole --profile mine"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanApplicationDirs = %#v, want %#v", got, want)
+	}
+}
+
+func TestScanApplicationsMissingDir(t *testing.T) {
+	if _, err := scanApplications(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	tests := []struct {
+		exec string
+		want []string
+	}{
+		{"firefox %u", []string{"firefox"}},
+		{"gimp-2.10 %U --new-instance", []string{"gimp-2.10", "--new-instance"}},
+		{`sh -c "foo %U"`, []string{"sh", "-c", "foo "}},
+		{`"/opt/My App/run" --flag`, []string{"/opt/My App/run", "--flag"}},
+		{"echo \"say \\\"hi\\\" for \\$5 \\\\ \\`ok\\`\"", []string{"echo", "say \"hi\" for $5 \\ `ok`"}},
+		{"printf 100%%", []string{"printf", "100%"}},
+		{"app --icon=%i %c %k", []string{"app", "--icon="}},
+		{`app ""`, []string{"app", ""}},
+		{`app  "unterminated arg`, []string{"app", "unterminated arg"}},
+	}
+	for _, tt := range tests {
+		if got := execArgs(tt.exec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("execArgs(%q) = %q, want %q", tt.exec, got, tt.want)
+		}
+	}
+}
+
+func TestDesktopEntryCommand(t *testing.T) {
+	e := DesktopEntry{Exec: `sh -c "notify-send 'it works' %U"`}
+	if got, want := e.command(Config{}), `sh -c 'notify-send '\''it works'\'' '`; got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+func TestDesktopEntryMatch(t *testing.T) {
+	firefox := DesktopEntry{Name: "Firefox Web Browser", Categories: []string{"Network", "WebBrowser"}}
+	tests := []struct {
+		query string
+		ok    bool
+	}{
+		{"", true},
+		{"fire", true},
+		{"ffx", true},
+		{"FWB", true},
+		{"network", true},
+		{"xf", false},
+		{"chrome", false},
+	}
+	for _, tt := range tests {
+		if _, ok := firefox.match(tt.query); ok != tt.ok {
+			t.Errorf("match(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+		}
+	}
+
+	// Consecutive and word-start matches rank first
+	prefix, _ := firefox.match("fire")
+	scattered, _ := firefox.match("fier")
+	category, _ := firefox.match("network")
+	if !(prefix > scattered && scattered > category) {
+		t.Errorf("scores fire=%d fier=%d network=%d, want decreasing", prefix, scattered, category)
+	}
+}
+
+func BenchmarkScanApplications(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 800; i++ {
+		content := fmt.Sprintf("[Desktop Entry]\nType=Application\nName=App %d\nName[de]=Anwendung %d\nComment=Benchmark fixture\nExec=app%d %%U\nIcon=app%d\nCategories=Utility;\n", i, i, i, i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("app%d.desktop", i)), []byte(content), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := scanApplications(dir, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
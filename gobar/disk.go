@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskInterval is how often disk usage is read; it changes slowly
+const diskInterval = 30 * time.Second
+
+// diskWidget shows the used share of each configured mount point, e.g.
+// "Disk: / 45% /home 71%", coloured by the fullest one, with the sizes in
+// a tooltip
+type diskWidget struct {
+	cfg     Config
+	label   *colorLabel
+	tooltip *tooltipArea
+	metrics *metricsRegistry
+}
+
+// init registers the disk usage widget
+func init() {
+	registerWidget("disk", func(cfg Config) bool { return cfg.ShowDisk }, func(cfg Config) (Widget, error) {
+		return newDiskWidget(cfg), nil
+	})
+}
+
+// newDiskWidget creates the widget for cfg.DiskMounts, hidden until read
+func newDiskWidget(cfg Config) *diskWidget {
+	d := &diskWidget{cfg: cfg, label: newColorLabel("")}
+	d.tooltip = newTooltipArea(d.label)
+	d.tooltip.Hide()
+	return d
+}
+
+// Render returns the object to place in the bar
+func (d *diskWidget) Render() fyne.CanvasObject {
+	return d.tooltip
+}
+
+// Interval implements Widget
+func (d *diskWidget) Interval() time.Duration {
+	return diskInterval
+}
+
+// SetMetrics implements widgetExporter
+func (d *diskWidget) SetMetrics(r *metricsRegistry) {
+	d.metrics = r
+}
+
+// Update reads the usage of each mount point, skipping those that fail, e.g.
+// an unplugged drive; the widget hides while none can be read
+func (d *diskWidget) Update(ctx context.Context) {
+	var parts, details []string
+	fullest := -1.0
+	for _, mount := range d.cfg.DiskMounts {
+		usage, err := disk.UsageWithContext(ctx, mount)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, mount+" "+d.cfg.percent(usage.UsedPercent))
+		details = append(details, fmt.Sprintf("%s: %s of %s used", mount, formatBytes(float64(usage.Used)), formatBytes(float64(usage.Total))))
+		fullest = max(fullest, usage.UsedPercent)
+	}
+	if len(parts) == 0 {
+		d.tooltip.Hide()
+		return
+	}
+	d.metrics.set("gobar_disk_used_percent", "gauge", "Used share of the fullest configured mount point.", fullest)
+	d.label.SetText(d.cfg.prefix("disk") + strings.Join(parts, " "))
+	d.label.SetColor(d.cfg.colorFor("disk", fullest))
+	d.tooltip.SetTooltip(strings.Join(details, "\n"))
+	d.tooltip.Show()
+}
+
+// OnClick runs the disk click command, if any
+func (d *diskWidget) OnClick(button desktop.MouseButton) {
+	if button == desktop.MouseButtonPrimary {
+		launchCommand(d.cfg.ClickCommands["disk"])
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// displayModeWidget shows the mode of one RandR output, e.g. "2560x1440@144".
+// It follows RandR's screen, CRTC and output change events, so a mode switch
+// shows up without polling.
+type displayModeWidget struct {
+	X      *xgb.Conn
+	events *xWatch
+	root   xproto.Window
+	output string // empty for the primary output
+	prefix string
+	label  *widget.Label
+	area   *tapArea
+}
+
+// newDisplayModeWidget watches X for RandR change events. output is
+// Config.Output; clicking runs command when it is set.
+func newDisplayModeWidget(output, prefix, command string) (*displayModeWidget, error) {
+	if _, err := barExtension("RandR", randr.Init); err != nil {
+		return nil, err
+	}
+	events, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	X := events.X
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	mask := randr.NotifyMaskScreenChange | randr.NotifyMaskCrtcChange | randr.NotifyMaskOutputChange
+	if err := events.SelectRandr(root, uint16(mask)); err != nil {
+		events.Close()
+		return nil, err
+	}
+	d := &displayModeWidget{X: X, events: events, root: root, output: output, prefix: prefix, label: widget.NewLabel(prefix)}
+	d.area = newTapArea(d.label, func() {
+		if command != "" {
+			launchCommand(command)
+		}
+	})
+	return d, nil
+}
+
+// CanvasObject returns the object to place in the bar
+func (d *displayModeWidget) CanvasObject() fyne.CanvasObject {
+	return d.area
+}
+
+// Run shows the current mode, then rereads it on every RandR event until
+// Close or the X connection closes; call it in its own goroutine
+func (d *displayModeWidget) Run() {
+	d.update()
+	for {
+		ev, ok := d.events.Next()
+		if !ok {
+			return
+		}
+		switch ev.(type) {
+		case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+			d.update()
+		}
+	}
+}
+
+// Close stops Run
+func (d *displayModeWidget) Close() {
+	d.events.Close()
+}
+
+// update shows the mode, or hides the widget when the output shows nothing
+func (d *displayModeWidget) update() {
+	mode, err := d.mode()
+	if err != nil {
+		d.area.Hide()
+		return
+	}
+	setLabelText(d.label, d.prefix+mode)
+	d.area.Show()
+}
+
+// mode formats the current mode of the configured output, falling back to
+// the primary output and then the first active one
+func (d *displayModeWidget) mode() (string, error) {
+	resources, err := randr.GetScreenResourcesCurrent(d.X, d.root).Reply()
+	if err != nil {
+		return "", err
+	}
+	var primary randr.Output
+	if d.output == "" {
+		if reply, err := randr.GetOutputPrimary(d.X, d.root).Reply(); err == nil {
+			primary = reply.Output
+		}
+	}
+	var crtc randr.Crtc
+	for _, output := range resources.Outputs {
+		info, err := randr.GetOutputInfo(d.X, output, resources.ConfigTimestamp).Reply()
+		if err != nil || info.Crtc == 0 {
+			continue
+		}
+		if d.output != "" && string(info.Name) == d.output || d.output == "" && output == primary {
+			crtc = info.Crtc
+			break
+		}
+		if crtc == 0 && d.output == "" {
+			crtc = info.Crtc
+		}
+	}
+	if crtc == 0 {
+		return "", fmt.Errorf("no active output")
+	}
+	info, err := randr.GetCrtcInfo(d.X, crtc, resources.ConfigTimestamp).Reply()
+	if err != nil {
+		return "", err
+	}
+	for _, m := range resources.Modes {
+		if randr.Mode(m.Id) == info.Mode {
+			return fmt.Sprintf("%dx%d@%d", info.Width, info.Height, int(math.Round(refreshRate(m)))), nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %d", info.Mode)
+}
+
+// refreshRate is a mode's vertical refresh rate in Hz
+func refreshRate(m randr.ModeInfo) float64 {
+	vtotal := float64(m.Vtotal)
+	if m.ModeFlags&randr.ModeFlagDoubleScan != 0 {
+		vtotal *= 2
+	}
+	if m.ModeFlags&randr.ModeFlagInterlace != 0 {
+		vtotal /= 2
+	}
+	if m.Htotal == 0 || vtotal == 0 {
+		return 0
+	}
+	return float64(m.DotClock) / (float64(m.Htotal) * vtotal)
+}
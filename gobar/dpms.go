@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/BurntSushi/xgb/dpms"
+)
+
+// screenOffDelay lets the click's pointer motion settle; otherwise the
+// display wakes straight back up
+const screenOffDelay = 500 * time.Millisecond
+
+// forceDPMSOff blanks the displays through the X DPMS extension
+func forceDPMSOff() error {
+	X, err := barExtension("DPMS", dpms.Init)
+	if err != nil {
+		return err
+	}
+	capable, err := dpms.Capable(X).Reply()
+	if err != nil {
+		return err
+	}
+	if !capable.Capable {
+		return errors.New("display does not support DPMS")
+	}
+	// ForceLevel is ignored while DPMS is disabled
+	if err := dpms.EnableChecked(X).Check(); err != nil {
+		return err
+	}
+	return dpms.ForceLevelChecked(X, dpms.DPMSModeOff).Check()
+}
+
+// screenOff optionally locks the session and then turns the displays off,
+// using the configured command or native DPMS when none is set
+func screenOff(cfg Config) {
+	if cfg.LockBeforeScreenOff && cfg.LockCommand != "" {
+		// Lockers usually keep running until unlocked, so don't wait for them
+		launchCommand(cfg.LockCommand)
+	}
+	time.Sleep(screenOffDelay)
+	if cfg.ScreenOffCommand != "" {
+		if out, err := exec.Command("sh", "-c", cfg.ScreenOffCommand).CombinedOutput(); err != nil {
+			log.Printf("Screen off command failed: %v: %s", err, out)
+		}
+		return
+	}
+	if err := forceDPMSOff(); err != nil {
+		log.Println("Failed to turn screens off:", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Widget formatters. Compact mode (see Config.Compact) uses terse forms for
+// narrow screens; prefix already drops the text labels in that mode.
+
+// formatPercent renders a percentage rounded to precision decimals, e.g. "34%"
+func formatPercent(percent float64, precision int) string {
+	return fmt.Sprintf("%.*f%%", precision, percent)
+}
+
+// figureSpace is as wide as a digit in most fonts, unlike a normal space
+const figureSpace = '\u2007'
+
+// Padded rate widths, those of the widest rates: one decimal below 1024 of
+// the largest unit, "1023.9M" compact and "1023.9 MB/s" otherwise
+var (
+	terseRateWidth = utf8.RuneCountInString(terseRate(1023.9*(1<<30), "bytes"))
+	rateWidth      = utf8.RuneCountInString(formatRate(1023.9*(1<<30), "bytes"))
+)
+
+// padFigures right-aligns s to width runes with figure spaces, so a number
+// keeps its width as it changes, e.g. " 11%" and "100%"
+func padFigures(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(string(figureSpace), width-n) + s
+	}
+	return s
+}
+
+// percent formats a percentage at the configured precision, padded to the
+// width of 100% with FixedWidthNumbers
+func (c Config) percent(v float64) string {
+	s := formatPercent(v, c.percentPrecision())
+	if c.FixedWidthNumbers {
+		return padFigures(s, utf8.RuneCountInString(formatPercent(100, c.percentPrecision())))
+	}
+	return s
+}
+
+// percentPrecision is the configured Precision, or whole numbers in compact mode
+func (c Config) percentPrecision() int {
+	if c.Compact {
+		return 0
+	}
+	return c.Precision
+}
+
+// formatCPU renders the CPU widget, e.g. "CPU: 34%"
+func (c Config) formatCPU(percent float64) string {
+	return c.prefix("cpu") + c.percent(percent)
+}
+
+// formatRAM renders the memory widget with used and total memory in the
+// total's unit, e.g. "RAM: 7.1/16.0 GB (44%)", or just "44%" in compact mode
+func (c Config) formatRAM(used, total uint64, percent float64) string {
+	if c.Compact || total == 0 {
+		return c.prefix("ram") + c.percent(percent)
+	}
+	unit, size := byteUnit(float64(total))
+	return fmt.Sprintf("%s%.1f/%.1f %s (%s)", c.prefix("ram"), float64(used)/size, float64(total)/size, unit, c.percent(percent))
+}
+
+// formatNet renders the network widget's current rates, e.g.
+// "↑1.2 MB/s ↓340 KB/s", or tersely in compact mode, e.g. "↑120K ↓3.4M"
+func (c Config) formatNet(upRate, downRate float64) string {
+	up, down := formatRate(upRate, c.NetUnit), formatRate(downRate, c.NetUnit)
+	width := rateWidth
+	if c.Compact {
+		up, down = terseRate(upRate, c.NetUnit), terseRate(downRate, c.NetUnit)
+		width = terseRateWidth
+	}
+	if c.FixedWidthNumbers {
+		up, down = padFigures(up, width), padFigures(down, width)
+	}
+	return fmt.Sprintf("%s↑%s ↓%s", c.prefix("net"), up, down)
+}
+
+// terseRate is compactRate without the unit suffix, e.g. "120K"
+func terseRate(bytesPerSec float64, unit string) string {
+	s := compactRate(bytesPerSec, unit)
+	s = strings.TrimSuffix(s, "B/s")
+	return strings.TrimSuffix(s, "bps")
+}
+
+// clampText shortens s to at most max runes, marking the cut with an
+// ellipsis; max <= 0 leaves s unchanged
+func clampText(s string, max int) string {
+	return clampTextWith(s, max, "…")
+}
+
+// clampTextWith is clampText with the given mark, which counts towards max
+// unless it doesn't fit
+func clampTextWith(s string, max int, mark string) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	keep := max - utf8.RuneCountInString(mark)
+	if keep <= 0 {
+		return string([]rune(s)[:max])
+	}
+	return string([]rune(s)[:keep]) + mark
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Reconnect backoff while Qtile's socket is unavailable
+const (
+	groupsRetryMin = time.Second
+	groupsRetryMax = 30 * time.Second
+)
+
+// groupsWidget shows Qtile's groups as buttons with the current group
+// highlighted, and those visible on other screens marked. Clicking a button
+// switches to it and scrolling over the widget moves to the previous/next
+// group.
+type groupsWidget struct {
+	client *qtileClient
+	wrap   bool
+	box    *fyne.Container
+
+	mu      sync.Mutex
+	groups  []Group
+	buttons []*widget.Button
+
+	// Shown instead of the buttons while Qtile is unreachable
+	offline *widget.Label
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// newGroupsWidget creates the widget; wrap lets scrolling cycle past the ends
+func newGroupsWidget(client *qtileClient, wrap bool) *groupsWidget {
+	g := &groupsWidget{client: client, wrap: wrap, box: container.NewHBox(), offline: widget.NewLabel("groups: —")}
+	g.box.Add(g.offline)
+	return g
+}
+
+// CanvasObject returns the object to place in the bar
+func (g *groupsWidget) CanvasObject() fyne.CanvasObject {
+	return newScrollArea(g.box, g.scrolled)
+}
+
+// Update refreshes the group list from Qtile. While the socket is missing
+// (Qtile not started yet or restarting) it retries with exponential backoff.
+func (g *groupsWidget) Update() {
+	g.mu.Lock()
+	waiting := time.Now().Before(g.retryAt)
+	g.mu.Unlock()
+	if waiting {
+		return
+	}
+
+	groups, err := fetchGroups(g.client)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		g.disconnected(err)
+		return
+	}
+	if g.backoff > 0 {
+		log.Println("Reconnected to Qtile")
+		g.backoff, g.retryAt = 0, time.Time{}
+	}
+	g.box.Remove(g.offline)
+	g.groups = groups
+
+	// Reuse the existing buttons, adding or removing only the difference
+	for len(g.buttons) < len(groups) {
+		i := len(g.buttons)
+		b := widget.NewButton("", func() { g.activate(i) })
+		g.buttons = append(g.buttons, b)
+		g.box.Add(b)
+	}
+	for len(g.buttons) > len(groups) {
+		last := g.buttons[len(g.buttons)-1]
+		g.box.Remove(last)
+		g.buttons = g.buttons[:len(g.buttons)-1]
+	}
+	for i, group := range groups {
+		b := g.buttons[i]
+		text := group.Label
+		if text == "" {
+			text = group.Name
+		}
+		// Groups shown on another screen are marked less strongly than
+		// the focused one
+		importance := widget.LowImportance
+		switch {
+		case group.Focused:
+			importance = widget.HighImportance
+		case group.Screen != nil:
+			importance = widget.MediumImportance
+		}
+		if b.Text != text || b.Importance != importance {
+			b.Text, b.Importance = text, importance
+			b.Refresh()
+		}
+	}
+}
+
+// disconnected drops the stale groups, shows the offline label and schedules
+// the next attempt; callers hold mu
+func (g *groupsWidget) disconnected(err error) {
+	if g.backoff == 0 {
+		log.Println("Qtile IPC unavailable, retrying:", err)
+		g.backoff = groupsRetryMin
+	} else {
+		g.backoff = min(g.backoff*2, groupsRetryMax)
+	}
+	g.retryAt = time.Now().Add(g.backoff)
+
+	g.groups = nil
+	for _, b := range g.buttons {
+		g.box.Remove(b)
+	}
+	g.buttons = nil
+	if len(g.box.Objects) == 0 {
+		g.box.Add(g.offline)
+	}
+}
+
+// activate switches to the group at index i
+func (g *groupsWidget) activate(i int) {
+	g.mu.Lock()
+	if i < 0 || i >= len(g.groups) {
+		g.mu.Unlock()
+		return
+	}
+	name := g.groups[i].Name
+	g.mu.Unlock()
+	if err := switchGroup(g.client, name); err != nil {
+		log.Println("Failed to switch group:", err)
+		return
+	}
+	g.Update()
+}
+
+// scrolled moves to the previous group on scroll up and the next on scroll down
+func (g *groupsWidget) scrolled(ev *fyne.ScrollEvent) {
+	step := 1
+	if ev.Scrolled.DY > 0 {
+		step = -1
+	}
+	g.mu.Lock()
+	current, n := -1, len(g.groups)
+	for i, group := range g.groups {
+		if group.Focused {
+			current = i
+		}
+	}
+	g.mu.Unlock()
+	if current < 0 || n == 0 {
+		return
+	}
+	next := current + step
+	if g.wrap {
+		next = (next + n) % n
+	} else if next < 0 || next >= n {
+		return
+	}
+	g.activate(next)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// defaultScreenArea is assumed when X can't be asked for the screen size
+var defaultScreenArea = screenRect{width: 1920, height: 1080}
+
+// screenArea is the area the bar spans: the RandR output named output, or
+// the whole screen when it is empty or not active
+func screenArea(output string) (screenRect, error) {
+	if output != "" {
+		rect, err := outputRect(output)
+		if err == nil {
+			return rect, nil
+		}
+		log.Println("Ignoring Output:", err)
+	}
+	width, height, err := detectScreenGeometry()
+	if err != nil {
+		return screenRect{}, err
+	}
+	return screenRect{width: width, height: height}, nil
+}
+
+// dockGeometry places the bar on area: along the top or bottom edge
+// barHeight high, or as a BarWidth wide side panel when vertical. It also
+// returns the window size to match.
+func dockGeometry(cfg Config, area screenRect, barHeight float32) (barGeometry, fyne.Size) {
+	if cfg.Orientation != "vertical" {
+		g := barGeometry{edge: cfg.Position, thickness: int(barHeight), length: area.width, x: area.x, y: area.y}
+		if cfg.Position == "bottom" {
+			g.y += area.height - int(barHeight)
+		}
+		return g, fyne.NewSize(float32(area.width), barHeight)
+	}
+	g := barGeometry{edge: cfg.VerticalEdge, thickness: int(cfg.BarWidth), length: area.height, x: area.x, y: area.y}
+	if cfg.VerticalEdge == "right" {
+		g.x += area.width - int(cfg.BarWidth)
+	}
+	return g, fyne.NewSize(cfg.BarWidth, float32(area.height))
+}
+
+// watchScreenChanges calls onChange, from its own goroutine, after each
+// RandR screen change: a monitor plugged in or out, or a new mode or layout
+func watchScreenChanges(onChange func()) error {
+	if _, err := barExtension("RandR", randr.Init); err != nil {
+		return err
+	}
+	events, err := watchX()
+	if err != nil {
+		return err
+	}
+	root := xproto.Setup(events.X).DefaultScreen(events.X).Root
+	if err := events.SelectRandr(root, randr.NotifyMaskScreenChange); err != nil {
+		events.Close()
+		return err
+	}
+	go func() {
+		for {
+			ev, ok := events.Next()
+			if !ok {
+				return
+			}
+			if _, ok := ev.(randr.ScreenChangeNotifyEvent); ok {
+				onChange()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"golang.org/x/image/draw"
+)
+
+// minIconSize keeps icons recognisable on very thin bars
+const minIconSize = 12
+
+// barIconSize is the edge of icons on a bar of the given height, leaving the
+// theme padding above and below
+func barIconSize(height float32) int {
+	return max(int(height-2*theme.Padding()), minIconSize)
+}
+
+// iconSize is barIconSize for the configured BarHeight, or for the smallest
+// automatic height
+func (c Config) iconSize() int {
+	if c.BarHeight > 0 {
+		return barIconSize(c.BarHeight)
+	}
+	return barIconSize(defaultBarHeight)
+}
+
+// scaleIcon resizes img so its longer side is size pixels, keeping the aspect
+// ratio. Catmull-Rom keeps downscaled icons sharp without the blocky,
+// aliased edges of nearest-neighbour scaling; an icon already at size is
+// returned as is.
+func scaleIcon(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 || max(b.Dx(), b.Dy()) == size {
+		return img
+	}
+	w, h := size, size
+	if b.Dx() > b.Dy() {
+		h = max(b.Dy()*size/b.Dx(), 1)
+	} else if b.Dy() > b.Dx() {
+		w = max(b.Dx()*size/b.Dy(), 1)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// imageResource encodes img, scaled to size, as a PNG resource for widgets
+// that take a fyne.Resource; it returns nil if encoding fails
+func imageResource(name string, img image.Image, size int) fyne.Resource {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleIcon(img, size)); err != nil {
+		return nil
+	}
+	return fyne.NewStaticResource(name+".png", buf.Bytes())
+}
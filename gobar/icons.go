@@ -0,0 +1,52 @@
+package main
+
+// widgetIcon is the text label and Nerd Font glyph that can prefix a widget
+type widgetIcon struct {
+	text  string
+	glyph string
+}
+
+// widgetIcons maps widget names to their prefixes. Glyphs need a Nerd Font
+// (see FontPath) to render; the text labels work with any font.
+var widgetIcons = map[string]widgetIcon{
+	"time":    {text: "Time: ", glyph: " "},    // nf-fa-clock_o
+	"cpu":     {text: "CPU: ", glyph: " "},     // nf-fa-microchip
+	"ram":     {text: "RAM: ", glyph: " "},     // nf-fa-memory
+	"net":     {text: "Network: ", glyph: " "}, // nf-fa-exchange
+	"disk":    {text: "Disk: ", glyph: " "},    // nf-fa-hdd_o
+	"temp":    {text: "Temp: ", glyph: " "},    // nf-fa-thermometer_half
+	"battery": {text: "Bat: ", glyph: " "},     // nf-fa-battery_full
+	"kbd":     {text: "Kbd: ", glyph: " "},     // nf-fa-keyboard_o
+	"proc":    {text: "Proc: ", glyph: " "},    // nf-fa-cogs
+	"idle":    {text: "idle ", glyph: " "},     // nf-fa-moon_o
+	"log":     {text: "", glyph: " "},          // nf-fa-file_text_o
+	"media":   {text: "♪ ", glyph: " "},        // nf-fa-music
+	"layout":  {text: "", glyph: " "},          // nf-fa-th_large
+	"wan":     {text: "WAN: ", glyph: " "},     // nf-fa-globe
+	"vpn":     {text: "🔒 ", glyph: " "},        // nf-fa-lock
+	"nm":      {text: "", glyph: " "},          // nf-fa-wifi
+	"display": {text: "", glyph: " "},          // nf-fa-desktop
+	"volume":  {text: "Vol: ", glyph: " "},     // nf-fa-volume_up
+	// The notifications badge, followed by its count
+	"notifications": {text: "🔔", glyph: ""}, // nf-fa-bell
+}
+
+// dndIcon replaces the notifications badge's icon during Do not disturb
+var dndIcon = widgetIcon{text: "🔕", glyph: ""} // nf-fa-bell_slash
+
+// prefix returns the label prefix for a widget, using its glyph when
+// GlyphIcons enables it for that widget. Compact mode drops text prefixes.
+func (c Config) prefix(widget string) string {
+	return c.prefixAs(widget, widgetIcons[widget])
+}
+
+// prefixAs is prefix with another icon for the widget, e.g. for a state
+func (c Config) prefixAs(widget string, icon widgetIcon) string {
+	if c.GlyphIcons[widget] && icon.glyph != "" {
+		return icon.glyph
+	}
+	if c.Compact {
+		return ""
+	}
+	return icon.text
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// themeIconSize is the size preferred among an icon theme's fixed sizes;
+// the bar scales icons to its own size from there
+const themeIconSize = 24
+
+// pixmapsDir holds unthemed icons, searched after the themes
+const pixmapsDir = "/usr/share/pixmaps"
+
+// iconThemeDir is a directory of an icon theme, e.g. "22x22/apps"
+type iconThemeDir struct {
+	path     string
+	size     int
+	scale    int
+	scalable bool
+}
+
+// iconTheme is a theme's index.theme: its directories, most fitting first,
+// and the themes it falls back to
+type iconTheme struct {
+	name     string
+	dirs     []iconThemeDir
+	inherits []string
+}
+
+// parseIconTheme reads the index.theme of the theme name. Directories are
+// ordered by how close their size is to themeIconSize, the fixed sizes
+// before the scalable ones and normal before HiDPI ones.
+func parseIconTheme(name, content string) iconTheme {
+	theme := iconTheme{name: name}
+	dirs := map[string]*iconThemeDir{}
+	var order []string
+	var group string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if group == "Icon Theme" {
+			switch key {
+			case "Directories", "ScaledDirectories":
+				for _, d := range strings.Split(value, ",") {
+					if d = strings.TrimSpace(d); d != "" && dirs[d] == nil {
+						dirs[d] = &iconThemeDir{path: d, scale: 1}
+						order = append(order, d)
+					}
+				}
+			case "Inherits":
+				for _, t := range strings.Split(value, ",") {
+					if t = strings.TrimSpace(t); t != "" {
+						theme.inherits = append(theme.inherits, t)
+					}
+				}
+			}
+			continue
+		}
+		d := dirs[group]
+		if d == nil {
+			continue
+		}
+		switch key {
+		case "Size":
+			d.size, _ = strconv.Atoi(value)
+		case "Scale":
+			d.scale, _ = strconv.Atoi(value)
+		case "Type":
+			d.scalable = value == "Scalable"
+		}
+	}
+	for _, d := range order {
+		theme.dirs = append(theme.dirs, *dirs[d])
+	}
+	rank := func(d iconThemeDir) int {
+		r := abs(d.size - themeIconSize)
+		if d.scalable {
+			r += 1000
+		}
+		if d.scale > 1 {
+			r += 2000
+		}
+		return r
+	}
+	sort.SliceStable(theme.dirs, func(i, j int) bool { return rank(theme.dirs[i]) < rank(theme.dirs[j]) })
+	return theme
+}
+
+// abs is the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// iconLookup finds icons by name as the icon theme spec does: in the
+// configured theme, then the themes it inherits and hicolor, and then the
+// pixmaps directory. Themes are looked for in ~/.icons and the icons
+// directories of the XDG data dirs, including ~/.local/share/icons and the
+// Flatpak exports. Results are remembered, including misses.
+type iconLookup struct {
+	mu    sync.Mutex
+	theme string
+	bases []string
+	chain []iconTheme // nil until the first lookup
+	found map[string]string
+}
+
+// themeIcons looks up the icons of the Start Menu and the tray
+var themeIcons iconLookup
+
+// use switches to the icon theme named theme; empty takes GTK's
+func (l *iconLookup) use(theme string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.theme, l.chain, l.found = theme, nil, nil
+}
+
+// find returns the file of the icon name, or "" when no theme has it
+func (l *iconLookup) find(name string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.chain == nil {
+		l.load()
+	}
+	if path, ok := l.found[name]; ok {
+		return path
+	}
+	path := l.search(name)
+	l.found[name] = path
+	return path
+}
+
+// search looks name up in the theme chain and then the pixmaps; callers
+// hold mu
+func (l *iconLookup) search(name string) string {
+	for _, theme := range l.chain {
+		for _, base := range l.bases {
+			root := filepath.Join(base, theme.name)
+			if _, err := os.Stat(root); err != nil {
+				continue
+			}
+			for _, d := range theme.dirs {
+				for _, ext := range []string{".png", ".svg"} {
+					path := filepath.Join(root, d.path, name+ext)
+					if _, err := os.Stat(path); err == nil {
+						return path
+					}
+				}
+			}
+		}
+	}
+	for _, ext := range []string{".png", ".svg"} {
+		path := filepath.Join(pixmapsDir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// load finds the base directories and reads the theme chain; callers hold mu
+func (l *iconLookup) load() {
+	home, _ := os.UserHomeDir()
+	l.bases = []string{filepath.Join(home, ".icons")}
+	for _, d := range dataDirs() {
+		l.bases = append(l.bases, filepath.Join(d, "icons"))
+	}
+	l.found = map[string]string{}
+	l.chain = []iconTheme{}
+	name := l.theme
+	if name == "" {
+		name = gtkIconTheme()
+	}
+	// hicolor ends every chain, after all the inherited themes
+	seen := map[string]bool{"hicolor": true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		name, queue = queue[0], queue[1:]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if theme, ok := l.readTheme(name); ok {
+			l.chain = append(l.chain, theme)
+			queue = append(queue, theme.inherits...)
+		}
+	}
+	if theme, ok := l.readTheme("hicolor"); ok {
+		l.chain = append(l.chain, theme)
+	}
+}
+
+// readTheme parses the first index.theme of name in the base directories
+func (l *iconLookup) readTheme(name string) (iconTheme, bool) {
+	for _, base := range l.bases {
+		if data, err := os.ReadFile(filepath.Join(base, name, "index.theme")); err == nil {
+			return parseIconTheme(name, string(data)), true
+		}
+	}
+	return iconTheme{}, false
+}
+
+// gtkIconTheme is the gtk-icon-theme-name of GTK 3's settings.ini, or ""
+func gtkIconTheme() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "gtk-3.0", "settings.ini"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "gtk-icon-theme-name" {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
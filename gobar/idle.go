@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/xgb/screensaver"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// idleSource reports how long the session has gone without user input,
+// using the X Screensaver extension on the shared connection
+type idleSource struct{}
+
+// newIdleSource checks for the extension on the shared connection
+func newIdleSource() (*idleSource, error) {
+	if _, err := barExtension("MIT-SCREEN-SAVER", screensaver.Init); err != nil {
+		return nil, err
+	}
+	return &idleSource{}, nil
+}
+
+// Idle returns the time since the last keyboard or pointer input
+func (s *idleSource) Idle() (time.Duration, error) {
+	X, err := barExtension("MIT-SCREEN-SAVER", screensaver.Init)
+	if err != nil {
+		return 0, err
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	info, err := screensaver.QueryInfo(X, xproto.Drawable(root)).Reply()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(info.MsSinceUserInput) * time.Millisecond, nil
+}
+
+// idleDimmer lowers the bar's opacity once the session has been idle for
+// a while and restores it on the next input. It is fed by the same
+// idleSource as the idle widget.
+type idleDimmer struct {
+	after       time.Duration
+	dim, normal float64
+	// The bar's X window, set once it is docked; 0 until then
+	winID  atomic.Uint32
+	dimmed bool
+}
+
+// newIdleDimmer dims to opacity dim after the given idle time, and restores
+// opacity normal
+func newIdleDimmer(after time.Duration, dim, normal float64) *idleDimmer {
+	return &idleDimmer{after: after, dim: dim, normal: normal}
+}
+
+// Update applies the opacity for the current idle time when it changes
+func (d *idleDimmer) Update(idle time.Duration) {
+	winID := d.winID.Load()
+	dim := idle >= d.after
+	if winID == 0 || dim == d.dimmed {
+		return
+	}
+	opacity := d.normal
+	if dim {
+		opacity = d.dim
+	}
+	if err := setWindowOpacity(winID, opacity); err != nil {
+		log.Println("Failed to change bar opacity:", err)
+		return
+	}
+	d.dimmed = dim
+}
+
+// formatIdle renders an idle duration compactly, e.g. "3m"
+func formatIdle(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
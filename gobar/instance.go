@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// instanceWait is how long a replacing gobar waits for the old one to exit
+const instanceWait = 5 * time.Second
+
+// errInstanceRunning means another gobar already runs on the same output
+var errInstanceRunning = errors.New("gobar is already running (start it with -replace to take over)")
+
+// instanceLock is the X selection that makes gobar a single instance per
+// output, like a window manager's WM_Sn selection: the running bar owns it
+// with a hidden window, and exits once a replacing bar takes it over.
+type instanceLock struct {
+	X      *xgb.Conn
+	events *xWatch
+	win    xproto.Window
+	lost   chan struct{}
+
+	old       xproto.Window // the replaced bar's window
+	destroyed chan struct{}
+}
+
+// instanceSelection names the selection of the bar on output; an empty
+// output is the bar on the default screen
+func instanceSelection(output string) string {
+	if output == "" {
+		return "_GOBAR_BAR"
+	}
+	return "_GOBAR_BAR_" + strings.ToUpper(output)
+}
+
+// claimInstance takes the selection for output. With replace it takes it
+// from a running gobar and waits for that one to exit; otherwise a running
+// gobar makes it fail with errInstanceRunning.
+func claimInstance(output string, replace bool) (*instanceLock, error) {
+	events, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	l, err := claimSelection(events, instanceSelection(output), replace)
+	if err != nil {
+		events.Close()
+		return nil, err
+	}
+	go l.run()
+	if l.old != xproto.WindowNone {
+		select {
+		case <-l.destroyed:
+		case <-time.After(instanceWait):
+			log.Println("The replaced gobar did not exit in time, starting anyway")
+		}
+	}
+	return l, nil
+}
+
+// claimSelection takes the selection called name for claimInstance, as
+// ICCCM has managers do: it takes the selection at a server timestamp, so
+// of two bars starting at once only the later one keeps it, and announces
+// itself with a MANAGER message. The previous owner learns it was replaced
+// from the SelectionClear the server sends it, and its window is watched to
+// know when it has exited.
+func claimSelection(events *xWatch, name string, replace bool) (*instanceLock, error) {
+	X := events.X
+	selection, err := internAtom(X, name)
+	if err != nil {
+		return nil, err
+	}
+	win, err := xproto.NewWindowId(X)
+	if err != nil {
+		return nil, err
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	if err := xproto.CreateWindowChecked(X, 0, win, root, -1, -1, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0, 0, nil).Check(); err != nil {
+		return nil, err
+	}
+	stamp, err := serverTime(events, win)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := xproto.GetSelectionOwner(X, selection).Reply()
+	if err != nil {
+		return nil, err
+	}
+	old := owner.Owner
+	if old != xproto.WindowNone {
+		if !replace {
+			return nil, errInstanceRunning
+		}
+		// Learn when the old owner's window goes away with its process; it
+		// may already be gone
+		if err := events.Select(old, xproto.EventMaskStructureNotify); err != nil {
+			old = xproto.WindowNone
+		}
+	}
+	if err := xproto.SetSelectionOwnerChecked(X, win, selection, stamp).Check(); err != nil {
+		return nil, err
+	}
+	// A bar that took it at a later timestamp in between keeps it
+	if owner, err := xproto.GetSelectionOwner(X, selection).Reply(); err != nil {
+		return nil, err
+	} else if owner.Owner != win {
+		return nil, errInstanceRunning
+	}
+
+	manager, err := internAtom(X, "MANAGER")
+	if err != nil {
+		return nil, err
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: root,
+		Type:   manager,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{uint32(stamp), uint32(selection), uint32(win), 0, 0}),
+	}
+	xproto.SendEvent(X, false, root, xproto.EventMaskStructureNotify, string(ev.Bytes()))
+
+	return &instanceLock{X: X, events: events, win: win, lost: make(chan struct{}), old: old, destroyed: make(chan struct{})}, nil
+}
+
+// serverTime gets a server timestamp for taking a selection, from the
+// PropertyNotify of an empty append to a property of win
+func serverTime(events *xWatch, win xproto.Window) (xproto.Timestamp, error) {
+	if err := events.Select(win, xproto.EventMaskPropertyChange); err != nil {
+		return 0, err
+	}
+	if err := xproto.ChangePropertyChecked(events.X, xproto.PropModeAppend, win, xproto.AtomWmName, xproto.AtomString, 8, 0, nil).Check(); err != nil {
+		return 0, err
+	}
+	for {
+		ev, ok := events.Next()
+		if !ok {
+			return 0, errors.New("X connection closed")
+		}
+		if pn, ok := ev.(xproto.PropertyNotifyEvent); ok && pn.Window == win {
+			return pn.Time, nil
+		}
+	}
+}
+
+// run closes destroyed when the replaced bar's window is gone, and lost
+// when another gobar takes the selection over
+func (l *instanceLock) run() {
+	defer l.events.Close()
+	old := l.old
+	for {
+		ev, ok := l.events.Next()
+		if !ok {
+			return
+		}
+		switch ev := ev.(type) {
+		case xproto.DestroyNotifyEvent:
+			if old != xproto.WindowNone && ev.Window == old {
+				old = xproto.WindowNone
+				close(l.destroyed)
+			}
+		case xproto.SelectionClearEvent:
+			if ev.Owner == l.win {
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+// Lost is closed when a replacing gobar has taken over
+func (l *instanceLock) Lost() <-chan struct{} {
+	return l.lost
+}
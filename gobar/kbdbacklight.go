@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// kbdBacklightGlob matches keyboard backlight LEDs, e.g. tpacpi::kbd_backlight
+const kbdBacklightGlob = "/sys/class/leds/*::kbd_backlight"
+
+// kbdBacklightWidget shows the keyboard backlight level; scrolling over it
+// steps the level up or down. It is hidden when there is no backlight.
+type kbdBacklightWidget struct {
+	label  *widget.Label
+	area   *scrollArea
+	dir    string
+	prefix string
+}
+
+// newKbdBacklightWidget finds the first keyboard backlight
+func newKbdBacklightWidget(prefix string) *kbdBacklightWidget {
+	k := &kbdBacklightWidget{label: widget.NewLabel(""), prefix: prefix}
+	if dirs, _ := filepath.Glob(kbdBacklightGlob); len(dirs) > 0 {
+		k.dir = dirs[0]
+	}
+	k.area = newScrollArea(k.label, k.scrolled)
+	k.area.Hide()
+	return k
+}
+
+// CanvasObject returns the object to place in the bar
+func (k *kbdBacklightWidget) CanvasObject() fyne.CanvasObject {
+	return k.area
+}
+
+// Update shows the current level, e.g. "Kbd: 1/2"
+func (k *kbdBacklightWidget) Update() {
+	level, maxLevel, err := k.read()
+	if err != nil {
+		k.area.Hide()
+		return
+	}
+	k.show(level, maxLevel)
+	k.area.Show()
+}
+
+// files are the sysfs attributes to watch for Update: brightness for writes
+// through the file, and brightness_hw_changed, which the LED class updates
+// when the firmware changes the level, e.g. with an Fn key. Without the
+// latter those changes emit no event, so files returns nil and Update has
+// to be polled.
+func (k *kbdBacklightWidget) files() []string {
+	if k.dir == "" {
+		return nil
+	}
+	hwChanged := filepath.Join(k.dir, "brightness_hw_changed")
+	if _, err := os.Stat(hwChanged); err != nil {
+		return nil
+	}
+	return []string{filepath.Join(k.dir, "brightness"), hwChanged}
+}
+
+// show sets the label text for a level
+func (k *kbdBacklightWidget) show(level, maxLevel int) {
+	setLabelText(k.label, fmt.Sprintf("%s%d/%d", k.prefix, level, maxLevel))
+}
+
+// read returns the current and maximum brightness
+func (k *kbdBacklightWidget) read() (int, int, error) {
+	if k.dir == "" {
+		return 0, 0, os.ErrNotExist
+	}
+	level, err := sysfsFloat(k.dir, "brightness")
+	if err != nil {
+		return 0, 0, err
+	}
+	maxLevel, err := sysfsFloat(k.dir, "max_brightness")
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(level), int(maxLevel), nil
+}
+
+// scrolled steps the level by one per wheel notch, up for scrolling up
+func (k *kbdBacklightWidget) scrolled(ev *fyne.ScrollEvent) {
+	level, maxLevel, err := k.read()
+	if err != nil {
+		return
+	}
+	next := level
+	switch {
+	case ev.Scrolled.DY > 0:
+		next = min(level+1, maxLevel)
+	case ev.Scrolled.DY < 0:
+		next = max(level-1, 0)
+	}
+	if next == level {
+		return
+	}
+	if err := k.set(next); err != nil {
+		log.Println("Failed to set keyboard backlight:", err)
+		return
+	}
+	k.show(next, maxLevel)
+}
+
+// set writes the level directly, which needs write access to the sysfs
+// file (e.g. a udev rule), and falls back to brightnessctl otherwise
+func (k *kbdBacklightWidget) set(level int) error {
+	path := filepath.Join(k.dir, "brightness")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(level)), 0o644); err == nil {
+		return nil
+	}
+	return exec.Command("brightnessctl", "--device="+filepath.Base(k.dir), "set", strconv.Itoa(level)).Run()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// keyboardWidget shows the active keyboard layout and lock states in one
+// label, e.g. "EN ⇪". An XKB server reports the layout group in bits 13-14
+// of the core modifier state, next to the Lock and Mod2 (NumLock) bits. It
+// follows XkbStateNotify events, or polls when XKB can't be set up, and a
+// click switches to the next layout, a right click to the previous one.
+type keyboardWidget struct {
+	X      *xgb.Conn
+	events *xWatch // nil without XKB events
+	root   xproto.Window
+	rules  xproto.Atom // _XKB_RULES_NAMES
+	label  *widget.Label
+	xkb    bool // XKB events and group switching work
+
+	showLayout, showCaps, showNum bool
+}
+
+// init registers the keyboard indicator
+func init() {
+	registerWidget("keyboard", func(cfg Config) bool { return cfg.ShowKeyboard }, func(cfg Config) (Widget, error) {
+		return newKeyboardWidget(cfg.KeyboardShowLayout, cfg.KeyboardShowCaps, cfg.KeyboardShowNum)
+	})
+}
+
+// newKeyboardWidget uses the shared X connection; the show flags pick the
+// shown elements
+func newKeyboardWidget(showLayout, showCaps, showNum bool) (*keyboardWidget, error) {
+	X, err := barConn()
+	if err != nil {
+		return nil, err
+	}
+	k := &keyboardWidget{
+		X:          X,
+		root:       xproto.Setup(X).DefaultScreen(X).Root,
+		label:      widget.NewLabel(""),
+		showLayout: showLayout,
+		showCaps:   showCaps,
+		showNum:    showNum,
+	}
+	if k.rules, err = internAtom(X, "_XKB_RULES_NAMES"); err != nil {
+		return nil, err
+	}
+	if _, err := barExtension(xkbExtension, initXkb); err != nil {
+		log.Println("Keyboard widget polls:", err)
+	} else if k.events, err = watchX(); err != nil {
+		log.Println("Keyboard widget polls, no XKB events:", err)
+	} else if err := xkbSelectState(X); err != nil {
+		log.Println("Keyboard widget polls, no XKB events:", err)
+		k.events.Close()
+		k.events = nil
+	} else {
+		k.xkb = true
+	}
+	return k, nil
+}
+
+// Render returns the object to place in the bar
+func (k *keyboardWidget) Render() fyne.CanvasObject {
+	return k.label
+}
+
+// Interval is 0 with XKB events, else a poll once a second
+func (k *keyboardWidget) Interval() time.Duration {
+	if k.xkb {
+		return 0
+	}
+	return time.Second
+}
+
+// Update shows the state, and with XKB keeps showing it on every state
+// change until ctx is done
+func (k *keyboardWidget) Update(ctx context.Context) {
+	k.refresh()
+	if !k.xkb {
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		k.events.Close()
+	}()
+	for {
+		ev, ok := k.events.Next()
+		if !ok {
+			return // closed
+		}
+		if _, ok := ev.(xkbStateEvent); ok {
+			k.refresh()
+		}
+	}
+}
+
+// refresh reads the modifier state and the configured layouts
+func (k *keyboardWidget) refresh() {
+	pointer, err := xproto.QueryPointer(k.X, k.root).Reply()
+	if err != nil {
+		return
+	}
+	var parts []string
+	if k.showLayout {
+		group := int(pointer.Mask>>13) & 3
+		if layouts := k.layouts(); group < len(layouts) {
+			parts = append(parts, strings.ToUpper(layouts[group]))
+		}
+	}
+	if k.showCaps && pointer.Mask&xproto.ModMaskLock != 0 {
+		parts = append(parts, "⇪")
+	}
+	if k.showNum && pointer.Mask&xproto.ModMask2 != 0 {
+		parts = append(parts, "⇭")
+	}
+	setLabelText(k.label, strings.Join(parts, " "))
+}
+
+// OnClick switches to the next layout, or the previous one on a right
+// click, wrapping around
+func (k *keyboardWidget) OnClick(button desktop.MouseButton) {
+	step := 1
+	switch button {
+	case desktop.MouseButtonPrimary:
+	case desktop.MouseButtonSecondary:
+		step = -1
+	default:
+		return
+	}
+	layouts := k.layouts()
+	if !k.xkb || len(layouts) < 2 {
+		return
+	}
+	pointer, err := xproto.QueryPointer(k.X, k.root).Reply()
+	if err != nil {
+		return
+	}
+	group := (int(pointer.Mask>>13)&3 + step + len(layouts)) % len(layouts)
+	if err := xkbLockGroup(k.X, group); err != nil {
+		log.Println("Failed to switch the keyboard layout:", err)
+	}
+}
+
+// layouts returns the configured layouts from the root's _XKB_RULES_NAMES,
+// whose third field lists them, e.g. "us,de"
+func (k *keyboardWidget) layouts() []string {
+	reply, err := xproto.GetProperty(k.X, false, k.root, k.rules, xproto.AtomString, 0, 1024).Reply()
+	if err != nil {
+		return nil
+	}
+	// rules, model, layout, variant and options, NUL separated
+	fields := strings.Split(string(reply.Value), "\x00")
+	if len(fields) < 3 || fields[2] == "" {
+		return nil
+	}
+	return strings.Split(fields[2], ",")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// launchCommand runs a shell command line detached from the bar, in its
+// own session
+func launchCommand(cmdline string) {
+	launchCommandIn(cmdline, "")
+}
+
+// launchCommandIn is launchCommand with a working directory; empty keeps gobar's
+func launchCommandIn(cmdline, dir string) {
+	launchCommandEnv(cmdline, dir, nil)
+}
+
+// launchCommandEnv is launchCommandIn adding env, "KEY=value" entries, to
+// gobar's environment
+func launchCommandEnv(cmdline, dir string, env []string) {
+	if cmdline == "" {
+		return
+	}
+	cmd := exec.Command("sh", "-c", cmdline)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	// A session of its own keeps the app running when the bar exits or
+	// restarts, and out of reach of signals sent to the bar's process group
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Failed to run %q: %v", cmdline, err)
+		return
+	}
+	// Reap the child so it doesn't linger as a zombie
+	go cmd.Wait()
+}
+
+// focusOrLaunch raises an existing window of the app when FocusOrLaunch is
+// set and one is found, otherwise it starts the command
+func focusOrLaunch(l TrayLauncher) {
+	if l.FocusOrLaunch && focusExisting(l.windowClass()) {
+		return
+	}
+	launchCommandEnv(l.Command, l.Dir, l.Env)
+}
+
+// focusExisting activates the first client window matching class
+func focusExisting(class string) bool {
+	if class == "" {
+		return false
+	}
+	X, err := barConn()
+	if err != nil {
+		return false
+	}
+	win, ok := findWindowByClass(X, class)
+	if !ok {
+		return false
+	}
+	if err := activateWindow(X, win); err != nil {
+		log.Println("Failed to focus window:", err)
+		return false
+	}
+	return true
+}
+
+// windowClass is the WM_CLASS to match, defaulting to the command's basename
+func (l TrayLauncher) windowClass() string {
+	if l.WMClass != "" {
+		return l.WMClass
+	}
+	fields := strings.Fields(l.Command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// editConfigCommand builds the command opening path for editing: EditorCommand
+// as a GUI editor, else $VISUAL or $EDITOR inside the terminal
+func editConfigCommand(cfg Config, path string) (string, error) {
+	if cfg.EditorCommand != "" {
+		return cfg.EditorCommand + " " + shellQuote(path), nil
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(env); editor != "" {
+			return cfg.terminal() + " -e " + editor + " " + shellQuote(path), nil
+		}
+	}
+	return "", errors.New("no editor configured: set EditorCommand in gobar.json, or $VISUAL or $EDITOR")
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"image/color"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// barLayout arranges objects left to right like an HBox, or top to bottom
+// for a vertical bar, with a configurable gap between them. Spacers share any
+// leftover length.
+type barLayout struct {
+	gap      float32
+	vertical bool
+}
+
+// Layout places each visible object at its minimum length and full thickness
+func (l barLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	spacers := 0
+	total := float32(0)
+	visible := 0
+	for _, o := range objects {
+		if !o.Visible() {
+			continue
+		}
+		visible++
+		if l.isSpacer(o) {
+			spacers++
+			continue
+		}
+		total += l.length(o.MinSize())
+	}
+	if visible > 1 {
+		total += l.gap * float32(visible-1)
+	}
+	extra := float32(0)
+	if spacers > 0 && l.length(size) > total {
+		extra = (l.length(size) - total) / float32(spacers)
+	}
+
+	pos := float32(0)
+	for _, o := range objects {
+		if !o.Visible() {
+			continue
+		}
+		length := l.length(o.MinSize())
+		if l.isSpacer(o) {
+			length = extra
+		}
+		if l.vertical {
+			o.Move(fyne.NewPos(0, pos))
+			o.Resize(fyne.NewSize(size.Width, length))
+		} else {
+			o.Move(fyne.NewPos(pos, 0))
+			o.Resize(fyne.NewSize(length, size.Height))
+		}
+		pos += length + l.gap
+	}
+}
+
+// MinSize is the sum of visible lengths plus gaps, and the largest thickness
+func (l barLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var length, thickness float32
+	visible := 0
+	for _, o := range objects {
+		if !o.Visible() {
+			continue
+		}
+		visible++
+		min := o.MinSize()
+		length += l.length(min)
+		if l.vertical {
+			thickness = max(thickness, min.Width)
+		} else {
+			thickness = max(thickness, min.Height)
+		}
+	}
+	if visible > 1 {
+		length += l.gap * float32(visible-1)
+	}
+	if l.vertical {
+		return fyne.NewSize(thickness, length)
+	}
+	return fyne.NewSize(length, thickness)
+}
+
+// length is a size's extent along the bar
+func (l barLayout) length(size fyne.Size) float32 {
+	if l.vertical {
+		return size.Height
+	}
+	return size.Width
+}
+
+// isSpacer reports whether o is a layout spacer that expands along the bar
+func (l barLayout) isSpacer(o fyne.CanvasObject) bool {
+	s, ok := o.(layout.SpacerObject)
+	if l.vertical {
+		return ok && s.ExpandVertical()
+	}
+	return ok && s.ExpandHorizontal()
+}
+
+// insetLayout places its objects inside fixed left and right margins
+type insetLayout struct {
+	left, right float32
+}
+
+// Layout fills the area between the margins
+func (l insetLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	inner := fyne.NewSize(size.Width-l.left-l.right, size.Height)
+	for _, o := range objects {
+		o.Move(fyne.NewPos(l.left, 0))
+		o.Resize(inner)
+	}
+}
+
+// MinSize adds the margins to the largest object
+func (l insetLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var size fyne.Size
+	for _, o := range objects {
+		size = size.Max(o.MinSize())
+	}
+	return size.AddWidthHeight(l.left+l.right, 0)
+}
+
+// barWidgets are the names statusBar.add is called with, for Spacers
+var barWidgets = []string{
+	"logo", "start", "terminal", "run", "groups", "layout", "taskbar", "title",
+	"time", "cpu", "ram", "net", "battery", "kbd", "keyboard", "proc", "idle",
+	"log", "screenshot", "desktop", "screenoff", "notifications", "media",
+	"audio", "mic", "camera", "power", "session", "wan", "vpn", "nm", "display",
+	"tray", "xembed",
+}
+
+// groupDividerWidth is the thickness of a coloured group divider, in pixels
+const groupDividerWidth = 2
+
+// barBox is the bar's widget container. Widgets are added under a name so a
+// flexible spacer can follow those listed in Config.Spacers, and so the
+// control socket can address them. The spacers split the bar into groups,
+// optionally marked by a divider on each side of a spacer.
+type barBox struct {
+	*fyne.Container
+	spacers []string
+	divider func() fyne.CanvasObject // nil for no group dividers
+	widgets map[string][]fyne.CanvasObject
+	tails   map[string][]fyne.CanvasObject // the spacer and dividers after each widget
+	colors  map[string]WidgetColors
+}
+
+// newBarBox wraps the bar container; groupSeparator is Config.GroupSeparator
+// and colors Config.WidgetColors
+func newBarBox(c *fyne.Container, spacers []string, groupSeparator string, colors map[string]WidgetColors) barBox {
+	b := barBox{Container: c, spacers: spacers, widgets: map[string][]fyne.CanvasObject{}, tails: map[string][]fyne.CanvasObject{}, colors: colors}
+	switch groupSeparator {
+	case "", "none":
+	case "line":
+		b.divider = func() fyne.CanvasObject { return widget.NewSeparator() }
+	default:
+		// A colour segment; validate ensures it parses
+		col, _ := parseHexColor(groupSeparator)
+		b.divider = func() fyne.CanvasObject { return groupDivider(col) }
+	}
+	return b
+}
+
+// groupDivider is a thin bar of colour c
+func groupDivider(c color.Color) fyne.CanvasObject {
+	r := canvas.NewRectangle(c)
+	r.SetMinSize(fyne.NewSize(groupDividerWidth, groupDividerWidth))
+	return r
+}
+
+// add appends a widget's objects, in its configured colours, then a spacer
+// if one is configured after name, between group dividers if those are on
+func (b barBox) add(name string, objects ...fyne.CanvasObject) {
+	if colors, ok := b.colors[name]; ok {
+		for i, o := range objects {
+			objects[i] = tintWidget(o, colors)
+		}
+	}
+	for _, o := range objects {
+		b.Add(o)
+	}
+	b.widgets[name] = append(b.widgets[name], objects...)
+	if !slices.Contains(b.spacers, name) {
+		return
+	}
+	var tail []fyne.CanvasObject
+	if b.divider != nil {
+		tail = append(tail, b.divider())
+	}
+	tail = append(tail, layout.NewSpacer())
+	if b.divider != nil {
+		tail = append(tail, b.divider())
+	}
+	for _, o := range tail {
+		b.Add(o)
+	}
+	b.tails[name] = append(b.tails[name], tail...)
+}
+
+// order rearranges the bar to list only the named widgets, in that order,
+// each followed by its spacer. Objects before the first widget, like the
+// degraded mode banner, stay in front; loose separators are dropped.
+func (b barBox) order(names []string) {
+	owned := map[fyne.CanvasObject]bool{}
+	for _, objects := range b.widgets {
+		for _, o := range objects {
+			owned[o] = true
+		}
+	}
+	var objects []fyne.CanvasObject
+	for _, o := range b.Objects {
+		if owned[o] {
+			break
+		}
+		objects = append(objects, o)
+	}
+	for _, name := range names {
+		objects = append(objects, b.widgets[name]...)
+		objects = append(objects, b.tails[name]...)
+	}
+	for name := range b.widgets {
+		if !slices.Contains(names, name) {
+			delete(b.widgets, name)
+			delete(b.tails, name)
+		}
+	}
+	b.Objects = objects
+	b.Refresh()
+}
+
+// names lists the widgets on the bar in bar order
+func (b barBox) names() []string {
+	owner := map[fyne.CanvasObject]string{}
+	for name, objects := range b.widgets {
+		owner[objects[0]] = name
+	}
+	var names []string
+	for _, o := range b.Objects {
+		if name, ok := owner[o]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// setVisible shows or hides a widget along with its separator
+func (b barBox) setVisible(name string, show bool) {
+	for _, o := range b.widgets[name] {
+		if show {
+			o.Show()
+		} else {
+			o.Hide()
+		}
+	}
+	b.Refresh()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// logTailChunk is how far back from the end of the file to look for the last line
+const logTailChunk = 4096
+
+// logScrollStep is how many characters a wheel step moves a long line by
+const logScrollStep = 8
+
+// lastLine returns the last non-empty line of the file at path. It only
+// reads the final chunk, so large logs cost the same as small ones.
+func lastLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := info.Size() - logTailChunk
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	buf = bytes.TrimRight(buf, "\r\n")
+	if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+		buf = buf[i+1:]
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
+
+// logTailer returns a poll function that calls update with the latest line
+// of path whenever the file changed since the last poll, including after
+// truncation or rotation
+func logTailer(path string, update func(string)) func() {
+	var lastSize int64 = -1
+	var lastMod time.Time
+	return func() {
+		if info, err := os.Stat(path); err == nil {
+			if info.Size() != lastSize || !info.ModTime().Equal(lastMod) {
+				lastSize, lastMod = info.Size(), info.ModTime()
+				if line, err := lastLine(path); err == nil {
+					update(line)
+				}
+			}
+		} else if lastSize != -1 {
+			lastSize = -1
+			update("")
+		}
+	}
+}
+
+// logView shows the tailed line cut to maxLen characters; scrolling over it
+// pans through the rest of a longer line
+type logView struct {
+	label  *widget.Label
+	area   *scrollArea
+	prefix string
+	maxLen int
+
+	mu     sync.Mutex
+	line   []rune
+	offset int // of the first character shown
+}
+
+// newLogView creates the view of a log widget
+func newLogView(prefix string, maxLen int) *logView {
+	v := &logView{label: widget.NewLabel(""), prefix: prefix, maxLen: maxLen}
+	v.area = newScrollArea(v.label, v.scrolled)
+	return v
+}
+
+// set shows a new line from its start
+func (v *logView) set(line string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.line, v.offset = []rune(line), 0
+	v.render()
+}
+
+// scrolled pans a long line, towards its end for scrolling down
+func (v *logView) scrolled(ev *fyne.ScrollEvent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.maxLen <= 0 || len(v.line) <= v.maxLen {
+		return
+	}
+	switch {
+	case ev.Scrolled.DY < 0:
+		v.offset += logScrollStep
+	case ev.Scrolled.DY > 0:
+		v.offset -= logScrollStep
+	default:
+		return
+	}
+	v.offset = max(0, min(v.offset, len(v.line)-v.maxLen))
+	v.render()
+}
+
+// render shows maxLen characters from offset, marking each cut end with an
+// ellipsis like clampText; callers hold mu
+func (v *logView) render() {
+	text := v.line
+	if v.maxLen > 0 && len(v.line) > v.maxLen {
+		text = append([]rune(nil), v.line[v.offset:v.offset+v.maxLen]...)
+		if v.offset > 0 {
+			text[0] = '…'
+		}
+		if v.offset+v.maxLen < len(v.line) {
+			text[len(text)-1] = '…'
+		}
+	}
+	setLabelText(v.label, v.prefix+string(text))
+}
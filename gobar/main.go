@@ -1,216 +1,635 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/widget"
-	"github.com/BurntSushi/xgb"
-	"github.com/BurntSushi/xgb/xproto"
+	"fyne.io/fyne/v2/theme"
 	"github.com/getlantern/systray"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/mem"
 )
 
-// Converts uint32 slice to byte slice for X11 properties
-func uint32SliceToBytes(slice []uint32) []byte {
-	buf := new(bytes.Buffer)
-	for _, v := range slice {
-		_ = binary.Write(buf, binary.LittleEndian, v)
-	}
-	return buf.Bytes()
+// trayTooltip summarises the latest stats sample for the tray icon hover text
+func trayTooltip(cpuPercent, ramPercent, upRate float64, netUnit string) string {
+	return fmt.Sprintf("CPU %.0f%% · RAM %.0f%% · ↑%s", cpuPercent, ramPercent, compactRate(upRate, netUnit))
 }
 
-// Set X11 Dock properties
-func setDockProperties(winID uint32, barHeight int, screenWidth int) {
-	X, err := xgb.NewConn()
-	if err != nil {
-		log.Println("Failed to connect to X server:", err)
-		return
-	}
-	defer X.Close()
-
-	// Get atoms
-	netWMWindowType, _ := xproto.InternAtom(X, true, uint16(len("_NET_WM_WINDOW_TYPE")), "_NET_WM_WINDOW_TYPE").Reply()
-	netWMWindowTypeDock, _ := xproto.InternAtom(X, true, uint16(len("_NET_WM_WINDOW_TYPE_DOCK")), "_NET_WM_WINDOW_TYPE_DOCK").Reply()
-
-	// Set window type to DOCK
-	data := uint32SliceToBytes([]uint32{uint32(netWMWindowTypeDock.Atom)})
-	_ = xproto.ChangePropertyChecked(X, xproto.PropModeReplace, xproto.Window(winID),
-		netWMWindowType.Atom, xproto.AtomAtom, 32, 1, data).Check()
-
-	// Reserve space so Qtile does not overlap the bar
-	netWMStrut, _ := xproto.InternAtom(X, true, uint16(len("_NET_WM_STRUT_PARTIAL")), "_NET_WM_STRUT_PARTIAL").Reply()
-	strutPartial := []uint32{
-		0, 0, 0, uint32(barHeight), // left, right, bottom, top
-		0, 0, 0, 0, // left_start, left_end, right_start, right_end
-		0, uint32(screenWidth), // top_start, top_end
-		0, 0, // bottom_start, bottom_end
-	}
-	data = uint32SliceToBytes(strutPartial)
-	_ = xproto.ChangePropertyChecked(X, xproto.PropModeReplace, xproto.Window(winID),
-		netWMStrut.Atom, xproto.AtomCardinal, 32, uint32(len(strutPartial)), data).Check()
-
-	// Move window to (0,0)
-	_ = xproto.ConfigureWindowChecked(X, xproto.Window(winID),
-		xproto.ConfigWindowX|xproto.ConfigWindowY, []uint32{0, 0}).Check()
-}
-
-// scanApplications gets available .desktop applications
-func scanApplications(dir string) ([]string, error) {
-	var apps []string
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return apps, err
+func main() {
+	// gobar ctl talks to a running bar; so does a gobar-ctl symlink
+	if filepath.Base(os.Args[0]) == "gobar-ctl" {
+		os.Exit(runCtl(os.Args[1:]))
 	}
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".desktop") {
-			content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
-			if err != nil {
-				continue
-			}
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "Name=") {
-					name := strings.TrimPrefix(line, "Name=")
-					apps = append(apps, name)
-					break
-				}
-			}
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(runCtl(os.Args[2:]))
+	}
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof on this localhost address, e.g. localhost:6060")
+	replace := flag.Bool("replace", false, "take over from a gobar already running on the same output")
+	flag.Parse()
+	if *pprofAddr != "" {
+		if err := startPprof(*pprofAddr); err != nil {
+			log.Println("pprof disabled:", err)
 		}
 	}
-	return apps, nil
-}
 
-// System tray startup function
-func onReady() {
-	// Load tray icon from a PNG file
-	iconData, err := os.ReadFile("/home/junktop/.config/qtile/icon.png")
-	if err != nil {
-		log.Println("Failed to load system tray icon:", err)
-	} else {
-		systray.SetIcon(iconData)
+	// Without an X server Fyne and systray would panic deep in a goroutine,
+	// and a DISPLAY that is set can still point at one that is gone
+	if _, err := barConn(); err != nil {
+		fmt.Fprintln(os.Stderr, "gobar: no usable X display:", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if errors.Is(err, errInvalidConfig) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	} else if err != nil {
+		log.Println("Failed to load config, using defaults:", err)
+	}
+	// Always on Top and pinned apps chosen in the bar
+	loadCache().applyTo(&cfg)
+	themeIcons.use(cfg.IconTheme)
+
+	// One bar per output: a reload always takes over
+	instance, err := claimInstance(cfg.Output, *replace || os.Getenv(reloadEnv) != "")
+	if errors.Is(err, errInstanceRunning) {
+		fmt.Fprintln(os.Stderr, "gobar:", err)
+		os.Exit(1)
+	} else if err != nil {
+		log.Println("Not checking for a running gobar:", err)
 	}
+	// Like the instance lock, the socket goes by the configured output
+	socketPath := controlSocketPath(cfg)
 
-	systray.SetTitle("System Tray")
-	systray.SetTooltip("Qtile Go Taskbar")
+	// The other bars go on the other monitors, so the main bar takes one
+	if (cfg.MirrorOutputs || cfg.AllOutputs) && cfg.Output == "" {
+		if cfg.Output, err = firstActiveOutput(); err != nil {
+			log.Println("Only one bar, cannot list the outputs:", err)
+			cfg.MirrorOutputs, cfg.AllOutputs = false, false
+		}
+	}
 
-	// Example tray icons
-	mSteam := systray.AddMenuItem("Steam", "Open Steam")
-	mFlameshot := systray.AddMenuItem("Flameshot", "Screenshot Tool")
-	mQuit := systray.AddMenuItem("Quit", "Exit")
+	// Start system tray in a separate goroutine
+	go systray.Run(func() { onReady(cfg) }, func() {})
 
+	// SIGUSR2 lets scripts flag the tray icon for attention
+	attention := make(chan os.Signal, 1)
+	signal.Notify(attention, syscall.SIGUSR2)
 	go func() {
-		for {
-			select {
-			case <-mSteam.ClickedCh:
-				if _, err := os.StartProcess("/usr/bin/steam", []string{}, &os.ProcAttr{}); err != nil {
-					log.Println("Failed to start Steam:", err)
-				}
-			case <-mFlameshot.ClickedCh:
-				if _, err := os.StartProcess("/usr/bin/flameshot", []string{"gui"}, &os.ProcAttr{}); err != nil {
-					log.Println("Failed to start Flameshot:", err)
-				}
-			case <-mQuit.ClickedCh:
-				systray.Quit()
+		for range attention {
+			if trayReady.Load() {
+				tray.setAttention(cfg.AttentionBlink)
 			}
 		}
 	}()
-}
+	// Switching to another window counts as having seen it, like a tray click
+	if _, err := watchRootProperties([]string{"_NET_ACTIVE_WINDOW"}, tray.clearAttention); err != nil {
+		log.Println("Tray attention only clears from the tray menu:", err)
+	}
 
-func main() {
-	// Start system tray in a separate goroutine
-	go systray.Run(onReady, func() {})
+	// SIGUSR1 shows or hides the bar, e.g. from a Qtile keybinding
+	toggle := make(chan os.Signal, 1)
+	signal.Notify(toggle, syscall.SIGUSR1)
+	// The control socket shows or hides it explicitly; only the latest
+	// request is kept until the dock loop takes it
+	visibility := make(chan bool, 1)
 
 	myApp := app.New()
-	w := myApp.NewWindow("Go Taskbar")
-
-	// Set bar size
-	screenWidth := float32(1920) // Adjust as needed
-	barHeight := float32(30)
-	w.Resize(fyne.NewSize(screenWidth, barHeight))
+	// Periodic work runs on the scheduler and stops when the app quits
+	ctx, cancel := context.WithCancel(context.Background())
+	myApp.Lifecycle().SetOnStopped(func() {
+		cancel()
+		if trayReady.Load() {
+			systray.Quit()
+		}
+	})
+	sched := newScheduler(ctx)
 
-	// Create widgets
-	timeLabel := widget.NewLabel("Time: ")
-	cpuLabel := widget.NewLabel("CPU: ")
-	netLabel := widget.NewLabel("Network: ")
+	// SIGINT, SIGTERM and being replaced by a new gobar quit like the Quit
+	// item, after undocking the bar once it is docked. A second signal exits
+	// at once, e.g. with a task stuck in a slow command.
+	var undock atomic.Pointer[func()]
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGINT, syscall.SIGTERM)
+	if instance != nil {
+		go func() {
+			<-instance.Lost()
+			log.Println("Replaced by a new gobar, exiting")
+			term <- syscall.SIGTERM
+		}()
+	}
+	go func() {
+		<-term
+		if release := undock.Load(); release != nil {
+			(*release)()
+		}
+		myApp.Quit()
+		<-term
+		os.Exit(1)
+	}()
+	// A reload re-executes gobar; startup commands only run the first time
+	reloaded := os.Getenv(reloadEnv) != ""
+	confirmReload(myApp)
 
-	// "Start Menu" button
-	startMenuButton := widget.NewButton("Start Menu", func() {
-		apps, err := scanApplications("/usr/share/applications")
+	// SIGHUP and the tray's Reload Config item restart with the new config
+	tray.mu.Lock()
+	tray.onReload = func() {
+		if err := reloadConfig(); err != nil {
+			log.Println("Config reload failed:", err)
+			showConfigErrors(myApp, err)
+		}
+	}
+	tray.onEdit = func() {
+		path := configPath()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Println("Failed to create config directory:", err)
+		}
+		cmdline, err := editConfigCommand(cfg, path)
 		if err != nil {
-			dialog.ShowError(err, w)
+			showMessageWindow(myApp, "Edit Config", err.Error())
 			return
 		}
-		list := widget.NewList(
-			func() int { return len(apps) },
-			func() fyne.CanvasObject { return widget.NewLabel("") },
-			func(i widget.ListItemID, o fyne.CanvasObject) {
-				o.(*widget.Label).SetText(apps[i])
-			},
-		)
-		d := dialog.NewCustom("Installed Applications", "Close", container.NewVScroll(list), w)
-		d.Show()
-	})
-
-	// System Tray Placeholder
-	trayLabel := widget.NewLabel("🖥️ System Tray")
-
-	// Arrange widgets horizontally
-	statusBar := container.NewHBox(
-		startMenuButton,
-		widget.NewSeparator(),
-		timeLabel,
-		widget.NewSeparator(),
-		cpuLabel,
-		widget.NewSeparator(),
-		netLabel,
-		widget.NewSeparator(),
-		trayLabel, // Placeholder for system tray
-	)
-
-	w.SetContent(statusBar)
-
-	// Update stats every second
+		launchCommand(cmdline)
+	}
+	tray.mu.Unlock()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		for {
-			timeLabel.SetText("Time: " + time.Now().Format("15:04:05"))
+		for range hup {
+			if err := reloadConfig(); err != nil {
+				log.Println("Config reload failed:", err)
+			}
+		}
+	}()
+	barTheme := newBarTheme()
+	if cfg.FontPath != "" {
+		if err := barTheme.loadFont(expandPath(cfg.FontPath)); err != nil {
+			log.Println("Failed to load font, using default:", err)
+		}
+	}
+	barTheme.textSize = cfg.FontSize
+	if cfg.ForegroundColor != "" {
+		barTheme.fixed[theme.ColorNameForeground], _ = parseHexColor(cfg.ForegroundColor)
+	}
+	if cfg.WalColorsPath != "" {
+		walPath := expandPath(cfg.WalColorsPath)
+		if _, err := barTheme.loadWalColors(walPath); err != nil {
+			log.Println("Failed to load pywal colours, using the theme:", err)
+		}
+		// Re-theme live when wal writes a new palette
+		sched.Watch("wal", []string{walPath}, 5*time.Second, func() {
+			changed, err := barTheme.loadWalColors(walPath)
+			if err != nil {
+				log.Println("Failed to reload pywal colours:", err)
+			} else if changed {
+				myApp.Settings().SetTheme(barTheme)
+			}
+		})
+	}
+	myApp.Settings().SetTheme(barTheme)
+
+	// Bar size; without a BarHeight the height grows to fit the content below.
+	// Output places the bar on one monitor, e.g. the main one.
+	monitor, err := monitorGeometry(cfg.Output)
+	// Narrow monitors switch their bar to the terse widget formats; the bars
+	// of other outputs decide for theirs from the configured Compact
+	compact := cfg.Compact
+	if err != nil {
+		log.Println("Assuming a 1920x1080 screen:", err)
+		monitor.Area = defaultScreenArea
+	} else {
+		cfg.Compact = cfg.compactOn(monitor)
+	}
+	barHeight := cfg.BarHeight
+	if barHeight <= 0 {
+		barHeight = defaultBarHeight
+	}
+	bar := createBar(myApp, cfg, "Go Taskbar", monitor, barHeight)
+	w := bar.win
+	vertical := cfg.Orientation == "vertical"
+	iconSize := barIconSize(barHeight)
+
+	// Widgets shared by the bars: popups, and sources doing their work once
+	// for the bars of every output
+	calendar := newCalendarPopup(myApp)
+	env := &barEnv{
+		app:     myApp,
+		clock:   newClockWidget(cfg, calendar),
+		qtile:   &qtileClient{path: qtileSocketPath(cfg.QtileSocket)},
+		desktop: &desktopToggler{},
+	}
+	// "Start Menu" button, listing applications indexed in the background
+	appDirs := applicationDirs()
+	if cfg.AppsDir != "" {
+		appDirs = []string{expandPath(cfg.AppsDir)}
+	}
+	apps := newAppIndex(appDirs, cfg.HiddenApps)
+	if err := apps.Watch(ctx); err != nil {
+		log.Println("Start Menu rescans on every open, cannot watch the application directories:", err)
+	}
+	go apps.Entries()
+	menu := newStartMenu(myApp, apps, fyne.NewSize(cfg.StartMenuWidth, cfg.StartMenuHeight), cfg.StartMenuSort)
+	menu.onPin = func(e DesktopEntry) {
+		pinToTray(&cfg, TrayLauncher{Name: e.Name, Command: e.command(cfg), Dir: e.workDir()})
+	}
+	menu.onLaunch = func(e DesktopEntry) { launchEntry(cfg, e) }
+	env.menu = menu
+	if cfg.ShowRunButton {
+		env.run = newRunDialog(myApp)
+	}
+	// The idle widget and idle dimming share one X Screensaver source
+	var dimmer *idleDimmer
+	if cfg.ShowIdle || cfg.IdleDimSec > 0 {
+		if idle, err := newIdleSource(); err != nil {
+			log.Println("Idle widget and dimming disabled, X Screensaver extension unavailable:", err)
+		} else {
+			if cfg.ShowIdle {
+				env.idle = idle
+			}
+			if cfg.IdleDimSec > 0 {
+				dimmer = newIdleDimmer(time.Duration(cfg.IdleDimSec)*time.Second, cfg.IdleDimOpacity, cfg.Opacity)
+				sched.Every("idle-dim", time.Second, func() {
+					if d, err := idle.Idle(); err == nil {
+						dimmer.Update(d)
+					}
+				})
+			}
+		}
+	}
+	for _, spec := range cfg.CustomWidgets {
+		custom := newCustomWidget(spec)
+		env.customs = append(env.customs, custom)
+		sched.Every("custom:"+spec.Name, custom.interval(), custom.Update)
+	}
+	for _, spec := range cfg.Plugins {
+		plugin := newPluginWidget(spec)
+		env.plugins = append(env.plugins, plugin)
+		sched.Supervise("plugin:"+spec.Name, func() error { return plugin.Run(ctx) })
+	}
+	// The daemon's popups are anchored to the main bar once its geometry is known
+	popups := newNotificationPopups(myApp)
+	if cfg.ShowNotifications || cfg.NotificationDaemon {
+		queue := &notificationQueue{max: cfg.NotificationQueueMax, dnd: cfg.NotificationDND}
+		var err error
+		if cfg.NotificationDaemon {
+			if _, err = serveNotifications(queue, popups, cfg); err != nil {
+				log.Println("Not serving notifications:", err)
+			}
+		}
+		if !cfg.NotificationDaemon || err != nil {
+			// Count the notifications another daemon shows
+			err = monitorNotifications(queue)
+		}
+		if cfg.ShowNotifications {
+			if err != nil {
+				log.Println("Notification badge disabled, cannot monitor D-Bus:", err)
+			} else {
+				env.notifications = newNotificationCenter(myApp, queue)
+			}
+		}
+	}
+	if cfg.ShowPublicIP {
+		interval := time.Duration(cfg.PublicIPIntervalSec) * time.Second
+		env.publicIP = newPublicIPWidget(cfg.PublicIPURL, interval, w.Clipboard())
+		sched.Every("publicip", min(interval, publicIPRetry), env.publicIP.Update)
+	}
+
+	// Prometheus export of the sampled values; nil when off
+	var metrics *metricsRegistry
+	if cfg.MetricsAddr != "" {
+		metrics = newMetricsRegistry()
+		if err := serveMetrics(cfg.MetricsAddr, metrics); err != nil {
+			log.Println("Metrics export disabled:", err)
+			metrics = nil
+		}
+	}
 
-			// CPU Usage
-			percents, _ := cpu.Percent(0, false)
-			if len(percents) > 0 {
-				cpuLabel.SetText(fmt.Sprintf("CPU: %.2f%%", percents[0]))
+	view := env.build(ctx, sched, cfg, bar, metrics)
+	statusBar := view.box
+	// Application tray icons; gobar becomes the StatusNotifierWatcher when
+	// nothing else provides one
+	var host *trayHost
+	if cfg.TrayHost {
+		if host, err = newTrayHost(iconSize); err != nil {
+			log.Println("Tray icons disabled, cannot use D-Bus:", err)
+		}
+	}
+	// XEmbed tray icons, docked once the bar window exists
+	var xembed *xembedTray
+	if cfg.XembedTray {
+		if xembed, err = newXembedTray(iconSize); err != nil {
+			log.Println("XEmbed tray disabled, X connection failed:", err)
+		} else if host == nil {
+			statusBar.add("xembed", xembed.CanvasObject())
+		}
+	}
+	// Without a StatusNotifierWatcher the tray icon may never appear
+	if host != nil && xembed != nil {
+		// Both kinds of icons share the tray area
+		statusBar.add("tray", container.NewHBox(host.CanvasObject(), xembed.CanvasObject()))
+	} else if host != nil {
+		statusBar.add("tray", host.CanvasObject())
+	} else if running, err := statusNotifierWatcherRunning(); err == nil && !running {
+		log.Println("No StatusNotifierWatcher on D-Bus: the tray icon needs a tray host (or an XEmbed tray such as Qtile's Systray widget) to appear")
+		if cfg.TrayFallbackButtons {
+			tray.fallback = container.NewHBox()
+			for _, l := range cfg.TrayLaunchers {
+				tray.addLauncherButtons(l)
 			}
+			statusBar.add("tray", tray.fallback)
+		}
+	}
+	content := view.content(myApp, cfg)
+	w.SetContent(content)
+
+	// Fit the tallest widget, e.g. with a large font, so nothing is clipped
+	if cfg.BarHeight <= 0 && !vertical {
+		barHeight = max(barHeight, float32(math.Ceil(float64(content.MinSize().Height))))
+	}
+	bar.place(cfg, monitor, barHeight)
+	geometry := bar.geometry
+	popups.SetAnchor(geometry)
 
-			// Network Usage
-			netIO, _ := net.IOCounters(false)
-			if len(netIO) > 0 {
-				netLabel.SetText(fmt.Sprintf("Network: ↑%d ↓%d", netIO[0].BytesSent, netIO[0].BytesRecv))
+	// The clock runs on its own schedule, per minute when neither the local
+	// nor the extra clocks show seconds
+	var lastTick time.Time
+	sched.EveryAligned("clock", env.clock.interval(), func() {
+		now := time.Now()
+		env.clock.Update(now)
+		// Once per day boundary, including one passed during suspend
+		if dayRollover(lastTick, now) {
+			calendar.Refresh(now)
+			if cfg.OnDayChange != "" {
+				launchCommand(cfg.OnDayChange)
 			}
+		}
+		lastTick = now
+	})
 
-			time.Sleep(time.Second)
+	// The battery widget is polled
+	if cfg.ShowBattery && cfg.BatteryNotify {
+		var alarm batteryAlarm
+		sched.Every("battery-alarm", time.Duration(cfg.SysfsPollMs)*time.Millisecond, func() {
+			if batteries, err := readBatteries(); err == nil {
+				combined := combineBatteries(batteries)
+				if alarm.check(combined, cfg.Thresholds["battery"].Crit) {
+					myApp.SendNotification(fyne.NewNotification("Battery low", combined.details()))
+				}
+			}
+		})
+	}
+	// The tray icon's hover text sums up the stats; the CPU sample is shared
+	// with the cpu widget
+	var trayRate netRate
+	sched.Every("tray", time.Second, func() {
+		if !trayReady.Load() {
+			return
 		}
-	}()
+		cpuPercent, _, _ := cpuUsage.sample()
+		var ramPercent, upRate float64
+		if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+			ramPercent = vm.UsedPercent
+		}
+		if shown, _, _ := shownCounters(ctx, cfg.NetInterface); len(shown) > 0 {
+			upRate, _ = trayRate.update(time.Now(), shown[0])
+		} else {
+			trayRate.reset()
+		}
+		systray.SetTooltip(trayTooltip(cpuPercent, ramPercent, upRate, cfg.NetUnit))
+	})
 
 	// Show window
 	w.Show()
-
-	// Set dock properties
-	if x11Win, ok := w.(interface{ X11Window() uintptr }); ok {
-		go setDockProperties(uint32(x11Win.X11Window()), int(barHeight), int(screenWidth))
+	// The bars on the other monitors: with AllOutputs each is built like this
+	// one, with MirrorOutputs they show a picture of it
+	var others *outputBars
+	if cfg.AllOutputs {
+		others = newOutputBars(cfg.Output, barHeight, func(m MonitorGeometry) outputBar {
+			c := cfg
+			c.Compact = compact
+			return newFullBar(env, sched, c, m, barHeight)
+		})
+	} else if cfg.MirrorOutputs {
+		others = newOutputBars(cfg.Output, barHeight, func(m MonitorGeometry) outputBar {
+			b := newMirrorBar(myApp, cfg, m, barHeight)
+			b.Show()
+			return b
+		})
+		sched.Every("mirror", time.Second, func() { others.refresh(w.Canvas()) })
+	}
+	if others != nil {
+		if err := others.sync(); err != nil {
+			log.Println("No bars on the other monitors:", err)
+		}
+	}
+	var control io.Closer
+	if socketPath != "" {
+		bars := func() []barBox {
+			if others == nil {
+				return []barBox{statusBar}
+			}
+			return append([]barBox{statusBar}, others.boxes()...)
+		}
+		server := &controlServer{bars: bars, sched: sched, visible: visibility, toggle: toggle, launcher: menu.Show}
+		if control, err = listenControl(socketPath, server); err != nil {
+			log.Println("Control socket disabled:", err)
+		}
 	}
+	if !reloaded {
+		for _, cmdline := range cfg.StartupCommands {
+			launchCommand(cmdline)
+		}
+	}
+
+	// Set dock properties once the native window exists
+	go func() {
+		winID, err := bar.dock(cfg.ReserveSpace, cfg.AlwaysOnTop)
+		ok := !errors.Is(err, errNoX11Window)
+		if !ok {
+			view.showBanner("No X11 window, bar is not docked")
+		} else if err != nil {
+			view.showBanner("Dock setup failed, bar is not docked: " + err.Error())
+		}
+		if ok {
+			// The tray's Always on Top item restacks the bar and saves the choice
+			tray.mu.Lock()
+			tray.onAlwaysOnTop = func(above bool) bool {
+				if err := setAlwaysOnTop(winID, above); err != nil {
+					log.Println("Failed to change always-on-top:", err)
+					return false
+				}
+				state := loadCache()
+				state.AlwaysOnTop = &above
+				if err := saveCache(state); err != nil {
+					log.Println("Failed to save AlwaysOnTop:", err)
+				}
+				return true
+			}
+			tray.mu.Unlock()
+		}
+		if ok && xembed != nil {
+			if err := xembed.Start(winID, w.Canvas()); err != nil {
+				log.Println("XEmbed tray disabled:", err)
+			} else {
+				sched.Every("xembed", time.Second, xembed.Update)
+			}
+		}
+		if ok && dimmer != nil {
+			dimmer.winID.Store(winID)
+		}
+		if ok {
+			view.decorate(sched, cfg, winID, w)
+		}
+
+		// Never leave a dead gap behind: release the strut when the window
+		// closes, and also unmap the bar when gobar is terminated, rather
+		// than waiting for the WM to notice the window is gone
+		if ok {
+			release := func() {
+				if !cfg.ReserveSpace {
+					return
+				}
+				if err := clearStrut(winID); err != nil {
+					log.Println("Failed to release strut:", err)
+				}
+			}
+			w.SetOnClosed(release)
+			shutdown := func() {
+				if others != nil {
+					others.Close()
+				}
+				release()
+				if err := unmapWindow(winID); err != nil {
+					log.Println("Failed to unmap the bar:", err)
+				}
+			}
+			undock.Store(&shutdown)
+		}
+
+		var hider *autoHider
+		var ticker *time.Ticker
+		var poll <-chan time.Time
+		var reveal <-chan struct{}
+		if cfg.AutoHide {
+			var err error
+			if hider, err = newAutoHider(geometry, time.Duration(cfg.AutoHideDelayMs)*time.Millisecond); err != nil {
+				log.Println("Auto-hide disabled, X connection failed:", err)
+			} else {
+				ticker = time.NewTicker(autoHidePoll)
+				poll = ticker.C
+				reveal = hider.reveal
+			}
+		}
+
+		// Hiding also releases the strut so Qtile reclaims the space
+		setVisible := func(visible bool) {
+			if hider != nil {
+				hider.showStrip(!visible)
+				// The reveal strip watches a hidden bar instead of the ticker
+				if hider.polls(visible) {
+					ticker.Reset(autoHidePoll)
+				} else {
+					ticker.Stop()
+				}
+			}
+			if ok && cfg.ReserveSpace {
+				if err := setStrut(winID, geometry, visible); err != nil {
+					log.Println("Failed to update strut:", err)
+				}
+			}
+			if visible {
+				w.Show()
+			} else {
+				w.Hide()
+			}
+			if cfg.AllOutputs && others != nil {
+				others.setVisible(visible)
+			}
+		}
+		visible := !cfg.StartHidden
+		if !visible {
+			setVisible(false)
+		}
+		// Follow monitor hotplug and mode changes with a new size, position
+		// and strut
+		screens := make(chan struct{}, 1)
+		if err := watchScreenChanges(func() {
+			select {
+			case screens <- struct{}{}:
+			default:
+			}
+		}); err != nil {
+			log.Println("Not following screen changes:", err)
+		}
+		for {
+			select {
+			case <-toggle:
+				visible = !visible
+				setVisible(visible)
+			case v := <-visibility:
+				if v != visible {
+					visible = v
+					setVisible(visible)
+				}
+			case <-screens:
+				if others != nil {
+					if err := others.sync(); err != nil {
+						log.Println("Failed to update the bars of the other outputs:", err)
+					}
+				}
+				m, err := monitorGeometry(cfg.Output)
+				if err != nil {
+					log.Println("Failed to read the new screen layout:", err)
+					continue
+				}
+				if !bar.place(cfg, m, barHeight) {
+					continue
+				}
+				geometry = bar.geometry
+				popups.SetAnchor(geometry)
+				if ok {
+					if err := redock(winID, geometry, cfg.ReserveSpace && visible); err != nil {
+						log.Println("Failed to move the bar to the new screen layout:", err)
+					}
+				}
+				if hider != nil {
+					hider.geometry = geometry
+					hider.showStrip(!visible)
+				}
+			case <-poll:
+				if v := hider.next(visible); v != visible {
+					visible = v
+					setVisible(visible)
+				}
+			case <-reveal:
+				if !visible {
+					visible = true
+					setVisible(visible)
+				}
+			}
+		}
+	}()
 
 	myApp.Run()
+	// Closing the listener removes the socket file
+	if control != nil {
+		control.Close()
+	}
+	// Let running tasks finish before the X connections go away with the process
+	if !sched.WaitTimeout(2 * time.Second) {
+		log.Println("Exiting with tasks still running")
+	}
 }
-
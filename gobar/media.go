@@ -0,0 +1,365 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"log"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// MPRIS names
+const (
+	mprisPrefix      = "org.mpris.MediaPlayer2."
+	mprisPath        = "/org/mpris/MediaPlayer2"
+	mprisPlayerIface = "org.mpris.MediaPlayer2.Player"
+)
+
+// mediaWidget shows the current MPRIS track with a thin progress bar that
+// seeks when clicked, optionally next to the album art and followed by
+// previous, play/pause and next buttons. It is hidden while no player is
+// running.
+type mediaWidget struct {
+	conn     *dbus.Conn
+	label    *widget.Label
+	progress *seekBar
+	art      *canvas.Image
+	arts     *artCache // nil when art is off
+	playing  *widget.Button
+	box      *fyne.Container
+	maxLen   int // characters of "artist – title"
+
+	mu      sync.Mutex
+	player  string
+	trackID dbus.ObjectPath
+	length  int64  // microseconds
+	artURL  string // mpris:artUrl shown or being loaded
+}
+
+// newMediaWidget connects to the session bus and subscribes to the players'
+// property changes; the track text is clamped to maxLen characters. With
+// artSize above 0 the album art is shown, scaled to artSize pixels, and
+// controls adds the playback buttons.
+func newMediaWidget(maxLen, artSize int, controls bool) (*mediaWidget, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range [][]dbus.MatchOption{
+		{dbus.WithMatchObjectPath(mprisPath), dbus.WithMatchInterface("org.freedesktop.DBus.Properties"), dbus.WithMatchMember("PropertiesChanged")},
+		// Players starting and quitting
+		{dbus.WithMatchInterface("org.freedesktop.DBus"), dbus.WithMatchMember("NameOwnerChanged"), dbus.WithMatchArg0Namespace("org.mpris.MediaPlayer2")},
+	} {
+		if err := conn.AddMatchSignal(match...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	m := &mediaWidget{conn: conn, label: widget.NewLabel(""), maxLen: maxLen, art: canvas.NewImageFromImage(nil)}
+	m.progress = newSeekBar(m.seek)
+	m.art.FillMode = canvas.ImageFillContain
+	m.art.Hide()
+	if artSize > 0 {
+		m.arts = newArtCache(artSize)
+		m.art.SetMinSize(fyne.NewSize(float32(artSize), float32(artSize)))
+	}
+	var buttons fyne.CanvasObject
+	if controls {
+		m.playing = m.control("⏯", "PlayPause")
+		buttons = container.NewHBox(m.control("⏮", "Previous"), m.playing, m.control("⏭", "Next"))
+	}
+	m.box = container.NewBorder(nil, m.progress, container.NewCenter(m.art), buttons, m.label)
+	m.box.Hide()
+	return m, nil
+}
+
+// control creates a playback button calling method on the current player
+func (m *mediaWidget) control(label, method string) *widget.Button {
+	b := widget.NewButton(label, func() { m.call(method) })
+	b.Importance = widget.LowImportance
+	return b
+}
+
+// CanvasObject returns the object to place in the bar
+func (m *mediaWidget) CanvasObject() fyne.CanvasObject {
+	return m.box
+}
+
+// Run updates the widget on each player property change, so a new track or
+// a pause shows at once rather than on the next tick; call it in its own
+// goroutine
+func (m *mediaWidget) Run(prefix string) {
+	signals := make(chan *dbus.Signal, 16)
+	m.conn.Signal(signals)
+	// A restart after a panic subscribes afresh
+	defer m.conn.RemoveSignal(signals)
+	for range signals {
+		m.Update(prefix)
+	}
+}
+
+// Close ends Run by closing the bus connection
+func (m *mediaWidget) Close() {
+	m.conn.Close()
+}
+
+// call runs a method without arguments, such as Next, on the current player
+func (m *mediaWidget) call(method string) {
+	m.mu.Lock()
+	player := m.player
+	m.mu.Unlock()
+	if player == "" {
+		return
+	}
+	if call := m.conn.Object(player, mprisPath).Call(mprisPlayerIface+"."+method, 0); call.Err != nil {
+		log.Printf("Failed to call %s on %s: %v", method, player, call.Err)
+	}
+}
+
+// Update refreshes the track and position; called every second for the
+// position
+func (m *mediaWidget) Update(prefix string) {
+	player, err := m.findPlayer()
+	if err != nil {
+		m.box.Hide()
+		return
+	}
+	obj := m.conn.Object(player, mprisPath)
+	var metadata map[string]dbus.Variant
+	if v, err := obj.GetProperty(mprisPlayerIface + ".Metadata"); err == nil {
+		_ = v.Store(&metadata)
+	}
+	var position int64
+	if v, err := obj.GetProperty(mprisPlayerIface + ".Position"); err == nil {
+		position = variantInt64(v)
+	}
+	if m.playing != nil {
+		label := "▶"
+		if v, err := obj.GetProperty(mprisPlayerIface + ".PlaybackStatus"); err == nil && v.Value() == "Playing" {
+			label = "⏸"
+		}
+		if m.playing.Text != label {
+			m.playing.SetText(label)
+		}
+	}
+
+	title, _ := metadata["xesam:title"].Value().(string)
+	artists, _ := metadata["xesam:artist"].Value().([]string)
+	trackID, _ := metadata["mpris:trackid"].Value().(dbus.ObjectPath)
+	artURL, _ := metadata["mpris:artUrl"].Value().(string)
+	length := variantInt64(metadata["mpris:length"])
+	if title == "" {
+		m.box.Hide()
+		return
+	}
+	text := title
+	if len(artists) > 0 {
+		text = strings.Join(artists, ", ") + " – " + title
+	}
+
+	m.mu.Lock()
+	m.player, m.trackID, m.length = player, trackID, length
+	m.mu.Unlock()
+
+	setLabelText(m.label, prefix+clampText(text, m.maxLen))
+	m.showArt(artURL)
+	if length > 0 {
+		m.progress.SetValue(float32(position) / float32(length))
+		m.progress.Show()
+	} else {
+		// Streams have no length to seek within
+		m.progress.Hide()
+	}
+	m.box.Show()
+}
+
+// showArt shows the album art at rawURL, loading it in the background the
+// first time; the art hides while it loads or when there is none
+func (m *mediaWidget) showArt(rawURL string) {
+	if m.arts == nil {
+		return
+	}
+	m.mu.Lock()
+	changed := m.artURL != rawURL
+	m.artURL = rawURL
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+	if rawURL == "" {
+		m.art.Hide()
+		return
+	}
+	if img, ok := m.arts.get(rawURL); ok {
+		m.setArt(img)
+		return
+	}
+	m.art.Hide()
+	go func() {
+		img, err := m.arts.load(rawURL)
+		if err != nil {
+			log.Println("Failed to load album art:", err)
+			return
+		}
+		m.mu.Lock()
+		current := m.artURL == rawURL
+		m.mu.Unlock()
+		if current {
+			m.setArt(img)
+		}
+	}()
+}
+
+// setArt displays img as the album art
+func (m *mediaWidget) setArt(img image.Image) {
+	m.art.Image = img
+	m.art.Refresh()
+	m.art.Show()
+}
+
+// findPlayer returns the first playing MPRIS player, or else the first one found
+func (m *mediaWidget) findPlayer() (string, error) {
+	var names []string
+	if err := m.conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return "", err
+	}
+	var first string
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisPrefix) {
+			continue
+		}
+		if first == "" {
+			first = name
+		}
+		v, err := m.conn.Object(name, mprisPath).GetProperty(mprisPlayerIface + ".PlaybackStatus")
+		if status, _ := v.Value().(string); err == nil && status == "Playing" {
+			return name, nil
+		}
+	}
+	if first == "" {
+		return "", errors.New("no MPRIS player")
+	}
+	return first, nil
+}
+
+// seek moves playback to fraction of the current track
+func (m *mediaWidget) seek(fraction float32) {
+	m.mu.Lock()
+	player, trackID, length := m.player, m.trackID, m.length
+	m.mu.Unlock()
+	if player == "" || trackID == "" || length <= 0 {
+		return
+	}
+	position := int64(float64(fraction) * float64(length))
+	obj := m.conn.Object(player, mprisPath)
+	if call := obj.Call(mprisPlayerIface+".SetPosition", 0, trackID, position); call.Err != nil {
+		log.Println("Failed to seek:", call.Err)
+		return
+	}
+	m.progress.SetValue(fraction)
+}
+
+// variantInt64 reads an integer property; players differ between int64 and uint64
+func variantInt64(v dbus.Variant) int64 {
+	switch n := v.Value().(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	}
+	return 0
+}
+
+// seekBarHeight is the thickness of the media progress bar
+const seekBarHeight = 3
+
+// seekBar is a thin progress bar; clicking it reports the clicked fraction
+type seekBar struct {
+	widget.BaseWidget
+	onSeek func(float32)
+
+	mu    sync.Mutex
+	value float32
+}
+
+// newSeekBar creates a seekBar calling onSeek with the clicked position, 0..1
+func newSeekBar(onSeek func(float32)) *seekBar {
+	s := &seekBar{onSeek: onSeek}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// SetValue sets the filled fraction, 0..1
+func (s *seekBar) SetValue(v float32) {
+	v = min(max(v, 0), 1)
+	s.mu.Lock()
+	changed := s.value != v
+	s.value = v
+	s.mu.Unlock()
+	if changed {
+		s.Refresh()
+	}
+}
+
+// Tapped implements fyne.Tappable
+func (s *seekBar) Tapped(ev *fyne.PointEvent) {
+	if width := s.Size().Width; width > 0 && s.onSeek != nil {
+		s.onSeek(min(max(ev.Position.X/width, 0), 1))
+	}
+}
+
+// CreateRenderer draws a track rectangle with the filled part on top
+func (s *seekBar) CreateRenderer() fyne.WidgetRenderer {
+	r := &seekBarRenderer{bar: s, track: canvas.NewRectangle(color.Transparent), fill: canvas.NewRectangle(color.Transparent)}
+	r.Refresh()
+	return r
+}
+
+// seekBarRenderer lays out a seekBar's rectangles
+type seekBarRenderer struct {
+	bar         *seekBar
+	track, fill *canvas.Rectangle
+}
+
+// Layout stretches the track and fills it up to the position
+func (r *seekBarRenderer) Layout(size fyne.Size) {
+	r.bar.mu.Lock()
+	value := r.bar.value
+	r.bar.mu.Unlock()
+	r.track.Resize(size)
+	r.fill.Resize(fyne.NewSize(size.Width*value, size.Height))
+}
+
+// MinSize is the bar's height; it takes the width it is given
+func (r *seekBarRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, seekBarHeight)
+}
+
+// Refresh recolours the track and fill and lays them out
+func (r *seekBarRenderer) Refresh() {
+	r.track.FillColor = theme.Color(theme.ColorNameInputBackground)
+	r.fill.FillColor = theme.Color(theme.ColorNamePrimary)
+	r.Layout(r.bar.Size())
+	r.track.Refresh()
+	r.fill.Refresh()
+}
+
+// Objects returns the track and the fill
+func (r *seekBarRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.track, r.fill}
+}
+
+// Destroy has nothing to release
+func (r *seekBarRenderer) Destroy() {}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // covers from Spotify and most players
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// artCacheSize bounds the album art kept in memory, a playlist's worth
+const artCacheSize = 32
+
+// artFetchLimit caps the size of downloaded album art
+const artFetchLimit = 8 << 20
+
+// artCache holds album art scaled to the bar, by mpris:artUrl
+type artCache struct {
+	size int
+
+	mu     sync.Mutex
+	images map[string]image.Image
+}
+
+// newArtCache creates a cache scaling art to size pixels
+func newArtCache(size int) *artCache {
+	return &artCache{size: size, images: map[string]image.Image{}}
+}
+
+// get returns the cached art for rawURL, if loaded
+func (c *artCache) get(rawURL string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img, ok := c.images[rawURL]
+	return img, ok
+}
+
+// load reads the art at a file:// or http(s):// URL, scales and caches it
+func (c *artCache) load(rawURL string) (image.Image, error) {
+	img, err := loadArt(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	img = scaleIcon(img, c.size)
+	c.mu.Lock()
+	if len(c.images) >= artCacheSize {
+		// Starting over is simpler than tracking use and just as good for
+		// a cache this small
+		c.images = map[string]image.Image{}
+	}
+	c.images[rawURL] = img
+	c.mu.Unlock()
+	return img, nil
+}
+
+// loadArt decodes the image at a file:// or http(s):// URL
+func loadArt(rawURL string) (image.Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var r io.ReadCloser
+	switch u.Scheme {
+	case "file":
+		if r, err = os.Open(u.Path); err != nil {
+			return nil, err
+		}
+	case "http", "https":
+		client := http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("album art: %s", resp.Status)
+		}
+		r = resp.Body
+	default:
+		return nil, errors.New("album art: unsupported URL " + rawURL)
+	}
+	defer r.Close()
+	img, _, err := image.Decode(io.LimitReader(r, artFetchLimit))
+	return img, err
+}
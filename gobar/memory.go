@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// memoryWidget shows the used physical memory, with an optional sparkline
+type memoryWidget struct {
+	cfg     Config
+	label   *colorLabel
+	graph   *sparkline
+	view    fyne.CanvasObject
+	metrics *metricsRegistry
+}
+
+// init registers the RAM widget, shown wherever gopsutil can read memory
+func init() {
+	registerWidget("ram", func(Config) bool { return statSupport().mem.Available() }, func(cfg Config) (Widget, error) {
+		return newMemoryWidget(cfg), nil
+	})
+}
+
+// newMemoryWidget creates the widget with the graph cfg asks for
+func newMemoryWidget(cfg Config) *memoryWidget {
+	m := &memoryWidget{cfg: cfg, label: newColorLabel(cfg.prefix("ram"))}
+	m.view, m.graph = statGraph(cfg, "ram", m.label, cfg.iconSize(), 100, cfg.ramColor)
+	return m
+}
+
+// Render returns the object to place in the bar
+func (m *memoryWidget) Render() fyne.CanvasObject {
+	return m.view
+}
+
+// Interval implements Widget
+func (m *memoryWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// SetMetrics implements widgetExporter
+func (m *memoryWidget) SetMetrics(r *metricsRegistry) {
+	m.metrics = r
+}
+
+// Update reads the memory usage
+func (m *memoryWidget) Update(ctx context.Context) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return
+	}
+	m.label.SetText(m.cfg.formatRAM(vm.Used, vm.Total, vm.UsedPercent))
+	m.label.SetColor(m.cfg.ramColor(vm.UsedPercent))
+	if m.graph != nil {
+		m.graph.Push(vm.UsedPercent)
+	}
+	m.metrics.set("gobar_memory_used_percent", "gauge", "Used share of physical memory.", vm.UsedPercent)
+	m.metrics.set("gobar_memory_used_bytes", "gauge", "Used physical memory.", float64(vm.Used))
+}
+
+// OnClick runs the ram click command, if any
+func (m *memoryWidget) OnClick(button desktop.MouseButton) {
+	if button == desktop.MouseButtonPrimary {
+		launchCommand(m.cfg.ClickCommands["ram"])
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// metricsRegistry holds the latest sample of each exported metric. A nil
+// registry ignores updates, so callers needn't check whether export is on.
+type metricsRegistry struct {
+	mu     sync.Mutex
+	values map[string]metricValue
+}
+
+// metricValue is one metric with its Prometheus type and help text
+type metricValue struct {
+	kind  string // "gauge" or "counter"
+	help  string
+	value float64
+}
+
+// newMetricsRegistry creates an empty registry
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{values: map[string]metricValue{}}
+}
+
+// set records the current value of a metric
+func (r *metricsRegistry) set(name, kind, help string, value float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.values[name] = metricValue{kind: kind, help: help, value: value}
+	r.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format, sorted by
+// name. It copies them first so a slow scraper doesn't hold up set.
+func (r *metricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	values := make(map[string]metricValue, len(r.values))
+	names := make([]string, 0, len(r.values))
+	for name, v := range r.values {
+		values[name] = v
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range names {
+		v := values[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			name, v.help, name, v.kind, name, strconv.FormatFloat(v.value, 'g', -1, 64))
+	}
+}
+
+// serveMetrics serves r at /metrics on addr, e.g. ":9101". It uses its own
+// mux so the pprof handlers on the default mux aren't exposed with it.
+func serveMetrics(addr string, r *metricsRegistry) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	log.Printf("Serving metrics on http://%s/metrics", l.Addr())
+	go func() {
+		log.Println("Metrics server stopped:", http.Serve(l, mux))
+	}()
+	return nil
+}
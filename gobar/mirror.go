@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+// mirrorBar shows a copy of the bar on another monitor. The copy is a picture
+// of the main bar's canvas, so widgets are updated once and the mirrors
+// cost one capture per refresh.
+type mirrorBar struct {
+	*barWindow
+	cfg   Config
+	image *canvas.Image
+}
+
+// newMirrorBar creates a mirror on m, placed on it by dockGeometry
+func newMirrorBar(a fyne.App, cfg Config, m MonitorGeometry, barHeight float32) *mirrorBar {
+	b := createBar(a, cfg, "Go Taskbar Mirror", m, barHeight)
+	image := canvas.NewImageFromImage(nil)
+	// Monitors of another size show the picture scaled, not distorted
+	image.FillMode = canvas.ImageFillContain
+	b.win.SetContent(image)
+	b.win.SetPadded(false)
+	return &mirrorBar{barWindow: b, cfg: cfg, image: image}
+}
+
+// Show opens the mirror and docks it like the main bar once it has a window
+func (m *mirrorBar) Show() {
+	m.win.Show()
+	go func() {
+		if _, err := m.dock(m.cfg.ReserveSpace, m.cfg.AlwaysOnTop); err != nil {
+			log.Println("Bar mirror is not docked:", err)
+		}
+	}()
+}
+
+// move places the mirror on mon
+func (m *mirrorBar) move(mon MonitorGeometry, barHeight float32) {
+	if m.place(m.cfg, mon, barHeight) {
+		if err := m.redock(m.cfg.ReserveSpace); err != nil {
+			log.Printf("Failed to move the bar on %s: %v", mon.Output, err)
+		}
+	}
+}
+
+// setVisible does nothing: mirrors stay shown while the main bar is hidden
+func (m *mirrorBar) setVisible(bool) {}
+
+// Close closes the mirror
+func (m *mirrorBar) Close() {
+	m.close(m.cfg.ReserveSpace)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// formatRate is compactRate with a space before the unit, e.g. "1.2 MB/s"
+func formatRate(bytesPerSec float64, unit string) string {
+	if unit != "bits" {
+		return formatBytes(bytesPerSec) + "/s"
+	}
+	s := compactRate(bytesPerSec, unit)
+	i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	return s[:i] + " " + s[i:]
+}
+
+// byteUnit picks the binary unit for n bytes and its size in bytes
+func byteUnit(n float64) (string, float64) {
+	switch {
+	case n >= 1<<30:
+		return "GB", 1 << 30
+	case n >= 1<<20:
+		return "MB", 1 << 20
+	case n >= 1<<10:
+		return "KB", 1 << 10
+	default:
+		return "B", 1
+	}
+}
+
+// formatBytes formats a byte count like compactRate does rates, with a
+// space before the unit, e.g. "1.2 MB" or "340 KB"
+func formatBytes(n float64) string {
+	unit, size := byteUnit(n)
+	if size >= 1<<20 {
+		return fmt.Sprintf("%.1f %s", n/size, unit)
+	}
+	return fmt.Sprintf("%.0f %s", n/size, unit)
+}
+
+// compactRate formats a bytes-per-second rate without spaces, e.g.
+// "120KB/s", or in bits per second ("960Kbps") when unit is "bits"
+func compactRate(bytesPerSec float64, unit string) string {
+	if unit == "bits" {
+		bitsPerSec := bytesPerSec * 8
+		switch {
+		case bitsPerSec >= 1e9:
+			return fmt.Sprintf("%.1fGbps", bitsPerSec/1e9)
+		case bitsPerSec >= 1e6:
+			return fmt.Sprintf("%.1fMbps", bitsPerSec/1e6)
+		case bitsPerSec >= 1e3:
+			return fmt.Sprintf("%.0fKbps", bitsPerSec/1e3)
+		default:
+			return fmt.Sprintf("%.0fbps", bitsPerSec)
+		}
+	}
+	switch {
+	case bytesPerSec >= 1<<30:
+		return fmt.Sprintf("%.1fGB/s", bytesPerSec/(1<<30))
+	case bytesPerSec >= 1<<20:
+		return fmt.Sprintf("%.1fMB/s", bytesPerSec/(1<<20))
+	case bytesPerSec >= 1<<10:
+		return fmt.Sprintf("%.0fKB/s", bytesPerSec/(1<<10))
+	default:
+		return fmt.Sprintf("%.0fB/s", bytesPerSec)
+	}
+}
+
+// interfaceCounters picks the counters of the named interface from a
+// per-interface list, as a one-element list like IOCounters(false) returns;
+// it is empty while the interface doesn't exist
+func interfaceCounters(counters []net.IOCountersStat, name string) []net.IOCountersStat {
+	for _, c := range counters {
+		if c.Name == name {
+			return []net.IOCountersStat{c}
+		}
+	}
+	return nil
+}
+
+// shownCounters reads the counters the net widget shows, as a one-element
+// list: the total, or iface's when set, empty while it doesn't exist. The
+// per-interface counters it read are returned as well, for the tooltip.
+func shownCounters(ctx context.Context, iface string) (shown, perIface []net.IOCountersStat, err error) {
+	perIface, err = net.IOCountersWithContext(ctx, true)
+	if iface != "" {
+		return interfaceCounters(perIface, iface), perIface, err
+	}
+	shown, _ = net.IOCountersWithContext(ctx, false)
+	return shown, perIface, err
+}
+
+// netRate turns successive byte counters into rates
+type netRate struct {
+	at         time.Time
+	sent, recv uint64
+}
+
+// update returns the send and receive rates since the previous counters.
+// The first sample, and one whose counters went back, e.g. for a re-created
+// interface, reads as 0.
+func (r *netRate) update(now time.Time, c net.IOCountersStat) (up, down float64) {
+	elapsed := now.Sub(r.at).Seconds()
+	if !r.at.IsZero() && elapsed > 0 && c.BytesSent >= r.sent && c.BytesRecv >= r.recv {
+		up = float64(c.BytesSent-r.sent) / elapsed
+		down = float64(c.BytesRecv-r.recv) / elapsed
+	}
+	r.at, r.sent, r.recv = now, c.BytesSent, c.BytesRecv
+	return up, down
+}
+
+// reset forgets the previous counters, e.g. while the interface is gone
+func (r *netRate) reset() {
+	*r = netRate{}
+}
+
+// interfaceRates lists per-interface upload/download rates since the
+// previous sample, one "name  ↑up ↓down" line per interface except loopback.
+// prev is updated in place for the next call.
+func interfaceRates(counters []net.IOCountersStat, prev map[string]net.IOCountersStat, elapsed float64, unit string) string {
+	var lines []string
+	for _, c := range counters {
+		if c.Name == "lo" {
+			continue
+		}
+		var up, down float64
+		if p, ok := prev[c.Name]; ok && elapsed > 0 && c.BytesSent >= p.BytesSent && c.BytesRecv >= p.BytesRecv {
+			up = float64(c.BytesSent-p.BytesSent) / elapsed
+			down = float64(c.BytesRecv-p.BytesRecv) / elapsed
+		}
+		prev[c.Name] = c
+		lines = append(lines, fmt.Sprintf("%s  ↑%s ↓%s", c.Name, compactRate(up, unit), compactRate(down, unit)))
+	}
+	return strings.Join(lines, "\n")
+}
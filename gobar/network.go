@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// networkWidget shows the send and receive rates, with an optional
+// sparkline, the rates of each interface in a tooltip and, with
+// NetShowWifi, the Wi-Fi network of the interface
+type networkWidget struct {
+	cfg     Config
+	label   *widget.Label
+	graph   *sparkline
+	area    *tooltipArea
+	wifi    *widget.Label
+	metrics *metricsRegistry
+
+	rate      netRate
+	at        time.Time
+	prevIface map[string]net.IOCountersStat
+	wifiAt    time.Time
+}
+
+// init registers the network widget, shown wherever gopsutil can read the
+// counters
+func init() {
+	registerWidget("net", func(Config) bool { return statSupport().net.Available() }, func(cfg Config) (Widget, error) {
+		return newNetworkWidget(cfg), nil
+	})
+}
+
+// newNetworkWidget creates the widget with the graph cfg asks for
+func newNetworkWidget(cfg Config) *networkWidget {
+	n := &networkWidget{cfg: cfg, label: widget.NewLabel(cfg.prefix("net")), prevIface: map[string]net.IOCountersStat{}}
+	var view fyne.CanvasObject
+	view, n.graph = statGraph(cfg, "net", n.label, cfg.iconSize(), 0, nil)
+	n.area = newTooltipArea(view)
+	n.wifi = widget.NewLabel("")
+	n.wifi.Hide()
+	return n
+}
+
+// Render returns the object to place in the bar
+func (n *networkWidget) Render() fyne.CanvasObject {
+	if !n.cfg.NetShowWifi {
+		return n.area
+	}
+	return container.NewHBox(n.area, n.wifi)
+}
+
+// Interval implements Widget
+func (n *networkWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// SetMetrics implements widgetExporter
+func (n *networkWidget) SetMetrics(r *metricsRegistry) {
+	n.metrics = r
+}
+
+// Update reads the counters and, every wifiPoll, the Wi-Fi link
+func (n *networkWidget) Update(ctx context.Context) {
+	now := time.Now()
+	var elapsed float64
+	if !n.at.IsZero() {
+		elapsed = now.Sub(n.at).Seconds()
+	}
+	n.at = now
+	shown, perIface, ifaceErr := shownCounters(ctx, n.cfg.NetInterface)
+	if len(shown) > 0 {
+		up, down := n.rate.update(now, shown[0])
+		setLabelText(n.label, n.cfg.formatNet(up, down))
+		if n.graph != nil {
+			n.graph.Push(up + down)
+		}
+		n.metrics.set("gobar_network_transmit_bytes_total", "counter", "Bytes sent on the shown interfaces.", float64(shown[0].BytesSent))
+		n.metrics.set("gobar_network_receive_bytes_total", "counter", "Bytes received on the shown interfaces.", float64(shown[0].BytesRecv))
+		n.metrics.set("gobar_network_transmit_bytes_per_second", "gauge", "Send rate over the last sample.", up)
+		n.metrics.set("gobar_network_receive_bytes_per_second", "gauge", "Receive rate over the last sample.", down)
+	} else if n.cfg.NetInterface != "" {
+		n.rate.reset()
+		setLabelText(n.label, n.cfg.prefix("net")+n.cfg.NetInterface+" down")
+	}
+	// Per-interface breakdown for the tooltip
+	if ifaceErr == nil {
+		n.area.SetTooltip(interfaceRates(perIface, n.prevIface, elapsed, n.cfg.NetUnit))
+	}
+	if n.cfg.NetShowWifi && now.Sub(n.wifiAt) >= wifiPoll {
+		n.wifiAt = now
+		n.updateWifi()
+	}
+}
+
+// updateWifi shows the network of the shown interface, hiding the label
+// when it isn't wireless or not associated
+func (n *networkWidget) updateWifi() {
+	iface := n.cfg.NetInterface
+	if iface == "" {
+		iface, _ = defaultRouteInterface()
+	}
+	if iface == "" || !isWireless(iface) {
+		n.wifi.Hide()
+		return
+	}
+	link, err := readWifiLink(iface)
+	if err != nil {
+		n.wifi.Hide()
+		return
+	}
+	setLabelText(n.wifi, formatWifi(link))
+	n.wifi.Show()
+}
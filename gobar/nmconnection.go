@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// NetworkManager D-Bus names
+const (
+	nmName             = "org.freedesktop.NetworkManager"
+	nmPath             = "/org/freedesktop/NetworkManager"
+	nmActiveConnection = "org.freedesktop.NetworkManager.Connection.Active"
+)
+
+// nmConnectionWidget shows the name of NetworkManager's primary connection,
+// the WiFi SSID, "Wired connection 1" or a VPN, or "Offline". It updates on
+// NetworkManager's property change signals instead of polling, and a click
+// opens the network menu.
+type nmConnectionWidget struct {
+	conn   *dbus.Conn
+	label  *widget.Label
+	menu   *nmMenu
+	prefix string
+}
+
+// newNMConnectionWidget connects to the system bus and subscribes to
+// NetworkManager's property changes and the Wi-Fi scan results
+func newNMConnectionWidget(a fyne.App, prefix string) (*nmConnectionWidget, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range [][]dbus.MatchOption{
+		{dbus.WithMatchObjectPath(nmPath), dbus.WithMatchInterface("org.freedesktop.DBus.Properties"), dbus.WithMatchMember("PropertiesChanged")},
+		// Scans finding or losing networks, and finishing (LastScan), for the menu
+		{dbus.WithMatchInterface(nmWireless), dbus.WithMatchMember("AccessPointAdded")},
+		{dbus.WithMatchInterface(nmWireless), dbus.WithMatchMember("AccessPointRemoved")},
+		{dbus.WithMatchInterface("org.freedesktop.DBus.Properties"), dbus.WithMatchMember("PropertiesChanged"), dbus.WithMatchArg(0, nmWireless)},
+	} {
+		if err := conn.AddMatchSignal(match...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &nmConnectionWidget{conn: conn, label: widget.NewLabel(prefix), menu: newNMMenu(a, conn), prefix: prefix}, nil
+}
+
+// CanvasObject returns the object to place in the bar
+func (n *nmConnectionWidget) CanvasObject() fyne.CanvasObject {
+	return newTapArea(n.label, n.menu.Show)
+}
+
+// Run shows the current connection, then follows changes until the bus
+// connection closes; call it in its own goroutine
+func (n *nmConnectionWidget) Run() {
+	signals := make(chan *dbus.Signal, 8)
+	n.conn.Signal(signals)
+	// A restart after a panic subscribes afresh
+	defer n.conn.RemoveSignal(signals)
+	n.update()
+	for s := range signals {
+		// Only NetworkManager's own properties change the connection
+		if s.Path == nmPath {
+			n.update()
+		}
+		n.menu.refreshSoon()
+	}
+}
+
+// Close ends Run by closing the bus connection
+func (n *nmConnectionWidget) Close() {
+	n.conn.Close()
+}
+
+// update reads the primary connection's name
+func (n *nmConnectionWidget) update() {
+	setLabelText(n.label, n.prefix+n.connectionName())
+}
+
+// connectionName is the primary connection's Id, or "Offline" without one
+func (n *nmConnectionWidget) connectionName() string {
+	if path, name := nmPrimaryConnection(n.conn); path != "" {
+		return name
+	}
+	return "Offline"
+}
+
+// nmPrimaryConnection returns the active connection carrying the default
+// route and its name, or "" when offline
+func nmPrimaryConnection(conn *dbus.Conn) (dbus.ObjectPath, string) {
+	v, err := conn.Object(nmName, nmPath).GetProperty(nmName + ".PrimaryConnection")
+	if err != nil {
+		return "", ""
+	}
+	path, _ := v.Value().(dbus.ObjectPath)
+	if path == "" || path == "/" {
+		return "", ""
+	}
+	id, err := conn.Object(nmName, path).GetProperty(nmActiveConnection + ".Id")
+	if err != nil {
+		return "", ""
+	}
+	name, _ := id.Value().(string)
+	return path, name
+}
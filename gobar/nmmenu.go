@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// More NetworkManager D-Bus names
+const (
+	nmDevice         = "org.freedesktop.NetworkManager.Device"
+	nmWireless       = "org.freedesktop.NetworkManager.Device.Wireless"
+	nmAccessPoint    = "org.freedesktop.NetworkManager.AccessPoint"
+	nmSettingsPath   = "/org/freedesktop/NetworkManager/Settings"
+	nmSettings       = "org.freedesktop.NetworkManager.Settings"
+	nmSettingsConn   = "org.freedesktop.NetworkManager.Settings.Connection"
+	nmDeviceTypeWifi = 2
+)
+
+// nmRefreshDelay gathers the signals of a scan, one or more per access
+// point, into a single refresh of the menu
+const nmRefreshDelay = 250 * time.Millisecond
+
+// wifiNetwork is a Wi-Fi network in range, by its strongest access point
+type wifiNetwork struct {
+	SSID     string
+	Strength byte // percent
+	device   dbus.ObjectPath
+	ap       dbus.ObjectPath
+}
+
+// nmMenu is the window opened by clicking the connection widget: radio
+// switches, the active connection and the Wi-Fi networks in range. Like the
+// calendar it is a separate window, as the bar is too short for a popup.
+type nmMenu struct {
+	app  fyne.App
+	conn *dbus.Conn
+
+	// The signal goroutine refreshes the menu too
+	mu      sync.Mutex
+	win     fyne.Window
+	box     *fyne.Container
+	shown   bool
+	pending *time.Timer // the refresh asked for by refreshSoon
+}
+
+// newNMMenu prepares the menu for conn, a system bus connection
+func newNMMenu(a fyne.App, conn *dbus.Conn) *nmMenu {
+	return &nmMenu{app: a, conn: conn}
+}
+
+// Show opens the menu with the networks NetworkManager knows of and asks the
+// Wi-Fi devices for a fresh scan, whose results refresh the menu as they come
+func (m *nmMenu) Show() {
+	m.mu.Lock()
+	if m.win == nil {
+		m.win = m.app.NewWindow("Network")
+		m.box = container.NewVBox()
+		m.win.SetContent(container.NewVScroll(m.box))
+		m.win.Resize(fyne.NewSize(320, 360))
+		m.win.SetCloseIntercept(func() {
+			m.mu.Lock()
+			m.shown = false
+			m.mu.Unlock()
+			m.win.Hide()
+		})
+	}
+	m.shown = true
+	win := m.win
+	m.mu.Unlock()
+	m.scan()
+	m.Refresh()
+	win.Show()
+	win.RequestFocus()
+}
+
+// refreshSoon refreshes an open menu after nmRefreshDelay, taking in the
+// signals that arrive meanwhile
+func (m *nmMenu) refreshSoon() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.win == nil || !m.shown || m.pending != nil {
+		return
+	}
+	m.pending = time.AfterFunc(nmRefreshDelay, func() {
+		m.mu.Lock()
+		m.pending = nil
+		m.mu.Unlock()
+		m.Refresh()
+	})
+}
+
+// Refresh redraws an open menu, e.g. after NetworkManager's state changed
+func (m *nmMenu) Refresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.win == nil || !m.shown {
+		return
+	}
+	nm := m.conn.Object(nmName, nmPath)
+	wifiOn := m.boolProperty(nm, "WirelessEnabled")
+	wwanOn := m.boolProperty(nm, "WwanEnabled")
+
+	// The checks get their callbacks after being set, so setting them
+	// doesn't write the properties back
+	wifi := widget.NewCheck("Wi-Fi", nil)
+	wifi.SetChecked(wifiOn)
+	wifi.OnChanged = func(on bool) { m.setRadio("WirelessEnabled", on) }
+	airplane := widget.NewCheck("Airplane mode", nil)
+	airplane.SetChecked(!wifiOn && !wwanOn)
+	airplane.OnChanged = func(on bool) {
+		m.setRadio("WirelessEnabled", !on)
+		m.setRadio("WwanEnabled", !on)
+	}
+	rows := []fyne.CanvasObject{wifi, airplane, widget.NewSeparator()}
+
+	if active, name := nmPrimaryConnection(m.conn); active != "" {
+		disconnect := widget.NewButton("Disconnect", func() {
+			if err := nm.Call(nmName+".DeactivateConnection", 0, active).Err; err != nil {
+				log.Println("Failed to disconnect:", err)
+			}
+		})
+		rows = append(rows, container.NewBorder(nil, nil, nil, disconnect,
+			widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})))
+	} else {
+		rows = append(rows, widget.NewLabel("Offline"))
+	}
+
+	rescan := widget.NewButton("Rescan", func() {
+		m.scan()
+		m.Refresh()
+	})
+	rows = append(rows, widget.NewSeparator(), container.NewBorder(nil, nil, nil, rescan, widget.NewLabel("Wi-Fi networks")))
+	networks := m.networks()
+	for _, n := range networks {
+		n := n
+		connect := widget.NewButton("Connect", func() { m.connect(n) })
+		rows = append(rows, container.NewBorder(nil, nil, nil, connect,
+			widget.NewLabel(fmt.Sprintf("%s  %d%%", n.SSID, n.Strength))))
+	}
+	if len(networks) == 0 && wifiOn {
+		rows = append(rows, widget.NewLabel("No networks found"))
+	}
+	m.box.Objects = rows
+	m.box.Refresh()
+}
+
+// boolProperty reads a boolean NetworkManager property, false on errors
+func (m *nmMenu) boolProperty(obj dbus.BusObject, name string) bool {
+	v, err := obj.GetProperty(nmName + "." + name)
+	if err != nil {
+		return false
+	}
+	on, _ := v.Value().(bool)
+	return on
+}
+
+// setRadio switches a radio kill switch property such as WirelessEnabled
+func (m *nmMenu) setRadio(name string, on bool) {
+	if err := m.conn.Object(nmName, nmPath).SetProperty(nmName+"."+name, dbus.MakeVariant(on)); err != nil {
+		log.Printf("Failed to set %s: %v", name, err)
+	}
+}
+
+// wifiDevices returns the paths of NetworkManager's Wi-Fi devices
+func (m *nmMenu) wifiDevices() []dbus.ObjectPath {
+	var devices, wifi []dbus.ObjectPath
+	if err := m.conn.Object(nmName, nmPath).Call(nmName+".GetDevices", 0).Store(&devices); err != nil {
+		return nil
+	}
+	for _, dev := range devices {
+		v, err := m.conn.Object(nmName, dev).GetProperty(nmDevice + ".DeviceType")
+		if err != nil {
+			continue
+		}
+		if t, _ := v.Value().(uint32); t == nmDeviceTypeWifi {
+			wifi = append(wifi, dev)
+		}
+	}
+	return wifi
+}
+
+// scan asks each Wi-Fi device to rescan; results arrive within seconds
+func (m *nmMenu) scan() {
+	for _, dev := range m.wifiDevices() {
+		// Fails while a scan runs or shortly after one, which is fine
+		m.conn.Object(nmName, dev).Call(nmWireless+".RequestScan", 0, map[string]dbus.Variant{})
+	}
+}
+
+// networks lists the Wi-Fi networks in range, strongest first, one entry
+// per SSID; hidden networks are left out
+func (m *nmMenu) networks() []wifiNetwork {
+	best := map[string]wifiNetwork{}
+	for _, dev := range m.wifiDevices() {
+		var aps []dbus.ObjectPath
+		if err := m.conn.Object(nmName, dev).Call(nmWireless+".GetAllAccessPoints", 0).Store(&aps); err != nil {
+			continue
+		}
+		for _, ap := range aps {
+			obj := m.conn.Object(nmName, ap)
+			ssid, err := obj.GetProperty(nmAccessPoint + ".Ssid")
+			if err != nil {
+				continue
+			}
+			strength, err := obj.GetProperty(nmAccessPoint + ".Strength")
+			if err != nil {
+				continue
+			}
+			raw, _ := ssid.Value().([]byte)
+			n := wifiNetwork{SSID: string(raw), device: dev, ap: ap}
+			n.Strength, _ = strength.Value().(byte)
+			if n.SSID == "" {
+				continue
+			}
+			if old, ok := best[n.SSID]; !ok || n.Strength > old.Strength {
+				best[n.SSID] = n
+			}
+		}
+	}
+	networks := make([]wifiNetwork, 0, len(best))
+	for _, n := range best {
+		networks = append(networks, n)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		if networks[i].Strength != networks[j].Strength {
+			return networks[i].Strength > networks[j].Strength
+		}
+		return networks[i].SSID < networks[j].SSID
+	})
+	return networks
+}
+
+// connect activates a saved connection for the network, or adds one, in
+// which case NetworkManager asks a secret agent such as nm-applet for the
+// password
+func (m *nmMenu) connect(n wifiNetwork) {
+	nm := m.conn.Object(nmName, nmPath)
+	var err error
+	if saved := m.savedConnection(n.SSID); saved != "" {
+		err = nm.Call(nmName+".ActivateConnection", 0, saved, n.device, n.ap).Err
+	} else {
+		err = nm.Call(nmName+".AddAndActivateConnection", 0, map[string]map[string]dbus.Variant{}, n.device, n.ap).Err
+	}
+	if err != nil {
+		log.Printf("Failed to connect to %s: %v", n.SSID, err)
+	}
+}
+
+// savedConnection returns the saved Wi-Fi connection for ssid, or ""
+func (m *nmMenu) savedConnection(ssid string) dbus.ObjectPath {
+	var conns []dbus.ObjectPath
+	if err := m.conn.Object(nmName, nmSettingsPath).Call(nmSettings+".ListConnections", 0).Store(&conns); err != nil {
+		return ""
+	}
+	for _, path := range conns {
+		var settings map[string]map[string]dbus.Variant
+		if err := m.conn.Object(nmName, path).Call(nmSettingsConn+".GetSettings", 0).Store(&settings); err != nil {
+			continue
+		}
+		if raw, ok := settings["802-11-wireless"]["ssid"].Value().([]byte); ok && bytes.Equal(raw, []byte(ssid)) {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// notifyMatchRule selects Notify calls to whichever notification daemon is running
+const notifyMatchRule = "type='method_call',interface='org.freedesktop.Notifications',member='Notify'"
+
+// notification is a desktop notification held in the queue
+type notification struct {
+	ID      int
+	App     string
+	Summary string
+	Body    string
+	Time    time.Time
+	Urgency byte // urgencyLow, urgencyNormal or urgencyCritical
+}
+
+// notificationQueue holds unread notifications until they are dismissed,
+// and the do-not-disturb state shared by the badge and the daemon
+type notificationQueue struct {
+	mu       sync.Mutex
+	items    []notification
+	nextID   int
+	max      int
+	dnd      bool
+	onChange func()
+}
+
+// Add queues a notification, dropping the oldest beyond the limit, and
+// returns its ID
+func (q *notificationQueue) Add(n notification) int {
+	q.mu.Lock()
+	q.nextID++
+	n.ID = q.nextID
+	q.items = append(q.items, n)
+	if q.max > 0 && len(q.items) > q.max {
+		q.items = q.items[len(q.items)-q.max:]
+	}
+	q.mu.Unlock()
+	q.changed()
+	return n.ID
+}
+
+// Replace updates the notification with the given ID in place, or queues n
+// under that ID again once it was dismissed. It reports false for an ID the
+// queue never handed out.
+func (q *notificationQueue) Replace(id int, n notification) bool {
+	q.mu.Lock()
+	if id <= 0 || id > q.nextID {
+		q.mu.Unlock()
+		return false
+	}
+	n.ID = id
+	found := false
+	for i := range q.items {
+		if q.items[i].ID == id {
+			q.items[i] = n
+			found = true
+			break
+		}
+	}
+	if !found {
+		q.items = append(q.items, n)
+		if q.max > 0 && len(q.items) > q.max {
+			q.items = q.items[len(q.items)-q.max:]
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+	return true
+}
+
+// SetDND turns do-not-disturb mode on or off
+func (q *notificationQueue) SetDND(on bool) {
+	q.mu.Lock()
+	q.dnd = on
+	q.mu.Unlock()
+	q.changed()
+}
+
+// DND reports whether do-not-disturb mode is on
+func (q *notificationQueue) DND() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dnd
+}
+
+// Dismiss removes the notification with the given ID, reporting whether it
+// was queued
+func (q *notificationQueue) Dismiss(id int) bool {
+	q.mu.Lock()
+	found := false
+	for i, n := range q.items {
+		if n.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			found = true
+			break
+		}
+	}
+	q.mu.Unlock()
+	q.changed()
+	return found
+}
+
+// Clear removes every notification
+func (q *notificationQueue) Clear() {
+	q.mu.Lock()
+	q.items = nil
+	q.mu.Unlock()
+	q.changed()
+}
+
+// Items returns a copy of the queued notifications, oldest first
+func (q *notificationQueue) Items() []notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]notification(nil), q.items...)
+}
+
+// changed notifies the listener outside the lock
+func (q *notificationQueue) changed() {
+	if q.onChange != nil {
+		q.onChange()
+	}
+}
+
+// monitorNotifications watches the session bus for Notify calls and queues
+// them. The running daemon (dunst etc.) still shows the popups; gobar only
+// observes, so it needs a private connection turned into a bus monitor.
+func monitorNotifications(q *notificationQueue) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	call := conn.BusObject().Call("org.freedesktop.DBus.Monitoring.BecomeMonitor", 0, []string{notifyMatchRule}, uint32(0))
+	if call.Err != nil {
+		conn.Close()
+		return call.Err
+	}
+	messages := make(chan *dbus.Message, 16)
+	conn.Eavesdrop(messages)
+	go func() {
+		for msg := range messages {
+			// Notify(app_name, replaces_id, app_icon, summary, body, actions, hints, expire_timeout)
+			if len(msg.Body) < 5 {
+				continue
+			}
+			app, _ := msg.Body[0].(string)
+			summary, _ := msg.Body[3].(string)
+			body, _ := msg.Body[4].(string)
+			urgency := urgencyNormal
+			if len(msg.Body) > 6 {
+				hints, _ := msg.Body[6].(map[string]dbus.Variant)
+				urgency = hintUrgency(hints)
+			}
+			q.Add(notification{App: app, Summary: summary, Body: body, Time: time.Now(), Urgency: urgency})
+		}
+	}()
+	return nil
+}
+
+// notificationCenter is the bar badge, one on every bar with AllOutputs, plus
+// the window listing queued notifications
+type notificationCenter struct {
+	app    fyne.App
+	queue  *notificationQueue
+	badges viewSet[*notificationBadge]
+	win    fyne.Window
+	list   *fyne.Container
+	dnd    *widget.Check
+}
+
+// notificationBadge is the badge of one bar, with that bar's prefixes for
+// the normal and the Do not disturb state
+type notificationBadge struct {
+	button      *widget.Button
+	prefix, dnd string
+}
+
+// newNotificationCenter creates the badge for queue
+func newNotificationCenter(a fyne.App, queue *notificationQueue) *notificationCenter {
+	c := &notificationCenter{app: a, queue: queue}
+	queue.onChange = c.refresh
+	return c
+}
+
+// View returns a badge to place in a bar, shown until ctx is done; prefix
+// and dnd start its text, e.g. "🔔 3"
+func (c *notificationCenter) View(ctx context.Context, prefix, dnd string) fyne.CanvasObject {
+	badge := &notificationBadge{button: widget.NewButton(prefix, c.Show), prefix: prefix, dnd: dnd}
+	badge.button.Importance = widget.LowImportance
+	c.badges.add(ctx, badge)
+	c.refresh()
+	return badge.button
+}
+
+// Show opens the notification list window
+func (c *notificationCenter) Show() {
+	if c.win == nil {
+		c.win = c.app.NewWindow("Notifications")
+		c.list = container.NewVBox()
+		clearAll := widget.NewButton("Clear All", c.queue.Clear)
+		c.dnd = widget.NewCheck("Do not disturb", c.queue.SetDND)
+		c.win.SetContent(container.NewBorder(nil, container.NewBorder(nil, nil, c.dnd, nil, clearAll), nil, nil, container.NewVScroll(c.list)))
+		c.win.Resize(fyne.NewSize(400, 300))
+		c.win.SetCloseIntercept(c.win.Hide)
+	}
+	c.refresh()
+	c.win.Show()
+	c.win.RequestFocus()
+}
+
+// refresh updates the badge count and, if built, the list window
+func (c *notificationCenter) refresh() {
+	items := c.queue.Items()
+	dnd := c.queue.DND()
+	c.badges.show(func(b *notificationBadge) {
+		prefix := b.prefix
+		if dnd {
+			prefix = b.dnd
+		}
+		// Compact drops the text icons, leaving the count alone
+		switch {
+		case prefix == "":
+			b.button.SetText(strconv.Itoa(len(items)))
+		case len(items) == 0:
+			b.button.SetText(prefix)
+		default:
+			b.button.SetText(fmt.Sprintf("%s %d", prefix, len(items)))
+		}
+	})
+	if c.list == nil {
+		return
+	}
+	if c.dnd.Checked != dnd {
+		c.dnd.SetChecked(dnd)
+	}
+	rows := make([]fyne.CanvasObject, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		n := items[i]
+		title := widget.NewLabelWithStyle(fmt.Sprintf("%s — %s (%s)", n.App, n.Summary, n.Time.Format("15:04")),
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		body := widget.NewLabel(n.Body)
+		body.Wrapping = fyne.TextWrapWord
+		dismiss := widget.NewButton("Dismiss", func() { c.queue.Dismiss(n.ID) })
+		rows = append(rows, container.NewBorder(nil, nil, nil, dismiss, container.NewVBox(title, body)))
+	}
+	if len(rows) == 0 {
+		rows = append(rows, widget.NewLabel("No notifications"))
+	}
+	c.list.Objects = rows
+	c.list.Refresh()
+}
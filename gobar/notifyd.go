@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// The notification daemon's bus name, object path and interface
+const (
+	notifyName = "org.freedesktop.Notifications"
+	notifyPath = "/org/freedesktop/Notifications"
+)
+
+// Urgency levels of the "urgency" hint
+const (
+	urgencyLow byte = iota
+	urgencyNormal
+	urgencyCritical
+)
+
+// urgencyLevels maps the config names of the urgency levels to them
+var urgencyLevels = map[string]byte{"low": urgencyLow, "normal": urgencyNormal, "critical": urgencyCritical}
+
+// Reasons sent with the NotificationClosed signal
+const (
+	closedExpired   uint32 = 1
+	closedDismissed uint32 = 2
+	closedByCall    uint32 = 3
+	closedOther     uint32 = 4 // pushed out by newer popups
+)
+
+// Popup layout: at most popupMax at once, popupWidth wide and popupMargin
+// away from the bar and the screen edge, in pixels
+const (
+	popupMax    = 5
+	popupWidth  = 320
+	popupMargin = 8
+)
+
+// hintUrgency reads the urgency hint of a Notify call, normal when missing
+func hintUrgency(hints map[string]dbus.Variant) byte {
+	if v, ok := hints["urgency"]; ok {
+		if u, ok := v.Value().(byte); ok && u <= urgencyCritical {
+			return u
+		}
+	}
+	return urgencyNormal
+}
+
+// notifyServer implements org.freedesktop.Notifications, so gobar replaces
+// dunst: every notification is queued for the badge's list, and those that
+// pass the urgency filter and do-not-disturb mode also pop up
+type notifyServer struct {
+	conn       *dbus.Conn
+	queue      *notificationQueue
+	popups     *notificationPopups
+	timeout    time.Duration
+	minUrgency map[string]byte
+}
+
+// serveNotifications takes the notification daemon's bus name and serves it
+// until gobar exits. It fails while another daemon owns the name.
+func serveNotifications(queue *notificationQueue, popups *notificationPopups, cfg Config) (*notifyServer, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	s := &notifyServer{conn: conn, queue: queue, popups: popups,
+		timeout: time.Duration(cfg.NotificationTimeoutSec) * time.Second, minUrgency: map[string]byte{}}
+	for app, name := range cfg.NotificationMinUrgency {
+		s.minUrgency[app] = urgencyLevels[name]
+	}
+	popups.onClose = s.closed
+	if err := conn.Export(s, notifyPath, notifyName); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	node := &introspect.Node{
+		Name: notifyPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    notifyName,
+				Methods: introspect.Methods(s),
+				Signals: []introspect.Signal{
+					{Name: "NotificationClosed", Args: []introspect.Arg{{Name: "id", Type: "u"}, {Name: "reason", Type: "u"}}},
+					{Name: "ActionInvoked", Args: []introspect.Arg{{Name: "id", Type: "u"}, {Name: "action_key", Type: "s"}}},
+				},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), notifyPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := conn.RequestName(notifyName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, errors.New("another notification daemon is running")
+	}
+	return s, nil
+}
+
+// GetCapabilities lists the optional features gobar supports
+func (s *notifyServer) GetCapabilities() ([]string, *dbus.Error) {
+	return []string{"body", "persistence"}, nil
+}
+
+// Notify queues a notification and pops it up unless it is filtered,
+// replacing the one with replacesID, or reusing that ID once it is dismissed
+func (s *notifyServer) Notify(app string, replacesID uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	n := notification{App: app, Summary: summary, Body: body, Time: time.Now(), Urgency: hintUrgency(hints)}
+	if replacesID != 0 && s.queue.Replace(int(replacesID), n) {
+		n.ID = int(replacesID)
+	} else {
+		n.ID = s.queue.Add(n)
+	}
+	if s.popsUp(n) {
+		s.popups.Show(n, s.expiry(n, timeout))
+	}
+	return uint32(n.ID), nil
+}
+
+// CloseNotification closes a notification on its sender's request; only one
+// still shown or queued is reported closed
+func (s *notifyServer) CloseNotification(id uint32) *dbus.Error {
+	shown := s.popups.Close(int(id))
+	if queued := s.queue.Dismiss(int(id)); shown || queued {
+		s.closed(int(id), closedByCall)
+	}
+	return nil
+}
+
+// GetServerInformation identifies the daemon and the spec version it follows
+func (s *notifyServer) GetServerInformation() (name, vendor, version, specVersion string, err *dbus.Error) {
+	return "gobar", "gobar", "1.0", "1.2", nil
+}
+
+// popsUp reports whether n is shown as a popup: critical ones always are,
+// others only outside do-not-disturb mode and at their app's minimum urgency
+func (s *notifyServer) popsUp(n notification) bool {
+	if n.Urgency == urgencyCritical {
+		return true
+	}
+	if s.queue.DND() {
+		return false
+	}
+	lowest, ok := s.minUrgency[n.App]
+	if !ok {
+		lowest = s.minUrgency["*"]
+	}
+	return n.Urgency >= lowest
+}
+
+// expiry is how long n's popup stays, 0 for until dismissed: the sender's
+// timeout in milliseconds, or the configured one when it leaves it to the
+// daemon with -1. Critical notifications stay by default.
+func (s *notifyServer) expiry(n notification, timeout int32) time.Duration {
+	switch {
+	case timeout > 0:
+		return time.Duration(timeout) * time.Millisecond
+	case timeout == 0 || n.Urgency == urgencyCritical:
+		return 0
+	default:
+		return s.timeout
+	}
+}
+
+// closed tells the sender its notification's popup is gone
+func (s *notifyServer) closed(id int, reason uint32) {
+	s.conn.Emit(notifyPath, notifyName+".NotificationClosed", uint32(id), reason)
+}
+
+// notificationPopups owns the popup window, a borderless override-redirect
+// window like the tooltip's, stacking the newest popups next to the bar's
+// right end
+type notificationPopups struct {
+	mu      sync.Mutex
+	app     fyne.App
+	win     fyne.Window
+	box     *fyne.Container
+	X       *xgb.Conn
+	xid     xproto.Window
+	anchor  barGeometry
+	shown   []notification
+	timers  map[int]*time.Timer
+	onClose func(id int, reason uint32) // a popup expired or was clicked away
+}
+
+// newNotificationPopups prepares the popups, created on the first Show
+func newNotificationPopups(a fyne.App) *notificationPopups {
+	return &notificationPopups{app: a, timers: map[int]*time.Timer{}}
+}
+
+// SetAnchor places later popups by the bar at g, e.g. after a screen change
+func (p *notificationPopups) SetAnchor(g barGeometry) {
+	p.mu.Lock()
+	p.anchor = g
+	p.mu.Unlock()
+}
+
+// Show pops up n, replacing a popup with its ID, for expire or until
+// clicked when expire is 0. Beyond popupMax the oldest popup goes, and its
+// sender is told it closed.
+func (p *notificationPopups) Show(n notification, expire time.Duration) {
+	p.mu.Lock()
+	if p.win == nil && !p.create() {
+		p.mu.Unlock()
+		return
+	}
+	p.remove(n.ID)
+	p.shown = append(p.shown, n)
+	dropped := 0
+	if len(p.shown) > popupMax {
+		dropped = p.shown[0].ID
+		p.remove(dropped)
+	}
+	if expire > 0 {
+		id := n.ID
+		p.timers[id] = time.AfterFunc(expire, func() {
+			p.mu.Lock()
+			p.remove(id)
+			p.render()
+			p.mu.Unlock()
+			p.closed(id, closedExpired)
+		})
+	}
+	p.render()
+	p.mu.Unlock()
+	if dropped != 0 {
+		p.closed(dropped, closedOther)
+	}
+}
+
+// Close takes down the popup with the given ID, reporting whether it was
+// shown
+func (p *notificationPopups) Close(id int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.win == nil || !p.remove(id) {
+		return false
+	}
+	p.render()
+	return true
+}
+
+// closed reports a popup that went away by itself or by a click
+func (p *notificationPopups) closed(id int, reason uint32) {
+	if p.onClose != nil {
+		p.onClose(id, reason)
+	}
+}
+
+// create builds the popup window, finishing the X11 setup once Fyne has
+// created the native window. Callers hold mu.
+func (p *notificationPopups) create() bool {
+	drv, ok := p.app.Driver().(desktop.Driver)
+	if !ok {
+		return false
+	}
+	X, err := barConn()
+	if err != nil {
+		return false
+	}
+	p.X = X
+	p.win = drv.CreateSplashWindow()
+	p.box = container.NewVBox()
+	p.win.SetContent(p.box)
+	p.win.Show()
+	go func() {
+		// Not under mu, as in tooltipManager.create
+		id, ok := x11WindowID(p.win, 5*time.Second)
+		if !ok {
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		win := xproto.Window(id)
+		xproto.UnmapWindow(p.X, win)
+		xproto.ChangeWindowAttributes(p.X, win, xproto.CwOverrideRedirect, []uint32{1})
+		p.xid = win
+		p.render()
+	}()
+	return true
+}
+
+// remove drops the popup with the given ID and its timer, reporting whether
+// it was shown; callers hold mu
+func (p *notificationPopups) remove(id int) bool {
+	if t, ok := p.timers[id]; ok {
+		t.Stop()
+		delete(p.timers, id)
+	}
+	for i, n := range p.shown {
+		if n.ID == id {
+			p.shown = append(p.shown[:i], p.shown[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// render lays out the shown popups, newest first, and maps the window by
+// the bar, or unmaps it when none are left; callers hold mu
+func (p *notificationPopups) render() {
+	rows := make([]fyne.CanvasObject, 0, len(p.shown))
+	for i := len(p.shown) - 1; i >= 0; i-- {
+		n := p.shown[i]
+		title := widget.NewLabelWithStyle(n.App+" — "+n.Summary, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		if n.Urgency == urgencyCritical {
+			title.Importance = widget.DangerImportance
+		}
+		body := widget.NewLabel(n.Body)
+		body.Wrapping = fyne.TextWrapWord
+		rows = append(rows, newTapArea(container.NewVBox(title, body), func() {
+			p.mu.Lock()
+			p.remove(n.ID)
+			p.render()
+			p.mu.Unlock()
+			p.closed(n.ID, closedDismissed)
+		}), widget.NewSeparator())
+	}
+	p.box.Objects = rows
+	p.box.Refresh()
+	if p.xid == 0 {
+		return
+	}
+	if len(rows) == 0 {
+		xproto.UnmapWindow(p.X, p.xid)
+		p.X.Sync()
+		return
+	}
+	size := fyne.NewSize(popupWidth, p.box.MinSize().Height)
+	p.win.Resize(size)
+	scale := p.win.Canvas().Scale()
+	x, y := popupOrigin(p.anchor, int(size.Width*scale), int(size.Height*scale))
+	xproto.ConfigureWindow(p.X, p.xid, xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(int32(x)), uint32(int32(y)), xproto.StackModeAbove})
+	xproto.MapWindow(p.X, p.xid)
+	p.X.Sync()
+}
+
+// popupOrigin places a w×h popup just off the bar at g: below or above a
+// horizontal bar at its right end, beside a vertical bar at its top
+func popupOrigin(g barGeometry, w, h int) (x, y int) {
+	switch g.edge {
+	case "bottom":
+		return g.x + g.length - w - popupMargin, g.y - h - popupMargin
+	case "left":
+		return g.x + g.thickness + popupMargin, g.y + popupMargin
+	case "right":
+		return g.x - w - popupMargin, g.y + popupMargin
+	default:
+		return g.x + g.length - w - popupMargin, g.y + g.thickness + popupMargin
+	}
+}
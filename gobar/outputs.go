@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// errNoX11Window means the bar window never got an X11 window to dock
+var errNoX11Window = errors.New("no X11 window")
+
+// MonitorGeometry is the area a bar docks on: an active RandR output, or the
+// whole screen when Output is empty
+type MonitorGeometry struct {
+	Output string
+	Area   screenRect
+}
+
+// monitorGeometry looks up the monitor of output, falling back to the whole
+// screen like screenArea
+func monitorGeometry(output string) (MonitorGeometry, error) {
+	area, err := screenArea(output)
+	return MonitorGeometry{Output: output, Area: area}, err
+}
+
+// compactOn reports whether a bar on m uses the terse widget formats: with
+// Compact, or on a monitor narrower than CompactBelowWidth
+func (c Config) compactOn(m MonitorGeometry) bool {
+	return c.Compact || c.CompactBelowWidth > 0 && m.Area.width < c.CompactBelowWidth
+}
+
+// barWindow is the window of a bar and where it docks on its monitor
+type barWindow struct {
+	win      fyne.Window
+	monitor  MonitorGeometry
+	geometry barGeometry
+	winID    atomic.Uint32 // the X11 window once docked
+}
+
+// createBar opens a window titled title for a bar on m, sized and placed by
+// dockGeometry
+func createBar(a fyne.App, cfg Config, title string, m MonitorGeometry, barHeight float32) *barWindow {
+	b := &barWindow{win: a.NewWindow(title)}
+	b.place(cfg, m, barHeight)
+	return b
+}
+
+// place moves the bar to m, reporting whether its geometry changed; a docked
+// bar must then be redocked
+func (b *barWindow) place(cfg Config, m MonitorGeometry, barHeight float32) bool {
+	g, size := dockGeometry(cfg, m.Area, barHeight)
+	b.monitor = m
+	if g == b.geometry {
+		return false
+	}
+	b.geometry = g
+	b.win.Resize(size)
+	return true
+}
+
+// dock waits for the shown bar's X11 window and makes it a dock with its
+// strut and stacking. It returns the window, or 0 with errNoX11Window.
+func (b *barWindow) dock(reserveSpace, above bool) (uint32, error) {
+	winID, ok := x11WindowID(b.win, 5*time.Second)
+	if !ok {
+		return 0, errNoX11Window
+	}
+	b.winID.Store(winID)
+	return winID, setDockProperties(winID, b.geometry, reserveSpace, above)
+}
+
+// redock docks a shown bar again once place moved it, reserving its space
+// when reserve is set
+func (b *barWindow) redock(reserve bool) error {
+	if winID := b.winID.Load(); winID != 0 {
+		return redock(winID, b.geometry, reserve)
+	}
+	return nil
+}
+
+// close closes the window, first releasing the space a docked bar reserved
+func (b *barWindow) close(reserveSpace bool) {
+	if winID := b.winID.Load(); winID != 0 && reserveSpace {
+		if err := clearStrut(winID); err != nil {
+			log.Println("Failed to release strut:", err)
+		}
+	}
+	b.win.Close()
+}
+
+// outputBar is a bar outputBars keeps on another monitor: a mirrorBar, or a
+// fullBar with AllOutputs
+type outputBar interface {
+	// move places the bar on m, redocking it if it moved
+	move(m MonitorGeometry, barHeight float32)
+	// setVisible shows or hides the bar along with the main one
+	setVisible(visible bool)
+	// Close closes the bar's window and stops its updates
+	Close()
+}
+
+// outputBars are the bars on the monitors other than the main bar's, from
+// MirrorOutputs or AllOutputs, by output name. They all run in this process.
+type outputBars struct {
+	main      string // the main bar's output
+	barHeight float32
+	// open creates and shows the bar of a new output
+	open func(m MonitorGeometry) outputBar
+
+	mu     sync.Mutex
+	bars   map[string]outputBar
+	hidden bool
+}
+
+// newOutputBars prepares the bars opened by open; sync creates them
+func newOutputBars(main string, barHeight float32, open func(MonitorGeometry) outputBar) *outputBars {
+	return &outputBars{main: main, barHeight: barHeight, open: open, bars: map[string]outputBar{}}
+}
+
+// sync gives every active output but the main bar's one a bar, following a
+// monitor hotplug: new outputs get a bar, moved ones are redocked, and the
+// bars of outputs that went away are closed
+func (o *outputBars) sync() error {
+	outputs, err := randrOutputs()
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	active := map[string]bool{}
+	for _, out := range outputs {
+		if !out.active || out.name == o.main {
+			continue
+		}
+		active[out.name] = true
+		m := MonitorGeometry{Output: out.name, Area: out.rect}
+		if b := o.bars[out.name]; b != nil {
+			b.move(m, o.barHeight)
+			continue
+		}
+		b := o.open(m)
+		if o.hidden {
+			b.setVisible(false)
+		}
+		o.bars[out.name] = b
+	}
+	for name, b := range o.bars {
+		if !active[name] {
+			log.Printf("Output %s went away, closing its bar", name)
+			b.Close()
+			delete(o.bars, name)
+		}
+	}
+	return nil
+}
+
+// setVisible shows or hides the bars with the main bar
+func (o *outputBars) setVisible(visible bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.hidden = !visible
+	for _, b := range o.bars {
+		b.setVisible(visible)
+	}
+}
+
+// Close closes every bar, e.g. when gobar is terminated
+func (o *outputBars) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for name, b := range o.bars {
+		b.Close()
+		delete(o.bars, name)
+	}
+}
+
+// boxes returns the widgets of the full bars, for the control socket
+func (o *outputBars) boxes() []barBox {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var boxes []barBox
+	for _, b := range o.bars {
+		if f, ok := b.(*fullBar); ok {
+			boxes = append(boxes, f.view.box)
+		}
+	}
+	return boxes
+}
+
+// refresh pushes a picture of main, the main bar's canvas, to every mirror
+func (o *outputBars) refresh(main fyne.Canvas) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var img image.Image
+	for _, b := range o.bars {
+		m, ok := b.(*mirrorBar)
+		if !ok {
+			continue
+		}
+		if img == nil {
+			img = main.Capture()
+		}
+		m.image.Image = img
+		m.image.Refresh()
+	}
+}
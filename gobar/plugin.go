@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"image/color"
+	"log"
+	"os/exec"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// pluginMaxLine is the longest line a plugin may print, e.g. with a long
+// tooltip; a longer one ends the run, and the plugin is restarted
+const pluginMaxLine = 1 << 20
+
+// pluginUpdate is one line a plugin prints, e.g.
+// {"text": "3 updates", "color": "#a3be8c", "tooltip": "linux 6.9.1"}
+type pluginUpdate struct {
+	Text    string `json:"text"`
+	Color   string `json:"color,omitempty"`
+	Tooltip string `json:"tooltip,omitempty"`
+}
+
+// pluginWidget shows the updates a long-running plugin process pushes on
+// stdout, one JSON object per line, like i3bar's protocol. Unlike a custom
+// widget it is never polled: every line repaints it at once.
+type pluginWidget struct {
+	spec  PluginWidget
+	views viewSet[*pluginView]
+}
+
+// pluginView is a plugin's label on one bar
+type pluginView struct {
+	label   *colorLabel
+	tooltip *tooltipArea
+}
+
+// newPluginWidget creates the widget for spec; Run runs the process
+func newPluginWidget(spec PluginWidget) *pluginWidget {
+	return &pluginWidget{spec: spec}
+}
+
+// View returns a label to place in a bar, shown until ctx is done. With
+// AllOutputs the one process updates the labels on every bar.
+func (p *pluginWidget) View(ctx context.Context) fyne.CanvasObject {
+	v := &pluginView{label: newColorLabel("")}
+	v.tooltip = newTooltipArea(scriptArea(v.label, p.spec.ScriptActions))
+	p.views.add(ctx, v)
+	return v.tooltip
+}
+
+// Run starts the plugin and applies its lines until it exits or ctx is
+// done; the scheduler's Supervise restarts it
+func (p *pluginWidget) Run(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.spec.Command)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, pluginMaxLine)
+	for scanner.Scan() {
+		p.apply(scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil {
+		// Nothing reads the pipe any more, so the plugin would block on it
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+// apply shows one line of plugin output. A line that isn't a JSON object is
+// shown as plain text, so simple shell loops work as plugins too.
+func (p *pluginWidget) apply(line []byte) {
+	var u pluginUpdate
+	if err := json.Unmarshal(line, &u); err != nil {
+		u = pluginUpdate{Text: strings.TrimSpace(string(line))}
+	}
+	var col color.Color
+	if u.Color != "" {
+		c, err := parseHexColor(u.Color)
+		if err != nil {
+			log.Printf("Plugin %s: ignoring color %q: %v", p.spec.Name, u.Color, err)
+		} else {
+			col = c
+		}
+	}
+	p.views.show(func(v *pluginView) {
+		v.label.SetText(u.Text)
+		if u.Color == "" {
+			v.label.SetColor(nil)
+		} else if col != nil {
+			v.label.SetColor(col)
+		}
+		v.tooltip.SetTooltip(u.Tooltip)
+	})
+}
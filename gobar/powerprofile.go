@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// power-profiles-daemon on the system bus
+const (
+	powerProfilesName = "net.hadess.PowerProfiles"
+	powerProfilesPath = "/net/hadess/PowerProfiles"
+)
+
+// powerProfileIcons marks the standard profiles; others show only their name
+var powerProfileIcons = map[string]string{
+	"performance": "🚀 ",
+	"balanced":    "⚖ ",
+	"power-saver": "🍃 ",
+}
+
+// powerProfileWidget shows the active power profile and opens a popup
+// window to switch it. The popup is a separate window since dialogs would be
+// clipped by the bar.
+type powerProfileWidget struct {
+	app    fyne.App
+	obj    dbus.BusObject
+	button *widget.Button
+	win    fyne.Window
+}
+
+// init registers the power profile widget
+func init() {
+	registerWidget("power", func(cfg Config) bool { return cfg.ShowPowerProfile }, func(Config) (Widget, error) {
+		return newPowerProfileWidget(fyne.CurrentApp())
+	})
+}
+
+// newPowerProfileWidget connects to power-profiles-daemon over the system bus
+func newPowerProfileWidget(a fyne.App) (*powerProfileWidget, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	p := &powerProfileWidget{app: a, obj: conn.Object(powerProfilesName, powerProfilesPath)}
+	p.button = widget.NewButton("", p.Show)
+	p.button.Importance = widget.LowImportance
+	p.button.Hide()
+	return p, nil
+}
+
+// Render returns the object to place in the bar
+func (p *powerProfileWidget) Render() fyne.CanvasObject {
+	return p.button
+}
+
+// Interval polls once a second, as the daemon is also switched elsewhere
+func (p *powerProfileWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// Update shows the active profile; the widget hides while the daemon is absent
+func (p *powerProfileWidget) Update(context.Context) {
+	var active string
+	if err := p.obj.StoreProperty(powerProfilesName+".ActiveProfile", &active); err != nil {
+		p.button.Hide()
+		return
+	}
+	setButtonText(p.button, powerProfileIcons[active]+active)
+	p.button.Show()
+}
+
+// Show opens the popup listing the available profiles
+func (p *powerProfileWidget) Show() {
+	var profiles []map[string]dbus.Variant
+	if err := p.obj.StoreProperty(powerProfilesName+".Profiles", &profiles); err != nil {
+		log.Println("Failed to list power profiles:", err)
+		return
+	}
+	var active string
+	_ = p.obj.StoreProperty(powerProfilesName+".ActiveProfile", &active)
+
+	if p.win == nil {
+		p.win = p.app.NewWindow("Power Profile")
+		p.win.SetCloseIntercept(p.win.Hide)
+	}
+	list := container.NewVBox()
+	for _, profile := range profiles {
+		name, _ := profile["Profile"].Value().(string)
+		if name == "" {
+			continue
+		}
+		b := widget.NewButton(powerProfileIcons[name]+name, func() { p.set(name) })
+		if name == active {
+			b.Importance = widget.HighImportance
+		}
+		list.Add(b)
+	}
+	p.win.SetContent(list)
+	p.win.Show()
+	p.win.RequestFocus()
+}
+
+// set switches to profile and closes the popup
+func (p *powerProfileWidget) set(profile string) {
+	if err := p.obj.SetProperty(powerProfilesName+".ActiveProfile", dbus.MakeVariant(profile)); err != nil {
+		log.Println("Failed to set power profile:", err)
+		return
+	}
+	p.win.Hide()
+	p.Update(context.Background())
+}
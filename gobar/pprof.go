@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof on the default mux
+)
+
+// startPprof serves the profiling endpoints on addr, e.g. "localhost:6060".
+// Profiles expose internals, so only loopback addresses are accepted.
+func startPprof(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return fmt.Errorf("%s is not a loopback address", addr)
+		}
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving pprof on http://%s/debug/pprof/", l.Addr())
+	go func() {
+		log.Println("pprof server stopped:", http.Serve(l, nil))
+	}()
+	return nil
+}
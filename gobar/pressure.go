@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// psiMemoryPath is the kernel's memory pressure stall information file
+const psiMemoryPath = "/proc/pressure/memory"
+
+// memoryPressure returns the "some avg10" value: the share of the last ten
+// seconds in which at least one task stalled waiting on memory. It fails on
+// kernels built without PSI.
+func memoryPressure() (float64, error) {
+	f, err := os.Open(psiMemoryPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if v, ok := strings.CutPrefix(field, "avg10="); ok {
+				return strconv.ParseFloat(v, 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("no \"some avg10\" entry in %s", psiMemoryPath)
+}
+
+// ramColor colours the RAM widget by memory pressure when PSI is available,
+// since cache-heavy usage reads high without any actual pressure, and by
+// used percentage otherwise
+func (c Config) ramColor(usedPercent float64) color.Color {
+	if pressure, err := memoryPressure(); err == nil {
+		return c.colorFor("mempressure", pressure)
+	}
+	return c.colorFor("ram", usedPercent)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// micIndicator is a red dot shown while an application records audio
+type micIndicator struct {
+	label *colorLabel
+}
+
+// cameraIndicator is a camera shown while a video device is open
+type cameraIndicator struct {
+	devices, detection string
+	label              *widget.Label
+}
+
+// init registers the microphone and camera indicators
+func init() {
+	registerWidget("mic", func(cfg Config) bool { return cfg.ShowMicIndicator }, func(Config) (Widget, error) {
+		m := &micIndicator{label: newColorLabel("●")}
+		m.label.SetColor(theme.Color(theme.ColorNameError))
+		m.label.Hide()
+		return m, nil
+	})
+	registerWidget("camera", func(cfg Config) bool { return cfg.ShowCameraIndicator }, func(cfg Config) (Widget, error) {
+		c := &cameraIndicator{devices: cfg.CameraDevices, detection: cfg.CameraDetection, label: widget.NewLabel("📷")}
+		c.label.Hide()
+		return c, nil
+	})
+}
+
+// Render returns the object to place in the bar
+func (m *micIndicator) Render() fyne.CanvasObject {
+	return m.label
+}
+
+// Interval checks once a second
+func (m *micIndicator) Interval() time.Duration {
+	return time.Second
+}
+
+// Update shows the dot while the microphone is in use
+func (m *micIndicator) Update(context.Context) {
+	if micInUse() {
+		m.label.Show()
+	} else {
+		m.label.Hide()
+	}
+}
+
+// Render returns the object to place in the bar
+func (c *cameraIndicator) Render() fyne.CanvasObject {
+	return c.label
+}
+
+// Interval checks once a second
+func (c *cameraIndicator) Interval() time.Duration {
+	return time.Second
+}
+
+// Update shows the camera while one of the devices is open
+func (c *cameraIndicator) Update(context.Context) {
+	if cameraInUse(c.devices, c.detection) {
+		c.label.Show()
+	} else {
+		c.label.Hide()
+	}
+}
+
+// micInUse reports whether any application is capturing audio, i.e. there
+// is at least one PulseAudio/PipeWire source output
+func micInUse() bool {
+	out, err := exec.Command("pactl", "list", "short", "source-outputs").Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) != ""
+}
+
+// cameraInUse reports whether a video device matching pattern is open. method
+// picks the check: "fuser" or "lsof" run that tool, "proc" scans /proc/*/fd
+// directly (only processes of the same user are visible).
+func cameraInUse(pattern, method string) bool {
+	devices, err := filepath.Glob(pattern)
+	if err != nil || len(devices) == 0 {
+		return false
+	}
+	switch method {
+	case "lsof":
+		// lsof exits 0 when at least one file is open
+		return exec.Command("lsof", append([]string{"-t"}, devices...)...).Run() == nil
+	case "proc":
+		return devicesOpen(devices)
+	default:
+		// Likewise fuser exits 0 when any process uses a device
+		return exec.Command("fuser", append([]string{"-s"}, devices...)...).Run() == nil
+	}
+}
+
+// devicesOpen scans processes' open file descriptors for any of devices
+func devicesOpen(devices []string) bool {
+	links, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err == nil && slices.Contains(devices, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processWidget shows the process count and, with ShowThreads, the threads
+type processWidget struct {
+	cfg   Config
+	label *widget.Label
+}
+
+// init registers the process count widget
+func init() {
+	registerWidget("proc", func(cfg Config) bool { return cfg.ShowProcesses && statSupport().proc.Available() }, func(cfg Config) (Widget, error) {
+		return &processWidget{cfg: cfg, label: widget.NewLabel(cfg.prefix("proc"))}, nil
+	})
+}
+
+// Render returns the object to place in the bar
+func (p *processWidget) Render() fyne.CanvasObject {
+	return p.label
+}
+
+// Interval implements Widget
+func (p *processWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// Update counts the processes
+func (p *processWidget) Update(context.Context) {
+	if text, err := processText(p.cfg.prefix("proc"), p.cfg.ShowThreads); err == nil {
+		setLabelText(p.label, text)
+	}
+}
+
+// processText formats the process count, with threads only when requested
+func processText(prefix string, showThreads bool) (string, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return "", err
+	}
+	text := fmt.Sprintf("%s%d", prefix, len(pids))
+	if showThreads {
+		// The loadavg total counts every scheduling entity, i.e. threads
+		if misc, err := load.Misc(); err == nil {
+			text += fmt.Sprintf(" Thr: %d", misc.ProcsTotal)
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// publicIPRetry is how soon a failed lookup is retried, e.g. while offline
+const publicIPRetry = time.Minute
+
+// fetchPublicIP asks a plain-text "what is my IP" service for the public address
+func fetchPublicIP(url string) (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", errors.New("not an IP address: " + ip)
+	}
+	return ip, nil
+}
+
+// publicIPWidget shows the public IP, refreshed every interval in the
+// background; clicking it copies the address. With AllOutputs one lookup
+// updates the button on every bar.
+type publicIPWidget struct {
+	url       string
+	interval  time.Duration
+	clipboard fyne.Clipboard
+	views     viewSet[*publicIPView]
+
+	mu      sync.Mutex
+	ip      string
+	fetched time.Time
+}
+
+// publicIPView is the address button on one bar
+type publicIPView struct {
+	button *widget.Button
+	prefix string
+}
+
+// newPublicIPWidget creates the widget; schedule Update to start lookups
+func newPublicIPWidget(url string, interval time.Duration, clipboard fyne.Clipboard) *publicIPWidget {
+	return &publicIPWidget{url: url, interval: interval, clipboard: clipboard}
+}
+
+// View returns a button to place in a bar, shown until ctx is done
+func (p *publicIPWidget) View(ctx context.Context, prefix string) fyne.CanvasObject {
+	v := &publicIPView{prefix: prefix}
+	v.button = widget.NewButton(prefix+"…", func() {
+		p.mu.Lock()
+		ip := p.ip
+		p.mu.Unlock()
+		if ip != "" {
+			p.clipboard.SetContent(ip)
+		}
+	})
+	v.button.Importance = widget.LowImportance
+	p.views.add(ctx, v)
+	return v.button
+}
+
+// Update looks the address up once interval has passed since the last
+// successful lookup. It is scheduled at most publicIPRetry apart so a failed
+// lookup, shown as "—", is retried sooner.
+func (p *publicIPWidget) Update() {
+	p.mu.Lock()
+	due := p.ip == "" || time.Since(p.fetched) >= p.interval
+	p.mu.Unlock()
+	if !due {
+		return
+	}
+	ip, err := fetchPublicIP(p.url)
+	p.mu.Lock()
+	p.ip, p.fetched = ip, time.Now()
+	p.mu.Unlock()
+	if err != nil {
+		ip = "—"
+	}
+	p.views.show(func(v *publicIPView) { v.button.SetText(v.prefix + ip) })
+}
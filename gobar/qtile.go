@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Qtile IPC reply status codes
+const (
+	qtileSuccess = 0
+)
+
+// qtileSelector is one [object, selector] step of a Qtile command path,
+// e.g. {"group", "1"}; a nil selector means the current object
+type qtileSelector [2]any
+
+// qtileClient sends commands to Qtile over its IPC socket. Messages use the
+// JSON form of the command protocol: [selectors, name, args, kwargs, lifted]
+// is written, the write side is shut down, and [status, result] is read back.
+type qtileClient struct {
+	path string
+}
+
+// Group is a Qtile group as reported by the groups command
+type Group struct {
+	Name    string
+	Label   string
+	Layout  string
+	Screen  *int
+	Windows []string
+	// Focused marks the group shown on the focused screen
+	Focused bool
+}
+
+// qtileSocketPath resolves the IPC socket: the override, $QTILE_SOCK, or
+// Qtile's default $XDG_CACHE_HOME/qtile/qtilesocket.$DISPLAY
+func qtileSocketPath(override string) string {
+	if override != "" {
+		return expandPath(override)
+	}
+	if sock := os.Getenv("QTILE_SOCK"); sock != "" {
+		return sock
+	}
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".cache")
+	}
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		display = os.Getenv("DISPLAY")
+	}
+	return filepath.Join(dir, "qtile", "qtilesocket."+display)
+}
+
+// call runs a command on the object addressed by selectors and returns the raw result
+func (c *qtileClient) call(selectors []qtileSelector, name string, args ...any) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", c.path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if selectors == nil {
+		selectors = []qtileSelector{}
+	}
+	if args == nil {
+		args = []any{}
+	}
+	req, err := json.Marshal([]any{selectors, name, args, map[string]any{}, false})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	// Qtile reads the request until EOF
+	if uc, ok := conn.(*net.UnixConn); ok {
+		_ = uc.CloseWrite()
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply []json.RawMessage
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("qtile: bad reply: %w", err)
+	}
+	if len(reply) != 2 {
+		return nil, errors.New("qtile: malformed reply")
+	}
+	var status int
+	if err := json.Unmarshal(reply[0], &status); err != nil {
+		return nil, fmt.Errorf("qtile: bad reply status: %w", err)
+	}
+	if status != qtileSuccess {
+		var msg string
+		_ = json.Unmarshal(reply[1], &msg)
+		return nil, fmt.Errorf("qtile: %s failed: %s", name, msg)
+	}
+	return reply[1], nil
+}
+
+// fetchGroups returns Qtile's groups in configured order with the current group marked
+func fetchGroups(c *qtileClient) ([]Group, error) {
+	raw, err := c.call(nil, "groups")
+	if err != nil {
+		return nil, err
+	}
+	// Decode the object key by key; a map would lose Qtile's group order
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("qtile: groups reply is not an object")
+	}
+	var groups []Group
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		var g Group
+		if err := dec.Decode(&g); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	raw, err = c.call([]qtileSelector{{"group", nil}}, "info")
+	if err != nil {
+		return nil, err
+	}
+	var current Group
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Focused = groups[i].Name == current.Name
+	}
+	return groups, nil
+}
+
+// switchGroup shows the named group on the focused screen
+func switchGroup(c *qtileClient, name string) error {
+	_, err := c.call([]qtileSelector{{"group", name}}, "toscreen")
+	return err
+}
+
+// currentLayout returns the name of the current group's layout, e.g. "monadtall"
+func currentLayout(c *qtileClient) (string, error) {
+	raw, err := c.call([]qtileSelector{{"layout", nil}}, "info")
+	if err != nil {
+		return "", err
+	}
+	var info struct{ Name string }
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return "", err
+	}
+	return info.Name, nil
+}
+
+// nextLayout switches the current group to its next layout
+func nextLayout(c *qtileClient) error {
+	_, err := c.call(nil, "next_layout")
+	return err
+}
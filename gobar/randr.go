@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// screenRect is an area of the root window, in pixels
+type screenRect struct {
+	x, y, width, height int
+}
+
+// randrOutput is a connected RandR output and, if it shows a CRTC, its area
+type randrOutput struct {
+	name   string
+	active bool
+	rect   screenRect
+}
+
+// randrOutputs lists the connected outputs in the server's order
+func randrOutputs() ([]randrOutput, error) {
+	X, err := barExtension("RandR", randr.Init)
+	if err != nil {
+		return nil, err
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	resources, err := randr.GetScreenResourcesCurrent(X, root).Reply()
+	if err != nil {
+		return nil, err
+	}
+	var outputs []randrOutput
+	for _, output := range resources.Outputs {
+		info, err := randr.GetOutputInfo(X, output, resources.ConfigTimestamp).Reply()
+		if err != nil || info.Connection != randr.ConnectionConnected {
+			continue
+		}
+		o := randrOutput{name: string(info.Name)}
+		if info.Crtc != 0 {
+			crtc, err := randr.GetCrtcInfo(X, info.Crtc, resources.ConfigTimestamp).Reply()
+			if err != nil {
+				return nil, err
+			}
+			o.active = true
+			o.rect = screenRect{int(crtc.X), int(crtc.Y), int(crtc.Width), int(crtc.Height)}
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// outputRect looks up a connected RandR output such as "HDMI-1" and returns
+// the area its CRTC shows
+func outputRect(name string) (screenRect, error) {
+	outputs, err := randrOutputs()
+	if err != nil {
+		return screenRect{}, err
+	}
+	for _, o := range outputs {
+		if o.name != name {
+			continue
+		}
+		if !o.active {
+			return screenRect{}, fmt.Errorf("output %s is not active", name)
+		}
+		return o.rect, nil
+	}
+	return screenRect{}, fmt.Errorf("no output named %s", name)
+}
+
+// firstActiveOutput names the first output that shows a CRTC
+func firstActiveOutput() (string, error) {
+	outputs, err := randrOutputs()
+	if err != nil {
+		return "", err
+	}
+	for _, o := range outputs {
+		if o.active {
+			return o.name, nil
+		}
+	}
+	return "", errors.New("no active output")
+}
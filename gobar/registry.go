@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Widget is a bar widget that lives in its own file and registers itself
+// with registerWidget, so main needs no changes to place and update it
+type Widget interface {
+	// Render returns the object to place in the bar; it is called once
+	Render() fyne.CanvasObject
+	// Update refreshes the widget; ctx ends when the bar shuts down
+	Update(ctx context.Context)
+	// Interval is the time between updates, or 0 for a widget that
+	// updates itself after the first Update
+	Interval() time.Duration
+}
+
+// widgetClicker is implemented by widgets that handle clicks themselves
+type widgetClicker interface {
+	OnClick(button desktop.MouseButton)
+}
+
+// widgetExporter is implemented by widgets that export their readings with
+// MetricsAddr; SetMetrics is called before the first Update, with a nil
+// registry when export is off
+type widgetExporter interface {
+	SetMetrics(r *metricsRegistry)
+}
+
+// widgetEntry is a registered widget
+type widgetEntry struct {
+	// enabled reports whether cfg turns the widget on; nil means it is on
+	// when listed in Config.Widgets
+	enabled func(cfg Config) bool
+	build   func(cfg Config) (Widget, error)
+}
+
+// widgetRegistry holds the registered widgets by name
+var widgetRegistry = map[string]widgetEntry{}
+
+// registerWidget adds a widget under name, from an init function. The name
+// is also accepted in Spacers and Widgets and by the control socket.
+func registerWidget(name string, enabled func(Config) bool, build func(Config) (Widget, error)) {
+	if _, ok := widgetRegistry[name]; ok {
+		panic("widget registered twice: " + name)
+	}
+	widgetRegistry[name] = widgetEntry{enabled: enabled, build: build}
+	if !slices.Contains(barWidgets, name) {
+		barWidgets = append(barWidgets, name)
+	}
+}
+
+// widgetSet places registered widgets on the bar and schedules their updates
+type widgetSet struct {
+	ctx     context.Context
+	cfg     Config
+	sched   *scheduler
+	bar     barBox
+	metrics *metricsRegistry
+	placed  map[string]bool
+}
+
+// newWidgetSet prepares placing registered widgets on bar, exporting to
+// metrics, which is nil when export is off
+func newWidgetSet(ctx context.Context, cfg Config, sched *scheduler, bar barBox, metrics *metricsRegistry) *widgetSet {
+	return &widgetSet{ctx: ctx, cfg: cfg, sched: sched, bar: bar, metrics: metrics, placed: map[string]bool{}}
+}
+
+// place builds the named widget if the config enables it and adds it to the
+// bar, followed by after, e.g. a separator. Built-in widgets are placed by
+// name to keep their spot in the default order.
+func (s *widgetSet) place(name string, after ...fyne.CanvasObject) {
+	s.placed[name] = true
+	entry := widgetRegistry[name]
+	enabled := slices.Contains(s.cfg.Widgets, name)
+	if entry.enabled != nil {
+		enabled = entry.enabled(s.cfg)
+	}
+	if !enabled {
+		return
+	}
+	w, err := entry.build(s.cfg)
+	if err != nil {
+		log.Printf("Widget %s disabled: %v", name, err)
+		return
+	}
+	if e, ok := w.(widgetExporter); ok {
+		e.SetMetrics(s.metrics)
+	}
+	obj := w.Render()
+	if c, ok := w.(widgetClicker); ok {
+		obj = newClickArea(obj, c.OnClick)
+	}
+	s.bar.add(name, append([]fyne.CanvasObject{obj}, after...)...)
+	if interval := w.Interval(); interval > 0 {
+		s.sched.Every(name, interval, func() { w.Update(s.ctx) })
+	} else {
+		s.sched.Go(name, func() { w.Update(s.ctx) })
+	}
+}
+
+// placeRest places the registered widgets not placed by name, sorted by name
+func (s *widgetSet) placeRest() {
+	var names []string
+	for name := range widgetRegistry {
+		if !s.placed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.place(name)
+	}
+}
+
+// clickArea wraps content and reports each click with its mouse button
+type clickArea struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+	onClick func(desktop.MouseButton)
+}
+
+// newClickArea creates a clickArea calling onClick
+func newClickArea(content fyne.CanvasObject, onClick func(desktop.MouseButton)) *clickArea {
+	c := &clickArea{content: content, onClick: onClick}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// MouseDown implements desktop.Mouseable
+func (c *clickArea) MouseDown(*desktop.MouseEvent) {}
+
+// MouseUp implements desktop.Mouseable
+func (c *clickArea) MouseUp(ev *desktop.MouseEvent) {
+	c.onClick(ev.Button)
+}
+
+// CreateRenderer draws the wrapped content unchanged
+func (c *clickArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(c.content)
+}
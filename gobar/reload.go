@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+)
+
+// reloadEnv tells a re-executed gobar to confirm that the reload worked
+const reloadEnv = "GOBAR_RELOADED"
+
+// reloadConfig validates the config file and restarts gobar with it. The bar
+// is built once at startup, so applying a new config means re-executing the
+// process; if the config has errors the running bar is left untouched.
+func reloadConfig() error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	return restartGobar()
+}
+
+// restartGobar re-executes gobar, which then loads the config again
+func restartGobar() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, append(os.Environ(), reloadEnv+"=1"))
+}
+
+// confirmReload opens a dialog saying a reload completed, once per re-exec
+func confirmReload(a fyne.App) {
+	if os.Getenv(reloadEnv) == "" {
+		return
+	}
+	os.Unsetenv(reloadEnv)
+	showMessageWindow(a, "Config Reloaded", "GoBar restarted with the new config.")
+}
+
+// showConfigErrors lists why a reload was refused
+func showConfigErrors(a fyne.App, err error) {
+	showMessageWindow(a, "Config Errors", err.Error())
+}
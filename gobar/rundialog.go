@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// runHistoryMax is how many run dialog commands are kept in the cache
+	runHistoryMax = 50
+	// runFailWindow is how soon a command must fail for its stderr to be shown
+	runFailWindow = 2 * time.Second
+	// runStderrMax is how much stderr is kept for the failure message
+	runStderrMax = 4096
+)
+
+// runDialog is a small window for typing a shell command to run, with
+// history recalled by the up/down arrows
+type runDialog struct {
+	app   fyne.App
+	win   fyne.Window
+	entry *historyEntry
+
+	history []string
+	pos     int // index into history while browsing; len(history) is the new line
+}
+
+// newRunDialog prepares the dialog; the window is created on first Show
+func newRunDialog(a fyne.App) *runDialog {
+	return &runDialog{app: a}
+}
+
+// Show opens the dialog with an empty command line
+func (d *runDialog) Show() {
+	if d.win == nil {
+		d.win = d.app.NewWindow("Run")
+		d.entry = newHistoryEntry()
+		d.entry.SetPlaceHolder("Command")
+		d.entry.OnSubmitted = d.submit
+		d.entry.onUp = func() { d.recall(-1) }
+		d.entry.onDown = func() { d.recall(1) }
+		d.win.SetContent(d.entry)
+		d.win.Resize(fyne.NewSize(400, d.entry.MinSize().Height))
+		d.win.SetCloseIntercept(d.win.Hide)
+	}
+	d.history = loadCache().RunHistory
+	d.pos = len(d.history)
+	d.entry.SetText("")
+	d.win.Show()
+	d.win.Canvas().Focus(d.entry)
+}
+
+// recall steps through the history, back for -1 and forward for 1
+func (d *runDialog) recall(step int) {
+	pos := d.pos + step
+	if pos < 0 || pos > len(d.history) {
+		return
+	}
+	d.pos = pos
+	if pos == len(d.history) {
+		d.entry.SetText("")
+	} else {
+		d.entry.SetText(d.history[pos])
+	}
+	d.entry.CursorColumn = len([]rune(d.entry.Text))
+	d.entry.Refresh()
+}
+
+// submit records the command, hides the dialog and runs it
+func (d *runDialog) submit(cmdline string) {
+	cmdline = strings.TrimSpace(cmdline)
+	d.win.Hide()
+	if cmdline == "" {
+		return
+	}
+	state := loadCache()
+	state.RunHistory = appendHistory(state.RunHistory, cmdline, runHistoryMax)
+	if err := saveCache(state); err != nil {
+		log.Println("Failed to save run history:", err)
+	}
+	go d.run(cmdline)
+}
+
+// run starts cmdline and shows its stderr if it fails within runFailWindow
+func (d *runDialog) run(cmdline string) {
+	stderr := &headBuffer{max: runStderrMax}
+	cmd := exec.Command("sh", "-c", cmdline)
+	cmd.Stderr = stderr
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		showMessageWindow(d.app, "Command Failed", err.Error())
+		return
+	}
+	err := cmd.Wait()
+	if err == nil || time.Since(start) > runFailWindow {
+		return
+	}
+	text := cmdline + ": " + err.Error()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		text += "\n\n" + msg
+	}
+	showMessageWindow(d.app, "Command Failed", text)
+}
+
+// appendHistory adds cmdline as the newest entry, dropping an earlier
+// duplicate and the oldest entries beyond max
+func appendHistory(history []string, cmdline string, max int) []string {
+	out := make([]string, 0, len(history)+1)
+	for _, h := range history {
+		if h != cmdline {
+			out = append(out, h)
+		}
+	}
+	out = append(out, cmdline)
+	if len(out) > max {
+		out = out[len(out)-max:]
+	}
+	return out
+}
+
+// headBuffer keeps the first max bytes written and discards the rest, so a
+// long-running command's stderr cannot grow without bound
+type headBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+// Write implements io.Writer, always reporting the full length
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// historyEntry is an entry reporting up/down arrows instead of moving the cursor
+type historyEntry struct {
+	widget.Entry
+	onUp, onDown func()
+}
+
+// newHistoryEntry creates a single-line historyEntry
+func newHistoryEntry() *historyEntry {
+	e := &historyEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// TypedKey intercepts the arrows and passes other keys to the entry
+func (e *historyEntry) TypedKey(key *fyne.KeyEvent) {
+	switch {
+	case key.Name == fyne.KeyUp && e.onUp != nil:
+		e.onUp()
+	case key.Name == fyne.KeyDown && e.onDown != nil:
+		e.onDown()
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Restart delays of a widget event loop after a panic
+const (
+	widgetRestartMin = time.Second
+	widgetRestartMax = time.Minute
+)
+
+// scheduler runs the bar's periodic work. Every task stops when the shared
+// context is cancelled, survives panics in its body, and records how often
+// and how long it ran so the control socket can report on them.
+type scheduler struct {
+	ctx    context.Context
+	suffix string // appended to task names, "@DP-2" for a bar's tasks
+	*taskList
+
+	// Restart delays of Go and Supervise, widgetRestartMin and
+	// widgetRestartMax but in tests
+	restartMin, restartMax time.Duration
+}
+
+// taskList is the tasks of a scheduler and of the schedulers made with sub
+type taskList struct {
+	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	tasks []*taskStatus
+}
+
+// taskStatus describes a registered task
+type taskStatus struct {
+	Name         string        `json:"name"`
+	Interval     time.Duration `json:"interval"`
+	Runs         int           `json:"runs"`
+	LastRun      time.Time     `json:"lastRun"`
+	LastDuration time.Duration `json:"lastDuration"`
+	Panics       int           `json:"panics,omitempty"`
+
+	owner *scheduler
+}
+
+// newScheduler creates a scheduler whose tasks run until ctx is done
+func newScheduler(ctx context.Context) *scheduler {
+	return &scheduler{ctx: ctx, taskList: &taskList{}, restartMin: widgetRestartMin, restartMax: widgetRestartMax}
+}
+
+// sub returns a scheduler for the tasks of the bar on output, e.g. with
+// AllOutputs. They are listed and waited for with the others, stop when ctx
+// is done and then drop out of Tasks.
+func (s *scheduler) sub(ctx context.Context, output string) *scheduler {
+	sub := &scheduler{ctx: ctx, suffix: "@" + output, taskList: s.taskList, restartMin: s.restartMin, restartMax: s.restartMax}
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.tasks = slices.DeleteFunc(s.tasks, func(t *taskStatus) bool { return t.owner == sub })
+	})
+	return sub
+}
+
+// register adds a task to the list
+func (s *scheduler) register(name string, interval time.Duration) *taskStatus {
+	status := &taskStatus{Name: name + s.suffix, Interval: interval, owner: s}
+	s.mu.Lock()
+	s.tasks = append(s.tasks, status)
+	s.mu.Unlock()
+	return status
+}
+
+// Every runs fn now and then every interval, measured from the end of the
+// previous run so a slow run never overlaps the next
+func (s *scheduler) Every(name string, interval time.Duration, fn func()) {
+	s.start(name, interval, fn, func(time.Time) time.Duration { return interval })
+}
+
+// EveryAligned runs fn now and then just after each multiple of interval on
+// the wall clock, e.g. at the start of every minute
+func (s *scheduler) EveryAligned(name string, interval time.Duration, fn func()) {
+	s.start(name, interval, fn, func(now time.Time) time.Duration {
+		return now.Truncate(interval).Add(interval).Sub(now)
+	})
+}
+
+// start registers a task and runs it in its own goroutine
+func (s *scheduler) start(name string, interval time.Duration, fn func(), wait func(time.Time) time.Duration) {
+	status := s.register(name, interval)
+	name = status.Name
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			start := time.Now()
+			ok := runTask(name, fn)
+			end := time.Now()
+			s.mu.Lock()
+			status.Runs++
+			status.LastRun, status.LastDuration = start, end.Sub(start)
+			if !ok {
+				status.Panics++
+			}
+			s.mu.Unlock()
+
+			timer := time.NewTimer(wait(end))
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Go runs fn, the event loop of a widget that updates itself, in its own
+// goroutine. A panic restarts it after a delay that doubles from
+// widgetRestartMin up to widgetRestartMax, resetting once a run lasted that
+// long, so one broken widget can't take the bar down; a normal return, e.g.
+// when its connection closed, ends it.
+func (s *scheduler) Go(name string, fn func()) {
+	s.restart(name, func() error { fn(); return nil }, false)
+}
+
+// Supervise is Go for work that should never end, such as a plugin's
+// process: fn is also restarted after those delays when it returns, with
+// the error it returned logged, until the context is done
+func (s *scheduler) Supervise(name string, fn func() error) {
+	s.restart(name, fn, true)
+}
+
+// restart runs fn for Go and Supervise, restarting it after a panic or, with
+// always, after any return
+func (s *scheduler) restart(name string, fn func() error, always bool) {
+	status := s.register(name, 0)
+	name = status.Name
+
+	go func() {
+		delay := s.restartMin
+		for {
+			start := time.Now()
+			var err error
+			ok := runTask(name, func() { err = fn() })
+			end := time.Now()
+			s.mu.Lock()
+			status.Runs++
+			status.LastRun, status.LastDuration = start, end.Sub(start)
+			if !ok {
+				status.Panics++
+			}
+			s.mu.Unlock()
+			if s.ctx.Err() != nil || ok && !always {
+				return
+			}
+			if end.Sub(start) > s.restartMax {
+				delay = s.restartMin
+			}
+			if ok {
+				log.Printf("Task %s ended (%v), restarting in %v", name, err, delay)
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, s.restartMax)
+		}
+	}()
+}
+
+// runTask calls fn, logging a panic with its stack instead of letting it end
+// the task, so one widget's bad read can't freeze its updates for good. It
+// reports whether fn returned normally.
+func runTask(name string, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Task %s panicked, retrying on its next run: %v\n%s", name, r, debug.Stack())
+		}
+	}()
+	fn()
+	return true
+}
+
+// Wait blocks until every task has stopped after the context is cancelled
+func (s *scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// WaitTimeout is Wait giving up after d, for a task stuck in a slow command;
+// it reports whether every task stopped
+func (s *scheduler) WaitTimeout(d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// Tasks returns a snapshot of the registered tasks, by name
+func (s *scheduler) Tasks() []taskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := make([]taskStatus, len(s.tasks))
+	for i, t := range s.tasks {
+		tasks[i] = *t
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks
+}
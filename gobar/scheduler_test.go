@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitRuns polls the scheduler until its only task ran runs times
+func waitRuns(t *testing.T, s *scheduler, runs int) taskStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tasks := s.Tasks()
+		if len(tasks) != 1 {
+			t.Fatalf("scheduler lists %d tasks, want 1", len(tasks))
+		}
+		if tasks[0].Runs >= runs || time.Now().After(deadline) {
+			return tasks[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGoRestartsAfterPanics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newScheduler(ctx)
+	s.restartMin, s.restartMax = 10*time.Millisecond, 100*time.Millisecond
+
+	var mu sync.Mutex
+	calls := 0
+	s.Go("widget", func() {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n <= 2 {
+			panic("bad read")
+		}
+	})
+
+	status := waitRuns(t, s, 3)
+	if status.Runs != 3 || status.Panics != 2 {
+		t.Fatalf("task ran %d times with %d panics, want 3 runs and 2 panics", status.Runs, status.Panics)
+	}
+	// A normal return ends the task for good
+	time.Sleep(3 * s.restartMax)
+	if status := waitRuns(t, s, 0); status.Runs != 3 {
+		t.Errorf("task ran %d times after returning, want 3", status.Runs)
+	}
+}
+
+func TestGoRestartDelayResets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newScheduler(ctx)
+	s.restartMin, s.restartMax = 30*time.Millisecond, 150*time.Millisecond
+
+	var mu sync.Mutex
+	var starts []time.Time
+	var longEnd time.Time
+	s.Go("widget", func() {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		n := len(starts)
+		mu.Unlock()
+		switch n {
+		case 1, 2:
+			panic("bad read")
+		case 3:
+			// Outlive restartMax before failing, which resets the delay
+			time.Sleep(200 * time.Millisecond)
+			mu.Lock()
+			longEnd = time.Now()
+			mu.Unlock()
+			panic("bad read")
+		}
+	})
+
+	status := waitRuns(t, s, 4)
+	if status.Runs != 4 || status.Panics != 3 {
+		t.Fatalf("task ran %d times with %d panics, want 4 runs and 3 panics", status.Runs, status.Panics)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gap := starts[1].Sub(starts[0]); gap < s.restartMin {
+		t.Errorf("first restart came after %v, want at least %v", gap, s.restartMin)
+	}
+	if gap := starts[2].Sub(starts[1]); gap < 2*s.restartMin {
+		t.Errorf("second restart came after %v, want the doubled %v", gap, 2*s.restartMin)
+	}
+	// Without the reset the delay would have doubled again to 4*restartMin
+	if gap := starts[3].Sub(longEnd); gap < s.restartMin || gap >= 3*s.restartMin {
+		t.Errorf("restart after a long run came after %v, want about %v", gap, s.restartMin)
+	}
+}
+
+func TestEveryWaitsFromTheEndOfEachRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newScheduler(ctx)
+	const interval = 20 * time.Millisecond
+
+	var mu sync.Mutex
+	var starts, ends []time.Time
+	s.Every("poll", interval, func() {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		// A slow run pushes the next one back instead of overlapping it
+		time.Sleep(interval)
+		mu.Lock()
+		ends = append(ends, time.Now())
+		mu.Unlock()
+	})
+
+	status := waitRuns(t, s, 3)
+	cancel()
+	if !s.WaitTimeout(time.Second) {
+		t.Fatal("task still running after cancel")
+	}
+	if status.Interval != interval {
+		t.Errorf("task lists interval %v, want %v", status.Interval, interval)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(ends[i-1]); gap < interval {
+			t.Errorf("run %d started %v after the previous one ended, want at least %v", i+1, gap, interval)
+		}
+	}
+}
+
+func TestEveryAlignedRunsAfterEachMultiple(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newScheduler(ctx)
+	const interval = 100 * time.Millisecond
+
+	var mu sync.Mutex
+	var starts []time.Time
+	s.EveryAligned("clock", interval, func() {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+	})
+
+	waitRuns(t, s, 3)
+	mu.Lock()
+	defer mu.Unlock()
+	// The first run is immediate, the others follow the wall clock
+	for i := 1; i < len(starts); i++ {
+		if late := starts[i].Sub(starts[i].Truncate(interval)); late > interval/2 {
+			t.Errorf("run %d came %v after a multiple of %v, want just after it", i+1, late, interval)
+		}
+		if starts[i].Truncate(interval).Equal(starts[i-1].Truncate(interval)) {
+			t.Errorf("runs %d and %d fell in the same %v", i, i+1, interval)
+		}
+	}
+}
+
+func TestEveryRecoversFromPanics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newScheduler(ctx)
+
+	s.Every("broken", 10*time.Millisecond, func() { panic("bad read") })
+
+	status := waitRuns(t, s, 3)
+	cancel()
+	if !s.WaitTimeout(time.Second) {
+		t.Fatal("task still running after cancel")
+	}
+	if status.Runs < 3 || status.Panics != status.Runs {
+		t.Errorf("task ran %d times with %d panics, want every run to panic and the task to go on", status.Runs, status.Panics)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// screenshotPath fills the {timestamp} placeholder of a path template
+func screenshotPath(template, timeFormat string, now time.Time) string {
+	return expandPath(strings.ReplaceAll(template, "{timestamp}", now.Format(timeFormat)))
+}
+
+// takeScreenshot runs the region-screenshot command with {path} replaced by
+// the quoted target path, waiting for the user to finish selecting.
+func takeScreenshot(command, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	cmdline := strings.ReplaceAll(command, "{path}", shellQuote(path))
+	if out, err := exec.Command("sh", "-c", cmdline).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	// Cancelling the selection usually exits cleanly without writing a file
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no screenshot written to %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+)
+
+// systemd-logind on the system bus
+const (
+	logindName    = "org.freedesktop.login1"
+	logindPath    = "/org/freedesktop/login1"
+	logindManager = "org.freedesktop.login1.Manager"
+	logindSession = "org.freedesktop.login1.Session"
+	// The session gobar runs in
+	logindSelfPath = "/org/freedesktop/login1/session/auto"
+)
+
+// sessionAction is an entry of the power menu: a logind Manager method,
+// taking the interactive flag, and the Can method telling if it's allowed
+type sessionAction struct {
+	icon, name, method, can string
+}
+
+// sessionActions are the menu's entries after Lock, in menu order
+var sessionActions = []sessionAction{
+	{"⏾", "Suspend", "Suspend", "CanSuspend"},
+	{"❄", "Hibernate", "Hibernate", "CanHibernate"},
+	{"↻", "Reboot", "Reboot", "CanReboot"},
+	{"⏻", "Power Off", "PowerOff", "CanPowerOff"},
+}
+
+// sessionWidget is a power button opening a window to lock the session,
+// suspend, hibernate, reboot or power off through logind, next to a ☕ shown
+// while an application blocks sleep. Like the power profile popup, the menu
+// is a separate window since dialogs would be clipped by the bar.
+type sessionWidget struct {
+	app         fyne.App
+	conn        *dbus.Conn
+	lockCommand string
+	box         *fyne.Container
+	inhibited   *tooltipArea
+	win         fyne.Window
+}
+
+// init registers the session menu
+func init() {
+	registerWidget("session", func(cfg Config) bool { return cfg.ShowSessionMenu }, func(cfg Config) (Widget, error) {
+		return newSessionWidget(fyne.CurrentApp(), cfg.LockCommand)
+	})
+}
+
+// newSessionWidget connects to logind over the system bus; lockCommand,
+// when set, locks the screen instead of logind's Lock
+func newSessionWidget(a fyne.App, lockCommand string) (*sessionWidget, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	s := &sessionWidget{app: a, conn: conn, lockCommand: lockCommand}
+	button := widget.NewButton("⏻", s.Show)
+	button.Importance = widget.LowImportance
+	s.inhibited = newTooltipArea(widget.NewLabel("☕"))
+	s.inhibited.Hide()
+	s.box = container.NewHBox(s.inhibited, button)
+	return s, nil
+}
+
+// Render returns the object to place in the bar
+func (s *sessionWidget) Render() fyne.CanvasObject {
+	return s.box
+}
+
+// Interval checks the inhibitors once a second
+func (s *sessionWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// Update shows the inhibit indicator while sleep is blocked, with the
+// blocking applications and their reasons as its tooltip
+func (s *sessionWidget) Update(context.Context) {
+	var inhibitors []struct {
+		What, Who, Why, Mode string
+		UID, PID             uint32
+	}
+	if err := s.conn.Object(logindName, logindPath).Call(logindManager+".ListInhibitors", 0).Store(&inhibitors); err != nil {
+		s.inhibited.Hide()
+		return
+	}
+	var blocking []string
+	for _, i := range inhibitors {
+		if i.Mode == "block" && strings.Contains(":"+i.What+":", ":sleep:") {
+			blocking = append(blocking, fmt.Sprintf("%s: %s", i.Who, i.Why))
+		}
+	}
+	if len(blocking) == 0 {
+		s.inhibited.Hide()
+		return
+	}
+	s.inhibited.SetTooltip("Sleep inhibited by\n" + strings.Join(blocking, "\n"))
+	s.inhibited.Show()
+}
+
+// Show opens the menu, leaving out the actions logind doesn't allow
+func (s *sessionWidget) Show() {
+	if s.win == nil {
+		s.win = s.app.NewWindow("Session")
+		s.win.SetCloseIntercept(s.win.Hide)
+	}
+	list := container.NewVBox(widget.NewButton("🔒 Lock", s.lock))
+	manager := s.conn.Object(logindName, logindPath)
+	for _, action := range sessionActions {
+		var can string
+		if err := manager.Call(logindManager+"."+action.can, 0).Store(&can); err != nil || can == "na" || can == "no" {
+			continue
+		}
+		action := action
+		list.Add(widget.NewButton(action.icon+" "+action.name, func() { s.confirm(action) }))
+	}
+	s.win.SetContent(list)
+	s.win.Show()
+	s.win.RequestFocus()
+}
+
+// lock locks the session with the lock command, or asks logind to, which
+// signals a locker started by e.g. xss-lock
+func (s *sessionWidget) lock() {
+	s.win.Hide()
+	if s.lockCommand != "" {
+		launchCommand(s.lockCommand)
+		return
+	}
+	if call := s.conn.Object(logindName, logindSelfPath).Call(logindSession+".Lock", 0); call.Err != nil {
+		log.Println("Failed to lock the session:", call.Err)
+	}
+}
+
+// confirm asks before running action, in place of the menu
+func (s *sessionWidget) confirm(action sessionAction) {
+	question := widget.NewLabel(action.name + " now?")
+	yes := widget.NewButton(action.icon+" "+action.name, func() {
+		s.win.Hide()
+		// Interactive, so polkit may ask for a password when others are logged in
+		if call := s.conn.Object(logindName, logindPath).Call(logindManager+"."+action.method, 0, true); call.Err != nil {
+			log.Printf("%s failed: %v", action.method, call.Err)
+		}
+	})
+	yes.Importance = widget.DangerImportance
+	cancel := widget.NewButton("Cancel", s.win.Hide)
+	s.win.SetContent(container.NewVBox(question, container.NewGridWithColumns(2, cancel, yes)))
+}
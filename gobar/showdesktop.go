@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// iconicState is the ICCCM WM_CHANGE_STATE value for minimizing a window
+const iconicState = 3
+
+// desktopToggler minimizes all windows and restores them on the next call.
+// It uses _NET_SHOWING_DESKTOP when the window manager supports it, and
+// otherwise iconifies each client itself, remembering which to restore.
+type desktopToggler struct {
+	minimized []xproto.Window
+}
+
+// Toggle shows the desktop, or brings the windows back
+func (d *desktopToggler) Toggle() {
+	X, err := barConn()
+	if err != nil {
+		log.Println("Show desktop failed:", err)
+		return
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	if hasAtom(X, root, "_NET_SUPPORTED", "_NET_SHOWING_DESKTOP") {
+		err = toggleShowingDesktop(X, root)
+	} else if d.minimized != nil {
+		err = d.restore(X)
+	} else {
+		err = d.minimizeAll(X, root)
+	}
+	if err != nil {
+		log.Println("Show desktop failed:", err)
+	}
+}
+
+// toggleShowingDesktop flips the window manager's show-desktop mode
+func toggleShowingDesktop(X *xgb.Conn, root xproto.Window) error {
+	showing, err := getProperty32(X, root, "_NET_SHOWING_DESKTOP")
+	if err != nil {
+		return err
+	}
+	value := uint32(1)
+	if len(showing) > 0 && showing[0] != 0 {
+		value = 0
+	}
+	return sendRootMessage(X, root, "_NET_SHOWING_DESKTOP", value)
+}
+
+// minimizeAll iconifies every visible client except docks such as the bar
+func (d *desktopToggler) minimizeAll(X *xgb.Conn, root xproto.Window) error {
+	clients, err := getProperty32(X, root, "_NET_CLIENT_LIST")
+	if err != nil {
+		return err
+	}
+	d.minimized = []xproto.Window{}
+	for _, id := range clients {
+		win := xproto.Window(id)
+		if hasAtom(X, win, "_NET_WM_WINDOW_TYPE", "_NET_WM_WINDOW_TYPE_DOCK", "_NET_WM_WINDOW_TYPE_DESKTOP") ||
+			hasAtom(X, win, "_NET_WM_STATE", "_NET_WM_STATE_HIDDEN") {
+			continue
+		}
+		if err := sendRootMessage(X, win, "WM_CHANGE_STATE", iconicState); err != nil {
+			return fmt.Errorf("failed to minimize window %d: %w", win, err)
+		}
+		d.minimized = append(d.minimized, win)
+	}
+	return nil
+}
+
+// restore activates the windows minimized by minimizeAll, skipping any that
+// have closed since
+func (d *desktopToggler) restore(X *xgb.Conn) error {
+	windows := d.minimized
+	d.minimized = nil
+	for _, win := range windows {
+		if _, err := xproto.GetWindowAttributes(X, win).Reply(); err != nil {
+			continue
+		}
+		if err := activateWindow(X, win); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendRootMessage sends a 32-bit client message about win to the window
+// manager through the root window
+func sendRootMessage(X *xgb.Conn, win xproto.Window, name string, data ...uint32) error {
+	atom, err := internAtom(X, name)
+	if err != nil {
+		return err
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   atom,
+		Data:   xproto.ClientMessageDataUnionData32New(append(data, make([]uint32, 5-len(data))...)),
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	return xproto.SendEventChecked(X, false, root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect, string(ev.Bytes())).Check()
+}
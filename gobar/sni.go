@@ -0,0 +1,440 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// StatusNotifierItem protocol names
+const (
+	sniWatcherName = "org.kde.StatusNotifierWatcher"
+	sniWatcherPath = "/StatusNotifierWatcher"
+	sniItemIface   = "org.kde.StatusNotifierItem"
+	sniItemPath    = "/StatusNotifierItem"
+)
+
+// trayHost is a minimal StatusNotifierHost that shows other apps' tray icons
+// as bar buttons. On a bare Qtile session nothing provides the
+// StatusNotifierWatcher, so the host takes that name and acts as the watcher
+// itself; otherwise it registers with the running one.
+type trayHost struct {
+	conn     *dbus.Conn
+	box      *fyne.Container
+	props    *prop.Properties // set when gobar is the watcher
+	iconSize int
+	menu     trayItemMenu
+
+	mu    sync.Mutex
+	items map[string]*trayItem
+}
+
+// trayItem is one registered icon, keyed by its "bus/path" service string
+type trayItem struct {
+	bus   string
+	owner string // unique bus name, to match signals and NameOwnerChanged
+	path  dbus.ObjectPath
+	// The item's dbusmenu, and whether clicks open it rather than activate
+	menu       dbus.ObjectPath
+	itemIsMenu bool
+	// The icon, or the title on a button while the item has no icon
+	view   *fyne.Container
+	image  *canvas.Image
+	button *widget.Button
+}
+
+// newTrayHost connects to the session bus and starts tracking tray items,
+// drawn iconSize pixels square
+func newTrayHost(iconSize int) (*trayHost, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	h := &trayHost{conn: conn, box: container.NewHBox(), items: map[string]*trayItem{}, iconSize: iconSize}
+	// Hidden while no application has an icon, so the bar keeps no empty gap
+	h.box.Hide()
+
+	for _, match := range [][]dbus.MatchOption{
+		{dbus.WithMatchInterface("org.freedesktop.DBus"), dbus.WithMatchMember("NameOwnerChanged")},
+		{dbus.WithMatchInterface(sniItemIface)},
+		{dbus.WithMatchInterface(sniWatcherName)},
+	} {
+		if err := conn.AddMatchSignal(match...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	reply, err := conn.RequestName(sniWatcherName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply == dbus.RequestNameReplyPrimaryOwner {
+		if err := h.serveWatcher(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		go h.listen(signals)
+		return h, nil
+	}
+
+	// Another watcher runs: register as a host and load its items
+	hostName := fmt.Sprintf("org.kde.StatusNotifierHost-%d", os.Getpid())
+	if _, err := conn.RequestName(hostName, dbus.NameFlagDoNotQueue); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	watcher := conn.Object(sniWatcherName, sniWatcherPath)
+	if call := watcher.Call(sniWatcherName+".RegisterStatusNotifierHost", 0, hostName); call.Err != nil {
+		log.Println("Failed to register tray host:", call.Err)
+	}
+	var registered []string
+	if v, err := watcher.GetProperty(sniWatcherName + ".RegisteredStatusNotifierItems"); err == nil {
+		_ = v.Store(&registered)
+	}
+	for _, service := range registered {
+		go h.add(service)
+	}
+	go h.listen(signals)
+	return h, nil
+}
+
+// CanvasObject returns the icon row to place in the bar
+func (h *trayHost) CanvasObject() fyne.CanvasObject {
+	return h.box
+}
+
+// sniWatcher implements the org.kde.StatusNotifierWatcher methods
+type sniWatcher struct {
+	h *trayHost
+}
+
+// RegisterStatusNotifierItem is called by apps with a bus name or an object
+// path on their own connection
+func (w sniWatcher) RegisterStatusNotifierItem(sender dbus.Sender, service string) *dbus.Error {
+	if strings.HasPrefix(service, "/") {
+		service = string(sender) + service
+	}
+	go w.h.add(service)
+	return nil
+}
+
+// RegisterStatusNotifierHost accepts other hosts; they share the item list
+func (w sniWatcher) RegisterStatusNotifierHost(service string) *dbus.Error {
+	return nil
+}
+
+// serveWatcher exports the watcher object once gobar owns its name
+func (h *trayHost) serveWatcher() error {
+	w := sniWatcher{h}
+	if err := h.conn.Export(w, sniWatcherPath, sniWatcherName); err != nil {
+		return err
+	}
+	props, err := prop.Export(h.conn, sniWatcherPath, prop.Map{
+		sniWatcherName: {
+			"RegisteredStatusNotifierItems":  {Value: []string{}, Emit: prop.EmitTrue},
+			"IsStatusNotifierHostRegistered": {Value: true, Emit: prop.EmitTrue},
+			"ProtocolVersion":                {Value: int32(0), Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	h.props = props
+	node := &introspect.Node{
+		Name: sniWatcherPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       sniWatcherName,
+				Methods:    introspect.Methods(w),
+				Properties: props.Introspection(sniWatcherName),
+				Signals: []introspect.Signal{
+					{Name: "StatusNotifierItemRegistered", Args: []introspect.Arg{{Name: "service", Type: "s"}}},
+					{Name: "StatusNotifierItemUnregistered", Args: []introspect.Arg{{Name: "service", Type: "s"}}},
+					{Name: "StatusNotifierHostRegistered"},
+				},
+			},
+		},
+	}
+	return h.conn.Export(introspect.NewIntrospectable(node), sniWatcherPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// listen handles item, watcher and bus name signals
+func (h *trayHost) listen(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		switch {
+		case sig.Name == "org.freedesktop.DBus.NameOwnerChanged" && len(sig.Body) == 3:
+			name, _ := sig.Body[0].(string)
+			newOwner, _ := sig.Body[2].(string)
+			if newOwner == "" {
+				h.removeOwner(name)
+			}
+		case sig.Name == sniWatcherName+".StatusNotifierItemRegistered" && h.props == nil && len(sig.Body) == 1:
+			if service, ok := sig.Body[0].(string); ok {
+				go h.add(service)
+			}
+		case sig.Name == sniWatcherName+".StatusNotifierItemUnregistered" && h.props == nil && len(sig.Body) == 1:
+			if service, ok := sig.Body[0].(string); ok {
+				h.remove(service)
+			}
+		case strings.HasPrefix(sig.Name, sniItemIface+".New"):
+			// NewIcon, NewTitle, NewStatus, ...
+			h.mu.Lock()
+			var changed []*trayItem
+			for _, item := range h.items {
+				if item.owner == sig.Sender && item.path == sig.Path {
+					changed = append(changed, item)
+				}
+			}
+			h.mu.Unlock()
+			for _, item := range changed {
+				h.refresh(item)
+			}
+		}
+	}
+}
+
+// splitService parses "bus/path" or a bare bus name using the default path
+func splitService(service string) (string, dbus.ObjectPath) {
+	if i := strings.Index(service, "/"); i >= 0 {
+		return service[:i], dbus.ObjectPath(service[i:])
+	}
+	return service, sniItemPath
+}
+
+// add starts showing the item registered as service
+func (h *trayHost) add(service string) {
+	bus, path := splitService(service)
+	key := bus + string(path)
+	var owner string
+	if err := h.conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, bus).Store(&owner); err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	if _, ok := h.items[key]; ok {
+		h.mu.Unlock()
+		return
+	}
+	item := &trayItem{bus: bus, owner: owner, path: path}
+	item.button = widget.NewButton("", func() { h.click(item, desktop.MouseButtonPrimary) })
+	item.button.Importance = widget.LowImportance
+	item.image = canvas.NewImageFromResource(nil)
+	item.image.FillMode = canvas.ImageFillContain
+	item.image.SetMinSize(fyne.NewSize(float32(h.iconSize), float32(h.iconSize)))
+	icon := newClickArea(container.NewCenter(item.image), func(b desktop.MouseButton) { h.click(item, b) })
+	icon.Hide()
+	// The button handles primary clicks itself
+	text := newClickArea(item.button, func(b desktop.MouseButton) {
+		if b != desktop.MouseButtonPrimary {
+			h.click(item, b)
+		}
+	})
+	item.view = container.NewHBox(icon, text)
+	h.items[key] = item
+	h.mu.Unlock()
+
+	h.refresh(item)
+	h.box.Add(item.view)
+	h.box.Show()
+	h.changed(key, true)
+}
+
+// remove drops the item registered as service
+func (h *trayHost) remove(service string) {
+	bus, path := splitService(service)
+	key := bus + string(path)
+	h.mu.Lock()
+	item, ok := h.items[key]
+	delete(h.items, key)
+	h.mu.Unlock()
+	if ok {
+		h.box.Remove(item.view)
+		if len(h.box.Objects) == 0 {
+			h.box.Hide()
+		}
+		h.changed(key, false)
+	}
+}
+
+// removeOwner drops every item whose application left the bus
+func (h *trayHost) removeOwner(name string) {
+	h.mu.Lock()
+	var gone []string
+	for key, item := range h.items {
+		if item.owner == name || item.bus == name {
+			gone = append(gone, key)
+		}
+	}
+	h.mu.Unlock()
+	for _, key := range gone {
+		h.remove(key)
+	}
+}
+
+// changed updates the watcher's item list and signals, when gobar is the watcher
+func (h *trayHost) changed(key string, registered bool) {
+	if h.props == nil {
+		return
+	}
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.items))
+	for k := range h.items {
+		keys = append(keys, k)
+	}
+	h.mu.Unlock()
+	sort.Strings(keys)
+	h.props.SetMust(sniWatcherName, "RegisteredStatusNotifierItems", keys)
+
+	signal := ".StatusNotifierItemUnregistered"
+	if registered {
+		signal = ".StatusNotifierItemRegistered"
+	}
+	_ = h.conn.Emit(sniWatcherPath, sniWatcherName+signal, key)
+}
+
+// sniPixmap is one IconPixmap entry: ARGB32 pixels in network byte order
+type sniPixmap struct {
+	Width, Height int32
+	Data          []byte
+}
+
+// refresh reloads the item's icon and title
+func (h *trayHost) refresh(item *trayItem) {
+	var props map[string]dbus.Variant
+	obj := h.conn.Object(item.bus, item.path)
+	if err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, sniItemIface).Store(&props); err != nil {
+		return
+	}
+	str := func(name string) string {
+		s, _ := props[name].Value().(string)
+		return s
+	}
+	title := str("Title")
+	if title == "" {
+		title = str("Id")
+	}
+	h.mu.Lock()
+	item.menu, _ = props["Menu"].Value().(dbus.ObjectPath)
+	item.itemIsMenu, _ = props["ItemIsMenu"].Value().(bool)
+	h.mu.Unlock()
+
+	var icon fyne.Resource
+	var pixmaps []sniPixmap
+	if v, ok := props["IconPixmap"]; ok && v.Store(&pixmaps) == nil {
+		icon = pixmapResource(item.bus, pixmaps, h.iconSize)
+	}
+	if icon == nil {
+		icon = themeIcon(str("IconName"), str("IconThemePath"))
+	}
+
+	iconArea, textArea := item.view.Objects[0], item.view.Objects[1]
+	if icon != nil {
+		item.image.Resource = icon
+		item.image.Refresh()
+		iconArea.Show()
+		textArea.Hide()
+	} else {
+		item.button.SetText(title)
+		textArea.Show()
+		iconArea.Hide()
+	}
+}
+
+// click handles a click on the item: the primary button activates it, or
+// opens its menu for ItemIsMenu items, the middle button is a secondary
+// activation and the secondary button opens the menu. Items without a
+// dbusmenu draw their own through ContextMenu.
+func (h *trayHost) click(item *trayItem, button desktop.MouseButton) {
+	h.mu.Lock()
+	menu, isMenu := item.menu, item.itemIsMenu
+	h.mu.Unlock()
+	method := "Activate"
+	switch {
+	case button == desktop.MouseButtonTertiary:
+		method = "SecondaryActivate"
+	case button == desktop.MouseButtonSecondary || isMenu:
+		if menu != "" {
+			h.menu.Show(h.conn, item.owner, menu)
+			return
+		}
+		method = "ContextMenu"
+	}
+	h.menu.Hide()
+	x, y := pointerPosition()
+	obj := h.conn.Object(item.bus, item.path)
+	if call := obj.Call(sniItemIface+"."+method, 0, x, y); call.Err != nil {
+		log.Printf("Tray item does not support %s: %v", method, call.Err)
+	}
+}
+
+// pixmapResource converts the pixmap closest to size to PNG, scaled to size
+func pixmapResource(name string, pixmaps []sniPixmap, size int) fyne.Resource {
+	var best *sniPixmap
+	for i := range pixmaps {
+		p := &pixmaps[i]
+		// As int, so a huge size from the item can't overflow the check
+		if p.Width <= 0 || p.Height <= 0 || int(p.Width)*int(p.Height) > len(p.Data)/4 {
+			continue
+		}
+		if best == nil || (int(best.Width) < size && p.Width > best.Width) ||
+			(int(p.Width) >= size && p.Width < best.Width) {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, int(best.Width), int(best.Height)))
+	for i := 0; i < int(best.Width)*int(best.Height); i++ {
+		a, r, g, b := best.Data[i*4], best.Data[i*4+1], best.Data[i*4+2], best.Data[i*4+3]
+		img.SetNRGBA(i%int(best.Width), i/int(best.Width), color.NRGBA{R: r, G: g, B: b, A: a})
+	}
+	return imageResource(name, img, size)
+}
+
+// themeIcon looks up an IconName in the item's theme path, then through
+// themeIcons in the icon theme
+func themeIcon(name, themePath string) fyne.Resource {
+	if name == "" {
+		return nil
+	}
+	var candidates []string
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	}
+	if themePath != "" {
+		candidates = append(candidates, filepath.Join(themePath, name+".png"), filepath.Join(themePath, name+".svg"))
+	}
+	if !filepath.IsAbs(name) {
+		if path := themeIcons.find(name); path != "" {
+			candidates = append(candidates, path)
+		}
+	}
+	for _, path := range candidates {
+		if res, err := fyne.LoadResourceFromPath(path); err == nil {
+			return res
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Sparkline sizes: the default sample count and the width of each sample,
+// in pixels
+const (
+	defaultGraphHistory = 30
+	sparklineStep       = 2
+)
+
+// sparkline draws the last samples of a stat as a line, or a filled area,
+// rising from the bottom, newest at the right
+type sparkline struct {
+	widget.BaseWidget
+	height  float32
+	history int
+	area    bool
+	scale   float64                   // value of a full-height sample; 0 scales to the largest sample
+	fixed   color.Color               // configured colour, or nil
+	colors  func(float64) color.Color // threshold colour of the newest sample, or nil
+
+	mu      sync.Mutex
+	samples []float64
+}
+
+// newSparkline creates a graph styled by g, as tall as icons of iconSize
+// pixels; colors, which may be nil, picks the colour from the newest sample
+// unless g sets one
+func newSparkline(g Graph, iconSize int, scale float64, colors func(float64) color.Color) *sparkline {
+	s := &sparkline{height: float32(iconSize), history: g.History, area: g.Fill == "area", scale: scale, colors: colors}
+	if s.history <= 0 {
+		s.history = defaultGraphHistory
+	}
+	if c, err := parseHexColor(g.Color); err == nil && g.Color != "" {
+		s.fixed = c
+	}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// Push adds a sample, dropping the oldest beyond the history length
+func (s *sparkline) Push(v float64) {
+	s.mu.Lock()
+	s.samples = append(s.samples, v)
+	if len(s.samples) > s.history {
+		s.samples = s.samples[len(s.samples)-s.history:]
+	}
+	s.mu.Unlock()
+	s.Refresh()
+}
+
+// statGraph places the sparkline configured for the stats widget name after
+// its label, hiding the label when the graph replaces the text. It returns
+// label unchanged and a nil graph when name has no graph.
+func statGraph(cfg Config, name string, label fyne.CanvasObject, iconSize int, scale float64,
+	colors func(float64) color.Color) (fyne.CanvasObject, *sparkline) {
+	g, ok := cfg.Graphs[name]
+	if !ok {
+		return label, nil
+	}
+	s := newSparkline(g, iconSize, scale, colors)
+	if g.HideText {
+		label.Hide()
+	}
+	return container.NewHBox(label, container.NewCenter(s)), s
+}
+
+// CreateRenderer builds the track; segments are added as samples arrive
+func (s *sparkline) CreateRenderer() fyne.WidgetRenderer {
+	r := &sparklineRenderer{s: s, track: canvas.NewRectangle(color.Transparent)}
+	r.Refresh()
+	return r
+}
+
+// sparklineRenderer keeps a track and, per sample, a filled column or the
+// line segment ending at it. Refresh takes the samples it draws, so a Push
+// in between can't leave Layout with more samples than objects.
+type sparklineRenderer struct {
+	s       *sparkline
+	samples []float64
+	track   *canvas.Rectangle
+	columns []*canvas.Rectangle
+	lines   []*canvas.Line
+	objects []fyne.CanvasObject
+}
+
+// Layout places the samples from the right edge, centred vertically
+func (r *sparklineRenderer) Layout(size fyne.Size) {
+	samples, top := r.samples, (size.Height-r.s.height)/2
+	width := float32(r.s.history * sparklineStep)
+	r.track.Move(fyne.NewPos(0, top))
+	r.track.Resize(fyne.NewSize(width, r.s.height))
+
+	scale := r.s.scale
+	if scale <= 0 {
+		for _, v := range samples {
+			scale = max(scale, v)
+		}
+	}
+	y := func(v float64) float32 {
+		if scale <= 0 {
+			return top + r.s.height
+		}
+		return top + r.s.height*(1-float32(min(max(v/scale, 0), 1)))
+	}
+	left := width - float32(len(samples)*sparklineStep)
+	for i, v := range samples {
+		x := left + float32(i*sparklineStep)
+		if r.s.area {
+			r.columns[i].Move(fyne.NewPos(x, y(v)))
+			r.columns[i].Resize(fyne.NewSize(sparklineStep, top+r.s.height-y(v)))
+		} else if i > 0 {
+			r.lines[i-1].Position1 = fyne.NewPos(x-sparklineStep, y(samples[i-1]))
+			r.lines[i-1].Position2 = fyne.NewPos(x, y(v))
+		}
+	}
+}
+
+// MinSize fits the whole history at the icon height
+func (r *sparklineRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(float32(r.s.history*sparklineStep), r.s.height)
+}
+
+// Refresh takes the current samples, adding objects for new ones, and
+// recolours and lays them out
+func (r *sparklineRenderer) Refresh() {
+	r.s.mu.Lock()
+	samples := append([]float64(nil), r.s.samples...)
+	r.s.mu.Unlock()
+	// Columns or segments are only added while the history fills up
+	if r.s.area {
+		for len(r.columns) < len(samples) {
+			r.columns = append(r.columns, canvas.NewRectangle(color.Transparent))
+		}
+	} else {
+		for len(r.lines) < len(samples)-1 {
+			line := canvas.NewLine(color.Transparent)
+			line.StrokeWidth = 1
+			r.lines = append(r.lines, line)
+		}
+	}
+	// Only now, with an object for each, can Layout draw them
+	r.samples = samples
+	r.objects = append(r.objects[:0], r.track)
+	c := r.s.fixed
+	if c == nil && r.s.colors != nil && len(samples) > 0 {
+		c = r.s.colors(samples[len(samples)-1])
+	}
+	if c == nil {
+		c = theme.Color(theme.ColorNameForeground)
+	}
+	r.track.FillColor = theme.Color(theme.ColorNameInputBackground)
+	for _, column := range r.columns {
+		column.FillColor = c
+		r.objects = append(r.objects, column)
+	}
+	for _, line := range r.lines {
+		line.StrokeColor = c
+		r.objects = append(r.objects, line)
+	}
+	r.Layout(r.s.Size())
+	for _, o := range r.objects {
+		o.Refresh()
+	}
+}
+
+// Objects returns the track and the sample objects
+func (r *sparklineRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+// Destroy has nothing to release
+func (r *sparklineRenderer) Destroy() {}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// startMenu is the launcher window opened by the Start Menu button. The
+// window is reused between opens, keeping the search text, and its size and
+// position are remembered in the cache.
+type startMenu struct {
+	app         fyne.App
+	index       *appIndex
+	win         fyne.Window
+	search      *widget.Entry
+	list        *widget.List
+	all         []DesktopEntry           // every scanned entry, sorted
+	apps        []DesktopEntry           // the entries matching the search, shown
+	icons       map[string]fyne.Resource // by Icon=, nil for icons not found
+	defaultSize fyne.Size
+	sortMode    string        // "alpha", "recent" or "frequency"
+	xid         atomic.Uint32 // the X11 window once created
+
+	// onPin is called when an entry's Pin button is pressed
+	onPin func(DesktopEntry)
+	// onLaunch is called when an entry is clicked
+	onLaunch func(DesktopEntry)
+}
+
+// newStartMenu prepares a Start Menu of the applications in index, opening
+// at defaultSize until resized and listing entries in sortMode order
+func newStartMenu(a fyne.App, index *appIndex, defaultSize fyne.Size, sortMode string) *startMenu {
+	return &startMenu{app: a, index: index, icons: map[string]fyne.Resource{}, defaultSize: defaultSize, sortMode: sortMode}
+}
+
+// Show lists the indexed applications and opens the menu at its remembered
+// size and position
+func (m *startMenu) Show() {
+	apps, err := m.index.Entries()
+	if m.win == nil {
+		m.build()
+	}
+	if err != nil {
+		dialog.ShowError(err, m.win)
+	}
+	state := loadCache()
+	sortApps(apps, m.sortMode, state.AppLaunches)
+	m.all = apps
+	m.filter(m.search.Text)
+
+	size := m.defaultSize
+	if state.StartMenuWidth > 0 && state.StartMenuHeight > 0 {
+		size = fyne.NewSize(state.StartMenuWidth, state.StartMenuHeight)
+	}
+	m.win.Resize(size)
+	m.win.Show()
+	m.win.RequestFocus()
+	m.win.Canvas().Focus(m.search)
+	go m.place(state.StartMenuX, state.StartMenuY)
+}
+
+// place moves the shown window to x, y once Fyne has created it, leaving
+// it where the window manager put it without a saved position
+func (m *startMenu) place(x, y *int) {
+	id, ok := x11WindowID(m.win, 5*time.Second)
+	if !ok {
+		return
+	}
+	m.xid.Store(id)
+	X, err := barConn()
+	if x == nil || y == nil || err != nil {
+		return
+	}
+	xproto.ConfigureWindow(X, xproto.Window(id), xproto.ConfigWindowX|xproto.ConfigWindowY,
+		[]uint32{uint32(int32(*x)), uint32(int32(*y))})
+}
+
+// position returns where the window is on the screen, if it can be read
+func (m *startMenu) position() (x, y int, ok bool) {
+	id := m.xid.Load()
+	if id == 0 {
+		return 0, 0, false
+	}
+	X, err := barConn()
+	if err != nil {
+		return 0, 0, false
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	reply, err := xproto.TranslateCoordinates(X, xproto.Window(id), root, 0, 0).Reply()
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(reply.DstX), int(reply.DstY), true
+}
+
+// build creates the window and list on first use
+func (m *startMenu) build() {
+	m.win = m.app.NewWindow("Installed Applications")
+	m.list = widget.NewList(
+		func() int { return len(m.apps) },
+		func() fyne.CanvasObject {
+			pin := widget.NewButton("Pin", nil)
+			pin.Importance = widget.LowImportance
+			icon := widget.NewIcon(nil)
+			return container.NewBorder(nil, nil, icon, pin, widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			row := o.(*fyne.Container)
+			entry := m.apps[i]
+			row.Objects[0].(*widget.Label).SetText(entry.Name)
+			row.Objects[1].(*widget.Icon).SetResource(m.icon(entry.Icon))
+			row.Objects[2].(*widget.Button).OnTapped = func() {
+				if m.onPin != nil {
+					m.onPin(entry)
+				}
+			}
+		},
+	)
+	m.list.OnSelected = func(i widget.ListItemID) {
+		m.list.Unselect(i)
+		if m.onLaunch != nil && i < len(m.apps) {
+			recordLaunch(m.apps[i].Name)
+			m.onLaunch(m.apps[i])
+			m.hide()
+		}
+	}
+	m.search = widget.NewEntry()
+	m.search.SetPlaceHolder("Search")
+	m.search.OnChanged = m.filter
+	m.win.SetContent(container.NewBorder(m.search, nil, nil, nil, container.NewVScroll(m.list)))
+	// Hide instead of closing so the window can be reopened, saving its size
+	m.win.SetCloseIntercept(m.hide)
+}
+
+// filter shows the entries matching query (see DesktopEntry.match), best
+// matches first and otherwise in the menu's sort order; an empty query shows
+// them all
+func (m *startMenu) filter(query string) {
+	type scored struct {
+		entry DesktopEntry
+		score int
+	}
+	var matches []scored
+	for _, e := range m.all {
+		if score, ok := e.match(query); ok {
+			matches = append(matches, scored{e, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	m.apps = make([]DesktopEntry, len(matches))
+	for i, match := range matches {
+		m.apps[i] = match.entry
+	}
+	m.list.UnselectAll()
+	m.list.Refresh()
+}
+
+// icon resolves an entry's Icon= from the icon theme, remembering the result
+// since list rows are rebound on every scroll
+func (m *startMenu) icon(name string) fyne.Resource {
+	if res, ok := m.icons[name]; ok {
+		return res
+	}
+	res := themeIcon(name, "")
+	m.icons[name] = res
+	return res
+}
+
+// hide remembers the current window size and position and hides the menu
+func (m *startMenu) hide() {
+	size := m.win.Canvas().Size()
+	state := loadCache()
+	state.StartMenuWidth, state.StartMenuHeight = size.Width, size.Height
+	if x, y, ok := m.position(); ok {
+		state.StartMenuX, state.StartMenuY = &x, &y
+	}
+	if err := saveCache(state); err != nil {
+		log.Println("Failed to save Start Menu size:", err)
+	}
+	m.win.Hide()
+}
+
+// sortApps orders the entries by name, by most recent launch or by launch
+// count. Entries never launched, and ties, fall back to name order.
+func sortApps(apps []DesktopEntry, mode string, launches map[string]appLaunches) {
+	sort.SliceStable(apps, func(i, j int) bool {
+		a, b := launches[apps[i].Name], launches[apps[j].Name]
+		switch {
+		case mode == "recent" && !a.Last.Equal(b.Last):
+			return a.Last.After(b.Last)
+		case mode == "frequency" && a.Count != b.Count:
+			return a.Count > b.Count
+		}
+		return strings.ToLower(apps[i].Name) < strings.ToLower(apps[j].Name)
+	})
+}
+
+// recordLaunch counts a Start Menu launch of the named entry in the cache
+func recordLaunch(name string) {
+	state := loadCache()
+	if state.AppLaunches == nil {
+		state.AppLaunches = map[string]appLaunches{}
+	}
+	l := state.AppLaunches[name]
+	l.Count++
+	l.Last = time.Now()
+	state.AppLaunches[name] = l
+	if err := saveCache(state); err != nil {
+		log.Println("Failed to save Start Menu launch history:", err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// taskbarWidget shows a button per open window from _NET_CLIENT_LIST, with
+// the window's icon, and activates the window on click or minimizes it on a
+// middle click. It watches the shared X connection for PropertyNotify so it
+// only updates when the client list, the active window, a title or an icon
+// changes.
+type taskbarWidget struct {
+	X        *xgb.Conn
+	events   *xWatch
+	root     xproto.Window
+	box      *fyne.Container
+	maxLen   int
+	iconSize int
+
+	clientList, activeWindow, netWMName, netWMIcon xproto.Atom
+
+	mu      sync.Mutex
+	windows []xproto.Window
+	buttons []*taskButton
+	watched map[xproto.Window]bool
+	icons   map[xproto.Window]fyne.Resource // nil for windows without one
+}
+
+// newTaskbarWidget watches X and subscribes to root property changes;
+// button titles are clamped to maxLen characters and icons scaled to
+// iconSize pixels
+func newTaskbarWidget(maxLen, iconSize int) (*taskbarWidget, error) {
+	events, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	X := events.X
+	t := &taskbarWidget{
+		X:        X,
+		events:   events,
+		root:     xproto.Setup(X).DefaultScreen(X).Root,
+		box:      container.NewHBox(),
+		watched:  map[xproto.Window]bool{},
+		icons:    map[xproto.Window]fyne.Resource{},
+		maxLen:   maxLen,
+		iconSize: iconSize,
+	}
+	for name, atom := range map[string]*xproto.Atom{
+		"_NET_CLIENT_LIST":   &t.clientList,
+		"_NET_ACTIVE_WINDOW": &t.activeWindow,
+		"_NET_WM_NAME":       &t.netWMName,
+		"_NET_WM_ICON":       &t.netWMIcon,
+	} {
+		if *atom, err = internAtom(X, name); err != nil {
+			events.Close()
+			return nil, err
+		}
+	}
+	if err := events.Select(t.root, xproto.EventMaskPropertyChange); err != nil {
+		events.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// CanvasObject returns the object to place in the bar
+func (t *taskbarWidget) CanvasObject() fyne.CanvasObject {
+	return t.box
+}
+
+// Run loads the window list and then updates it from X events until Close
+// or the connection closes
+func (t *taskbarWidget) Run() {
+	t.refresh()
+	for {
+		ev, ok := t.events.Next()
+		if !ok {
+			return
+		}
+		if pn, ok := ev.(xproto.PropertyNotifyEvent); ok {
+			switch pn.Atom {
+			case t.clientList, t.activeWindow, t.netWMName, xproto.AtomWmName:
+				t.refresh()
+			case t.netWMIcon:
+				delete(t.icons, pn.Window)
+				t.refresh()
+			}
+		}
+	}
+}
+
+// Close stops Run
+func (t *taskbarWidget) Close() {
+	t.events.Close()
+}
+
+// refresh rebuilds the button labels from the current client list
+func (t *taskbarWidget) refresh() {
+	clients, err := getProperty32(t.X, t.root, "_NET_CLIENT_LIST")
+	if err != nil {
+		return
+	}
+	active := xproto.Window(0)
+	if values, err := getProperty32(t.X, t.root, "_NET_ACTIVE_WINDOW"); err == nil && len(values) > 0 {
+		active = xproto.Window(values[0])
+	}
+
+	var windows []xproto.Window
+	var titles []string
+	var icons []fyne.Resource
+	for _, id := range clients {
+		win := xproto.Window(id)
+		if hasAtom(t.X, win, "_NET_WM_WINDOW_TYPE", "_NET_WM_WINDOW_TYPE_DOCK", "_NET_WM_WINDOW_TYPE_DESKTOP") ||
+			hasAtom(t.X, win, "_NET_WM_STATE", "_NET_WM_STATE_SKIP_TASKBAR") {
+			continue
+		}
+		t.watch(win)
+		windows = append(windows, win)
+		titles = append(titles, clampText(windowTitle(t.X, win), t.maxLen))
+		icons = append(icons, t.icon(win))
+	}
+	for win := range t.icons {
+		if !slices.Contains(windows, win) {
+			delete(t.icons, win)
+		}
+	}
+	for win := range t.watched {
+		if !slices.Contains(windows, win) {
+			delete(t.watched, win)
+			t.events.Select(win, 0)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.windows = windows
+	for len(t.buttons) < len(windows) {
+		i := len(t.buttons)
+		b := newTaskButton(func() { t.activate(i) }, func() { t.minimize(i) })
+		t.buttons = append(t.buttons, b)
+		t.box.Add(b)
+	}
+	for len(t.buttons) > len(windows) {
+		t.box.Remove(t.buttons[len(t.buttons)-1])
+		t.buttons = t.buttons[:len(t.buttons)-1]
+	}
+	for i, win := range windows {
+		b := t.buttons[i]
+		importance := widget.LowImportance
+		if win == active {
+			importance = widget.HighImportance
+		}
+		if b.Text != titles[i] || b.Icon != icons[i] || b.Importance != importance {
+			b.Text, b.Icon, b.Importance = titles[i], icons[i], importance
+			b.Refresh()
+		}
+	}
+}
+
+// watch subscribes to property changes on a client so title updates arrive
+func (t *taskbarWidget) watch(win xproto.Window) {
+	if t.watched[win] {
+		return
+	}
+	t.watched[win] = true
+	t.events.Select(win, xproto.EventMaskPropertyChange)
+}
+
+// icon returns the window's icon, decoding _NET_WM_ICON only when it was
+// not seen before or has changed
+func (t *taskbarWidget) icon(win xproto.Window) fyne.Resource {
+	if res, ok := t.icons[win]; ok {
+		return res
+	}
+	var res fyne.Resource
+	if img := windowIcon(t.X, win, t.iconSize); img != nil {
+		res = imageResource(fmt.Sprintf("window-%d", win), img, t.iconSize)
+	}
+	t.icons[win] = res
+	return res
+}
+
+// window returns the window behind button i, or 0 if the list shrank
+func (t *taskbarWidget) window(i int) xproto.Window {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i >= len(t.windows) {
+		return 0
+	}
+	return t.windows[i]
+}
+
+// activate focuses the window behind button i
+func (t *taskbarWidget) activate(i int) {
+	if win := t.window(i); win != 0 {
+		if err := activateWindow(t.X, win); err != nil {
+			log.Println("Failed to activate window:", err)
+		}
+	}
+}
+
+// minimize iconifies the window behind button i
+func (t *taskbarWidget) minimize(i int) {
+	if win := t.window(i); win != 0 {
+		if err := minimizeWindow(t.X, win); err != nil {
+			log.Println("Failed to minimize window:", err)
+		}
+	}
+}
+
+// taskButton is a window button that also reports middle clicks
+type taskButton struct {
+	widget.Button
+	onMiddle func()
+}
+
+// newTaskButton creates a button calling tapped on a click and middle on a
+// middle click
+func newTaskButton(tapped, middle func()) *taskButton {
+	b := &taskButton{onMiddle: middle}
+	b.OnTapped = tapped
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// MouseDown implements desktop.Mouseable
+func (b *taskButton) MouseDown(*desktop.MouseEvent) {}
+
+// MouseUp implements desktop.Mouseable, running onMiddle for the middle button
+func (b *taskButton) MouseUp(ev *desktop.MouseEvent) {
+	if ev.Button == desktop.MouseButtonTertiary && b.onMiddle != nil {
+		b.onMiddle()
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// tempInterval is how often the sensors are read
+const tempInterval = 2 * time.Second
+
+// tempWidget shows the hottest reading of each configured sensor group,
+// e.g. "Temp: 54°C 61°C" for the CPU and the GPU, coloured by the hottest
+// one. The tooltip lists the matching sensors.
+type tempWidget struct {
+	cfg     Config
+	label   *colorLabel
+	tooltip *tooltipArea
+	metrics *metricsRegistry
+}
+
+// init registers the temperature widget
+func init() {
+	registerWidget("temp", func(cfg Config) bool { return cfg.ShowTemp }, func(cfg Config) (Widget, error) {
+		return newTempWidget(cfg), nil
+	})
+}
+
+// newTempWidget creates the widget for cfg.TempSensors, hidden until read
+func newTempWidget(cfg Config) *tempWidget {
+	t := &tempWidget{cfg: cfg, label: newColorLabel("")}
+	t.tooltip = newTooltipArea(t.label)
+	t.tooltip.Hide()
+	return t
+}
+
+// Render returns the object to place in the bar
+func (t *tempWidget) Render() fyne.CanvasObject {
+	return t.tooltip
+}
+
+// Interval implements Widget
+func (t *tempWidget) Interval() time.Duration {
+	return tempInterval
+}
+
+// SetMetrics implements widgetExporter
+func (t *tempWidget) SetMetrics(r *metricsRegistry) {
+	t.metrics = r
+}
+
+// Update reads the sensors; the widget hides while none matches
+func (t *tempWidget) Update(ctx context.Context) {
+	// gopsutil returns the readings it got along with an error for the rest
+	sensors, _ := host.SensorsTemperaturesWithContext(ctx)
+	var parts, details []string
+	hottest := 0.0
+	for _, prefix := range t.cfg.TempSensors {
+		found := false
+		group := 0.0
+		for _, s := range sensors {
+			if strings.HasPrefix(s.SensorKey, prefix) && s.Temperature > 0 {
+				found = true
+				group = max(group, s.Temperature)
+				details = append(details, fmt.Sprintf("%s: %.0f°C", s.SensorKey, s.Temperature))
+			}
+		}
+		if found {
+			parts = append(parts, fmt.Sprintf("%.0f°C", group))
+			hottest = max(hottest, group)
+		}
+	}
+	if len(parts) == 0 {
+		t.tooltip.Hide()
+		return
+	}
+	t.metrics.set("gobar_temperature_celsius", "gauge", "Hottest reading of the configured sensors.", hottest)
+	t.label.SetText(t.cfg.prefix("temp") + strings.Join(parts, " "))
+	t.label.SetColor(t.cfg.colorFor("temp", hottest))
+	t.tooltip.SetTooltip(strings.Join(details, "\n"))
+	t.tooltip.Show()
+}
+
+// OnClick runs the temperature click command, if any
+func (t *tempWidget) OnClick(button desktop.MouseButton) {
+	if button == desktop.MouseButtonPrimary {
+		launchCommand(t.cfg.ClickCommands["temp"])
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+)
+
+// barTheme wraps the default theme, replacing the text font, size and
+// colours when they are configured. The pywal colours can change while the
+// bar runs, so they are guarded by mu.
+type barTheme struct {
+	fyne.Theme
+	font     fyne.Resource
+	textSize float32                             // 0 keeps the default
+	fixed    map[fyne.ThemeColorName]color.Color // from the config, over pywal
+
+	mu      sync.RWMutex
+	colors  map[fyne.ThemeColorName]color.Color
+	walData []byte // the colors.json last loaded
+}
+
+// newBarTheme builds the bar theme on top of Fyne's default
+func newBarTheme() *barTheme {
+	return &barTheme{Theme: theme.DefaultTheme(), fixed: map[fyne.ThemeColorName]color.Color{}}
+}
+
+// Font returns the custom font for regular text; monospace and symbol
+// text keep the default fonts
+func (t *barTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if t.font != nil && !style.Monospace && !style.Symbol {
+		return t.font
+	}
+	return t.Theme.Font(style)
+}
+
+// Size returns the configured text size, else the default sizes
+func (t *barTheme) Size(name fyne.ThemeSizeName) float32 {
+	if name == theme.SizeNameText && t.textSize > 0 {
+		return t.textSize
+	}
+	return t.Theme.Size(name)
+}
+
+// Color returns a configured colour, then one from pywal, else the default
+func (t *barTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if c, ok := t.fixed[name]; ok {
+		return c
+	}
+	t.mu.RLock()
+	c, ok := t.colors[name]
+	t.mu.RUnlock()
+	if ok {
+		return c
+	}
+	return t.Theme.Color(name, variant)
+}
+
+// walColors is the part of pywal's colors.json the bar uses
+type walColors struct {
+	Special struct {
+		Background string
+		Foreground string
+	}
+	Colors map[string]string
+}
+
+// walAccent is the pywal palette entry used as the accent colour
+const walAccent = "color1"
+
+// loadWalColors takes background, foreground and accent colours from a
+// pywal colors.json, reporting whether they changed since the last load
+func (t *barTheme) loadWalColors(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	t.mu.RLock()
+	same := bytes.Equal(data, t.walData)
+	t.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+	var wal walColors
+	if err := json.Unmarshal(data, &wal); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	colors := make(map[fyne.ThemeColorName]color.Color)
+	for name, hex := range map[fyne.ThemeColorName]string{
+		theme.ColorNameBackground: wal.Special.Background,
+		theme.ColorNameForeground: wal.Special.Foreground,
+		theme.ColorNamePrimary:    wal.Colors[walAccent],
+	} {
+		if hex == "" {
+			continue
+		}
+		c, err := parseHexColor(hex)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		colors[name] = c
+	}
+	t.mu.Lock()
+	t.colors, t.walData = colors, data
+	t.mu.Unlock()
+	return true, nil
+}
+
+// loadFont reads a TrueType/OpenType font to use for bar text
+func (t *barTheme) loadFont(path string) error {
+	res, err := fyne.LoadResourceFromPath(path)
+	if err != nil {
+		return err
+	}
+	if !isFontData(res.Content()) {
+		return fmt.Errorf("%s is not a TrueType or OpenType font", path)
+	}
+	t.font = res
+	return nil
+}
+
+// isFontData checks the sfnt magic number of TTF, OTF and TTC files
+func isFontData(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	magic := data[:4]
+	return bytes.Equal(magic, []byte{0, 1, 0, 0}) || bytes.Equal(magic, []byte("OTTO")) ||
+		bytes.Equal(magic, []byte("true")) || bytes.Equal(magic, []byte("ttcf"))
+}
+
+// tintTheme is the bar theme with one widget's text colour
+type tintTheme struct {
+	fyne.Theme
+	foreground color.Color
+}
+
+// Color returns the widget's text colour for the foreground
+func (t tintTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if name == theme.ColorNameForeground {
+		return t.foreground
+	}
+	return t.Theme.Color(name, variant)
+}
+
+// tintWidget applies a widget's configured colours to obj: its text colour
+// through a theme override and its background as a rectangle behind it.
+// validate ensures the colours parse.
+func tintWidget(obj fyne.CanvasObject, colors WidgetColors) fyne.CanvasObject {
+	if colors.Foreground != "" {
+		fg, _ := parseHexColor(colors.Foreground)
+		obj = container.NewThemeOverride(obj, tintTheme{Theme: fyne.CurrentApp().Settings().Theme(), foreground: fg})
+	}
+	if colors.Background != "" {
+		bg, _ := parseHexColor(colors.Background)
+		obj = container.NewStack(canvas.NewRectangle(bg), obj)
+	}
+	return obj
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// tooltips shows hover text for bar widgets
+var tooltips tooltipManager
+
+// tooltipManager owns the single tooltip window. The bar is too short for
+// Fyne's in-canvas popups, so tooltips use a borderless window that is made
+// override-redirect (unmanaged by Qtile) and mapped next to the pointer.
+type tooltipManager struct {
+	mu    sync.Mutex
+	win   fyne.Window
+	label *widget.Label
+	X     *xgb.Conn
+	xid   xproto.Window
+	owner any
+}
+
+// Show displays text for owner next to the pointer
+func (t *tooltipManager) Show(owner any, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.win == nil && !t.create() {
+		return
+	}
+	t.owner = owner
+	t.setText(text)
+	if t.xid != 0 {
+		t.place()
+	}
+}
+
+// Update replaces the text if owner's tooltip is currently shown
+func (t *tooltipManager) Update(owner any, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.owner == owner && t.win != nil {
+		t.setText(text)
+	}
+}
+
+// Hide hides the tooltip if owner is the one showing it
+func (t *tooltipManager) Hide(owner any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.owner != owner {
+		return
+	}
+	t.owner = nil
+	if t.xid != 0 {
+		xproto.UnmapWindow(t.X, t.xid)
+		t.X.Sync()
+	}
+}
+
+// create builds the tooltip window; the X11 setup finishes asynchronously
+// once Fyne has created the native window. Callers hold mu.
+func (t *tooltipManager) create() bool {
+	drv, ok := fyne.CurrentApp().Driver().(desktop.Driver)
+	if !ok {
+		return false
+	}
+	X, err := barConn()
+	if err != nil {
+		return false
+	}
+	t.X = X
+	t.win = drv.CreateSplashWindow()
+	t.label = widget.NewLabel("")
+	t.win.SetContent(t.label)
+	t.win.Show()
+	go func() {
+		// Not under mu: RunNative waits for the main thread, which may be
+		// blocked in Show waiting for the lock
+		id, ok := x11WindowID(t.win, 5*time.Second)
+		if !ok {
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		win := xproto.Window(id)
+		// Unmap, switch to override-redirect so the WM leaves it alone, and
+		// only map it again when placed
+		xproto.UnmapWindow(t.X, win)
+		xproto.ChangeWindowAttributes(t.X, win, xproto.CwOverrideRedirect, []uint32{1})
+		t.xid = win
+		if t.owner != nil {
+			t.place()
+		}
+	}()
+	return true
+}
+
+// setText updates the label and fits the window to it; callers hold mu
+func (t *tooltipManager) setText(text string) {
+	t.label.SetText(text)
+	t.win.Resize(t.label.MinSize())
+}
+
+// place moves the tooltip beside the pointer, keeping it on screen, and maps
+// it; callers hold mu
+func (t *tooltipManager) place() {
+	screen := xproto.Setup(t.X).DefaultScreen(t.X)
+	pointer, err := xproto.QueryPointer(t.X, screen.Root).Reply()
+	if err != nil {
+		return
+	}
+	scale := t.win.Canvas().Scale()
+	size := t.label.MinSize()
+	w, h := int(size.Width*scale), int(size.Height*scale)
+
+	x, y := int(pointer.RootX)+12, int(pointer.RootY)+20
+	if x+w > int(screen.WidthInPixels) {
+		x = int(screen.WidthInPixels) - w
+	}
+	if x < 0 {
+		x = 0
+	}
+	if y+h > int(screen.HeightInPixels) {
+		// Bottom bar: show above the pointer instead
+		y = int(pointer.RootY) - h - 10
+	}
+	xproto.ConfigureWindow(t.X, t.xid, xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(int32(x)), uint32(int32(y)), xproto.StackModeAbove})
+	xproto.MapWindow(t.X, t.xid)
+	t.X.Sync()
+}
+
+// tooltipArea wraps a non-interactive widget and shows a tooltip while the
+// pointer is over it
+type tooltipArea struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+
+	mu   sync.Mutex
+	text string
+}
+
+// newTooltipArea wraps content; set the text with SetTooltip
+func newTooltipArea(content fyne.CanvasObject) *tooltipArea {
+	a := &tooltipArea{content: content}
+	a.ExtendBaseWidget(a)
+	return a
+}
+
+// SetTooltip changes the hover text, updating it live if shown
+func (a *tooltipArea) SetTooltip(text string) {
+	a.mu.Lock()
+	changed := a.text != text
+	a.text = text
+	a.mu.Unlock()
+	if changed {
+		tooltips.Update(a, text)
+	}
+}
+
+// MouseIn implements desktop.Hoverable
+func (a *tooltipArea) MouseIn(*desktop.MouseEvent) {
+	a.mu.Lock()
+	text := a.text
+	a.mu.Unlock()
+	if text != "" {
+		tooltips.Show(a, text)
+	}
+}
+
+// MouseMoved implements desktop.Hoverable
+func (a *tooltipArea) MouseMoved(*desktop.MouseEvent) {}
+
+// MouseOut implements desktop.Hoverable
+func (a *tooltipArea) MouseOut() {
+	tooltips.Hide(a)
+}
+
+// CreateRenderer draws the wrapped content unchanged
+func (a *tooltipArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.content)
+}
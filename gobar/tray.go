@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getlantern/systray"
+	"github.com/godbus/dbus/v5"
+)
+
+// trayReady is set once systray has initialised and accepts updates
+var trayReady atomic.Bool
+
+// tray owns the system tray menu so launchers can be added after startup
+var tray trayMenu
+
+// trayBlinkInterval is how often the icon alternates in blinking attention mode
+const trayBlinkInterval = 500 * time.Millisecond
+
+// trayMenu tracks the tray's Always on Top, Reload Config, Edit Config and Quit items; systray can only
+// append items, so adding a launcher hides the old ones and re-adds them at
+// the bottom. It also holds the icons used to signal attention.
+type trayMenu struct {
+	mu     sync.Mutex
+	footer []*systray.MenuItem
+	// Called by Reload Config and Edit Config; set once the app exists
+	onReload, onEdit func()
+	// Called by Always on Top with the new state, once the bar is docked;
+	// it reports whether the change was applied
+	onAlwaysOnTop func(bool) bool
+	alwaysOnTop   bool
+
+	iconMu        sync.Mutex
+	icon          []byte
+	attentionIcon []byte
+	attention     bool
+	stopBlink     chan struct{}
+
+	// Bar buttons mirroring the launchers when there is no tray host
+	fallback *fyne.Container
+
+	// The launcher items by name, submenu entries included; under mu
+	launchers map[string]*systray.MenuItem
+	// The fallback buttons, and the launchers turned off by tray-disable;
+	// under mu
+	buttons  []fallbackButton
+	disabled map[string]bool
+}
+
+// fallbackButton is the bar button of a launcher, shown while neither the
+// launcher nor a submenu holding it is disabled
+type fallbackButton struct {
+	button *widget.Button
+	path   []string // the names of its submenus and its own
+}
+
+// addLauncher appends a menu item that runs the launcher's command, or a
+// submenu of its Items
+func (t *trayMenu) addLauncher(l TrayLauncher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, old := range t.footer {
+		old.Hide()
+	}
+	t.setupLauncher(systray.AddMenuItem(l.Name, "Open "+l.Name), l)
+	t.addQuit()
+}
+
+// setupLauncher gives a launcher's item its icon and state and runs it on
+// click, adding its submenu entries below it; callers hold mu
+func (t *trayMenu) setupLauncher(item *systray.MenuItem, l TrayLauncher) {
+	if t.launchers == nil {
+		t.launchers = map[string]*systray.MenuItem{}
+	}
+	t.launchers[l.Name] = item
+	if l.Icon != "" {
+		if icon, err := os.ReadFile(expandPath(l.Icon)); err != nil {
+			log.Printf("No icon for tray launcher %s: %v", l.Name, err)
+		} else {
+			item.SetIcon(icon)
+		}
+	}
+	if l.Disabled {
+		item.Disable()
+	}
+	for _, sub := range l.Items {
+		t.setupLauncher(item.AddSubMenuItem(sub.Name, "Open "+sub.Name), sub)
+	}
+	if len(l.Items) > 0 {
+		return
+	}
+	go func() {
+		for range item.ClickedCh {
+			t.clearAttention()
+			focusOrLaunch(l)
+		}
+	}()
+}
+
+// setLauncherEnabled greys out or re-enables the named launcher's item, and
+// hides or shows its fallback buttons
+func (t *trayMenu) setLauncherEnabled(name string, enabled bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item, ok := t.launchers[name]
+	if !ok && !t.hasButton(name) {
+		return fmt.Errorf("no tray launcher named %q", name)
+	}
+	if ok && enabled {
+		item.Enable()
+	} else if ok {
+		item.Disable()
+	}
+	if t.disabled == nil {
+		t.disabled = map[string]bool{}
+	}
+	t.disabled[name] = !enabled
+	t.showButtons()
+	return nil
+}
+
+// addQuit appends the Always on Top, Reload Config, Edit Config and Quit
+// items; callers hold mu
+func (t *trayMenu) addQuit() {
+	top := systray.AddMenuItemCheckbox("Always on Top", "Keep the bar above other windows", t.alwaysOnTop)
+	reload := systray.AddMenuItem("Reload Config", "Apply changes to gobar.json")
+	edit := systray.AddMenuItem("Edit Config", "Open gobar.json in an editor")
+	quit := systray.AddMenuItem("Quit", "Exit")
+	t.footer = []*systray.MenuItem{top, reload, edit, quit}
+	go t.toggleAlwaysOnTop(top)
+	go t.dispatch(reload, func() func() { return t.onReload })
+	go t.dispatch(edit, func() func() { return t.onEdit })
+	go func() {
+		<-quit.ClickedCh
+		systray.Quit()
+	}()
+}
+
+// dispatch calls the handler returned by get, read under mu as it is set
+// after the tray starts, whenever item is clicked
+func (t *trayMenu) dispatch(item *systray.MenuItem, get func() func()) {
+	for range item.ClickedCh {
+		t.clearAttention()
+		t.mu.Lock()
+		handler := get()
+		t.mu.Unlock()
+		if handler != nil {
+			handler()
+		}
+	}
+}
+
+// toggleAlwaysOnTop flips the bar's stacking state whenever item is clicked,
+// keeping the checkmark in sync when the change is applied
+func (t *trayMenu) toggleAlwaysOnTop(item *systray.MenuItem) {
+	for range item.ClickedCh {
+		t.clearAttention()
+		t.mu.Lock()
+		handler, above := t.onAlwaysOnTop, !t.alwaysOnTop
+		t.mu.Unlock()
+		if handler == nil || !handler(above) {
+			continue
+		}
+		t.mu.Lock()
+		t.alwaysOnTop = above
+		t.mu.Unlock()
+		if above {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
+
+// setAttention swaps to the attention icon, alternating with the normal
+// icon when blink is set, until clearAttention is called
+func (t *trayMenu) setAttention(blink bool) {
+	t.iconMu.Lock()
+	defer t.iconMu.Unlock()
+	if len(t.attentionIcon) == 0 {
+		log.Println("Attention requested but no AttentionIconPath is configured")
+		return
+	}
+	if t.attention {
+		return
+	}
+	t.attention = true
+	systray.SetIcon(t.attentionIcon)
+	if !blink || len(t.icon) == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	t.stopBlink = stop
+	go func() {
+		ticker := time.NewTicker(trayBlinkInterval)
+		defer ticker.Stop()
+		on := true
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				on = !on
+				if on {
+					systray.SetIcon(t.attentionIcon)
+				} else {
+					systray.SetIcon(t.icon)
+				}
+			}
+		}
+	}()
+}
+
+// clearAttention stops any blinking and restores the normal icon
+func (t *trayMenu) clearAttention() {
+	t.iconMu.Lock()
+	defer t.iconMu.Unlock()
+	if !t.attention {
+		return
+	}
+	t.attention = false
+	if t.stopBlink != nil {
+		close(t.stopBlink)
+		t.stopBlink = nil
+	}
+	if len(t.icon) > 0 {
+		systray.SetIcon(t.icon)
+	}
+}
+
+// System tray startup function
+func onReady(cfg Config) {
+	// Load tray icon from a PNG file
+	iconData, err := os.ReadFile(expandPath(cfg.IconPath))
+	if err != nil {
+		log.Println("Failed to load system tray icon:", err)
+	} else {
+		systray.SetIcon(iconData)
+	}
+	tray.iconMu.Lock()
+	tray.icon = iconData
+	if cfg.AttentionIconPath != "" {
+		if tray.attentionIcon, err = os.ReadFile(expandPath(cfg.AttentionIconPath)); err != nil {
+			log.Println("Failed to load attention icon:", err)
+		}
+	}
+	tray.iconMu.Unlock()
+	tray.mu.Lock()
+	tray.alwaysOnTop = cfg.AlwaysOnTop
+	tray.mu.Unlock()
+
+	systray.SetTitle("System Tray")
+	systray.SetTooltip("Qtile Go Taskbar")
+
+	for _, l := range cfg.TrayLaunchers {
+		tray.addLauncher(l)
+	}
+	if len(cfg.TrayLaunchers) == 0 {
+		tray.mu.Lock()
+		tray.addQuit()
+		tray.mu.Unlock()
+	}
+	trayReady.Store(true)
+}
+
+// statusNotifierWatcherRunning reports whether a StatusNotifierWatcher owns
+// its name on the session bus; without one the tray icon has nowhere to show
+func statusNotifierWatcherRunning() (bool, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	var running bool
+	err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, "org.kde.StatusNotifierWatcher").Store(&running)
+	return running, err
+}
+
+// addLauncherButtons adds a fallback bar button per launcher, for each entry
+// of a submenu rather than the submenu itself. Launchers that are Disabled,
+// or in a Disabled submenu, get none, as their menu items can't be clicked.
+func (t *trayMenu) addLauncherButtons(l TrayLauncher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.addButtons(l, nil)
+	t.showButtons()
+}
+
+// addButtons adds the buttons of l, in the submenus path; callers hold mu
+func (t *trayMenu) addButtons(l TrayLauncher, path []string) {
+	if l.Disabled {
+		return
+	}
+	path = append(slices.Clip(path), l.Name)
+	if len(l.Items) == 0 {
+		b := widget.NewButton(l.Name, func() { focusOrLaunch(l) })
+		b.Importance = widget.LowImportance
+		t.buttons = append(t.buttons, fallbackButton{button: b, path: path})
+		t.fallback.Add(b)
+		return
+	}
+	for _, sub := range l.Items {
+		t.addButtons(sub, path)
+	}
+}
+
+// hasButton reports whether a fallback button is of the launcher or submenu
+// name; callers hold mu
+func (t *trayMenu) hasButton(name string) bool {
+	for _, b := range t.buttons {
+		if slices.Contains(b.path, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// showButtons hides the fallback buttons of the launchers turned off by
+// tray-disable and shows the others; callers hold mu
+func (t *trayMenu) showButtons() {
+	for _, b := range t.buttons {
+		if slices.ContainsFunc(b.path, func(name string) bool { return t.disabled[name] }) {
+			b.button.Hide()
+		} else {
+			b.button.Show()
+		}
+	}
+}
+
+// pinToTray adds an app to the tray and saves it to the cache file, to be
+// added to the config's launchers on the next start
+func pinToTray(cfg *Config, l TrayLauncher) {
+	if hasLauncher(cfg.TrayLaunchers, l.Name) {
+		return
+	}
+	cfg.TrayLaunchers = append(cfg.TrayLaunchers, l)
+	if trayReady.Load() {
+		tray.addLauncher(l)
+	}
+	if tray.fallback != nil {
+		tray.addLauncherButtons(l)
+	}
+	state := loadCache()
+	state.PinnedLaunchers = append(state.PinnedLaunchers, l)
+	if err := saveCache(state); err != nil {
+		log.Println("Failed to save pinned app:", err)
+	}
+}
+
+// hasLauncher reports whether launchers or their submenus have an entry
+// named name
+func hasLauncher(launchers []TrayLauncher, name string) bool {
+	for _, l := range launchers {
+		if l.Name == name || hasLauncher(l.Items, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pactlSink is the PulseAudio name of the default output device
+const pactlSink = "@DEFAULT_SINK@"
+
+// volumeRetry is the delay before restarting an exited "pactl subscribe"
+const volumeRetry = 5 * time.Second
+
+// volumeWidget shows the default output volume, e.g. "Vol: 40%". Scrolling
+// over it changes the volume by step percent and a click toggles mute. It
+// uses pactl, so it works with PulseAudio and with PipeWire's pipewire-pulse,
+// and follows "pactl subscribe" events instead of polling.
+type volumeWidget struct {
+	label  *widget.Label
+	area   *scrollArea
+	prefix string
+	step   int
+}
+
+// init registers the volume widget
+func init() {
+	registerWidget("volume", func(cfg Config) bool { return cfg.ShowVolume }, func(cfg Config) (Widget, error) {
+		if _, err := exec.LookPath("pactl"); err != nil {
+			return nil, err
+		}
+		return newVolumeWidget(cfg.prefix("volume"), cfg.VolumeStep), nil
+	})
+}
+
+// newVolumeWidget creates the widget, hidden until the volume is read
+func newVolumeWidget(prefix string, step int) *volumeWidget {
+	v := &volumeWidget{label: widget.NewLabel(""), prefix: prefix, step: step}
+	v.area = newScrollArea(v.label, v.scrolled)
+	v.area.Hide()
+	return v
+}
+
+// Render returns the object to place in the bar
+func (v *volumeWidget) Render() fyne.CanvasObject {
+	return v.area
+}
+
+// Interval is 0: Update follows the sound server's events itself
+func (v *volumeWidget) Interval() time.Duration {
+	return 0
+}
+
+// Update shows the volume and then refreshes it on every sink or server
+// change until ctx is done, restarting pactl if it exits
+func (v *volumeWidget) Update(ctx context.Context) {
+	for {
+		v.refresh()
+		err := v.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("pactl subscribe exited (%v), restarting in %v", err, volumeRetry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(volumeRetry):
+		}
+	}
+}
+
+// subscribe runs "pactl subscribe" and refreshes for each event that can
+// change the default sink's volume, e.g. "Event 'change' on sink #54"
+func (v *volumeWidget) subscribe(ctx context.Context) error {
+	cmd := pactl(ctx, "subscribe")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		// Server events include a change of the default sink
+		if line := scanner.Text(); strings.Contains(line, " on sink #") || strings.Contains(line, " on server") {
+			v.refresh()
+		}
+	}
+	return cmd.Wait()
+}
+
+// refresh reads the volume and mute state; the widget hides while pactl fails
+func (v *volumeWidget) refresh() {
+	volume, muted, err := readVolume()
+	if err != nil {
+		v.area.Hide()
+		return
+	}
+	if muted {
+		setLabelText(v.label, v.prefix+"muted")
+	} else {
+		setLabelText(v.label, fmt.Sprintf("%s%d%%", v.prefix, volume))
+	}
+	v.area.Show()
+}
+
+// OnClick toggles mute on a primary click
+func (v *volumeWidget) OnClick(button desktop.MouseButton) {
+	if button != desktop.MouseButtonPrimary {
+		return
+	}
+	if err := pactl(context.Background(), "set-sink-mute", pactlSink, "toggle").Run(); err != nil {
+		log.Println("Failed to toggle mute:", err)
+	}
+}
+
+// scrolled steps the volume, up for scrolling up; the change event repaints
+func (v *volumeWidget) scrolled(ev *fyne.ScrollEvent) {
+	var change string
+	switch {
+	case ev.Scrolled.DY > 0:
+		change = fmt.Sprintf("+%d%%", v.step)
+	case ev.Scrolled.DY < 0:
+		change = fmt.Sprintf("-%d%%", v.step)
+	default:
+		return
+	}
+	if err := pactl(context.Background(), "set-sink-volume", pactlSink, change).Run(); err != nil {
+		log.Println("Failed to change the volume:", err)
+	}
+}
+
+// pactl runs pactl in the C locale, whose output and events are parsed here
+// and would otherwise be translated, e.g. "Stumm: ja" for "Mute: yes"
+func pactl(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "pactl", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	return cmd
+}
+
+// readVolume returns the default sink's volume in percent and its mute state
+func readVolume() (int, bool, error) {
+	out, err := pactl(context.Background(), "get-sink-volume", pactlSink).Output()
+	if err != nil {
+		return 0, false, err
+	}
+	volume, err := parseVolume(string(out))
+	if err != nil {
+		return 0, false, err
+	}
+	out, err = pactl(context.Background(), "get-sink-mute", pactlSink).Output()
+	if err != nil {
+		return 0, false, err
+	}
+	return volume, strings.TrimSpace(string(out)) == "Mute: yes", nil
+}
+
+// parseVolume takes the first channel's percentage from pactl's output, e.g.
+// "Volume: front-left: 26214 /  40% / -23.88 dB,   front-right: ..."
+func parseVolume(out string) (int, error) {
+	for _, field := range strings.Fields(out) {
+		if percent, ok := strings.CutSuffix(field, "%"); ok {
+			return strconv.Atoi(percent)
+		}
+	}
+	return 0, errors.New("no volume in pactl output")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// vpnWidget names the active VPN; clicking runs VPNToggleCommand. With a
+// toggle command and no VPN up it stays as 🔓, so the command can connect.
+type vpnWidget struct {
+	cfg    Config
+	button *widget.Button
+}
+
+// init registers the VPN widget
+func init() {
+	registerWidget("vpn", func(cfg Config) bool { return cfg.ShowVPN }, func(cfg Config) (Widget, error) {
+		v := &vpnWidget{cfg: cfg, button: widget.NewButton("", func() { launchCommand(cfg.VPNToggleCommand) })}
+		v.button.Importance = widget.LowImportance
+		v.button.Hide()
+		return v, nil
+	})
+}
+
+// Render returns the object to place in the bar
+func (v *vpnWidget) Render() fyne.CanvasObject {
+	return v.button
+}
+
+// Interval checks once a second
+func (v *vpnWidget) Interval() time.Duration {
+	return time.Second
+}
+
+// Update shows the active VPN's name, or hides the widget
+func (v *vpnWidget) Update(context.Context) {
+	if name := activeVPN(v.cfg.VPNInterfaces, v.cfg.VPNUseNetworkManager); name != "" {
+		setButtonText(v.button, v.cfg.prefix("vpn")+name)
+		v.button.Show()
+	} else if v.cfg.VPNToggleCommand != "" {
+		setButtonText(v.button, "🔓")
+		v.button.Show()
+	} else {
+		v.button.Hide()
+	}
+}
+
+// activeVPN returns the name of an active VPN, or "" when none is up.
+// Interfaces matching one of the prefixes count as a VPN; when useNM is set
+// NetworkManager's active vpn/wireguard connections are checked as well.
+func activeVPN(prefixes []string, useNM bool) string {
+	ifaces, err := net.Interfaces()
+	if err == nil {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 {
+				continue
+			}
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(iface.Name, prefix) {
+					return iface.Name
+				}
+			}
+		}
+	}
+	if useNM {
+		return activeNMVPN()
+	}
+	return ""
+}
+
+// activeNMVPN asks nmcli for an active vpn or wireguard connection
+func activeNMVPN() string {
+	out, err := exec.Command("nmcli", "-t", "-f", "NAME,TYPE", "connection", "show", "--active").Output()
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// Terse output escapes colons inside names as "\:"
+		line := scanner.Text()
+		idx := strings.LastIndex(line, ":")
+		if idx < 0 {
+			continue
+		}
+		name, kind := strings.ReplaceAll(line[:idx], `\:`, ":"), line[idx+1:]
+		if kind == "vpn" || kind == "wireguard" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchBackstop is how often watched files are re-read anyway, since sysfs
+// only reports changes written through the file, not by the hardware
+const watchBackstop = time.Minute
+
+// Watch runs fn now, whenever inotify reports a write to one of paths, and
+// every watchBackstop, never running it twice at once. Most of /proc and many
+// sysfs attributes never emit events and some can't be watched at all; then
+// fn is polled every poll.
+func (s *scheduler) Watch(name string, paths []string, poll time.Duration, fn func()) {
+	w, err := fsnotify.NewWatcher()
+	if err == nil {
+		for _, path := range paths {
+			if err = w.Add(path); err != nil {
+				w.Close()
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Polling %s, cannot watch it: %v", name, err)
+		s.Every(name, poll, fn)
+		return
+	}
+
+	// The backstop and the watcher call fn from their own goroutines
+	var mu sync.Mutex
+	serial := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
+	s.Every(name, watchBackstop, serial)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer w.Close()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Has(fsnotify.Write) {
+					runTask(name+s.suffix, serial)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Watching %s: %v", name+s.suffix, err)
+			}
+		}
+	}()
+}
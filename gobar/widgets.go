@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"slices"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// colorLabel is a label whose text colour can be changed at runtime
+type colorLabel struct {
+	widget.BaseWidget
+	text  *canvas.Text
+	color color.Color // nil for the theme foreground
+}
+
+// newColorLabel creates a label drawn in the theme foreground colour
+func newColorLabel(text string) *colorLabel {
+	l := &colorLabel{text: canvas.NewText(text, theme.Color(theme.ColorNameForeground))}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// Refresh follows theme changes, including a widget's own text colour
+func (l *colorLabel) Refresh() {
+	if l.color == nil {
+		l.text.Color = theme.ColorForWidget(theme.ColorNameForeground, l)
+	}
+	l.BaseWidget.Refresh()
+}
+
+// SetText replaces the label text, refreshing only when it changed
+func (l *colorLabel) SetText(text string) {
+	if l.text.Text == text {
+		return
+	}
+	l.text.Text = text
+	l.text.Refresh()
+}
+
+// SetColor changes the text colour; nil restores the theme foreground
+func (l *colorLabel) SetColor(c color.Color) {
+	if c == l.color {
+		return
+	}
+	l.color = c
+	if c == nil {
+		c = theme.ColorForWidget(theme.ColorNameForeground, l)
+	}
+	l.text.Color = c
+	l.text.Refresh()
+}
+
+// setLabelText replaces a label's text only when it differs from what is
+// shown, since every SetText repaints even for the same text
+func setLabelText(l *widget.Label, text string) {
+	if l.Text != text {
+		l.SetText(text)
+	}
+}
+
+// setButtonText is setLabelText for buttons
+func setButtonText(b *widget.Button, text string) {
+	if b.Text != text {
+		b.SetText(text)
+	}
+}
+
+// CreateRenderer pads the text like a regular label
+func (l *colorLabel) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewPadded(l.text))
+}
+
+// viewSet is the views of a widget shown on every bar with AllOutputs. The
+// widget does its work once and paints the result on each view with show;
+// a view added later gets the latest paint.
+type viewSet[V comparable] struct {
+	mu    sync.Mutex
+	views []V
+	last  func(V)
+}
+
+// add paints v and keeps it painted until ctx, its bar's, is done
+func (s *viewSet[V]) add(ctx context.Context, v V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.views = append(s.views, v)
+	if s.last != nil {
+		s.last(v)
+	}
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.views = slices.DeleteFunc(s.views, func(w V) bool { return w == v })
+	})
+}
+
+// show calls paint on every view, and on those added later
+func (s *viewSet[V]) show(paint func(V)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = paint
+	for _, v := range s.views {
+		paint(v)
+	}
+}
+
+// colorForValue picks a colour for v against warn/crit thresholds. When
+// crit is below warn (e.g. battery charge) lower values are worse.
+func colorForValue(v, warn, crit float64) color.Color {
+	if crit < warn {
+		v, warn, crit = -v, -warn, -crit
+	}
+	switch {
+	case v >= crit:
+		return theme.Color(theme.ColorNameError)
+	case v >= warn:
+		return theme.Color(theme.ColorNameWarning)
+	default:
+		return theme.Color(theme.ColorNameForeground)
+	}
+}
+
+// scrollArea wraps content and reports mouse-wheel events over any part of
+// it, including over child widgets that don't handle scrolling themselves
+type scrollArea struct {
+	widget.BaseWidget
+	content  fyne.CanvasObject
+	onScroll func(*fyne.ScrollEvent)
+}
+
+// newScrollArea creates a scrollArea calling onScroll for each wheel event
+func newScrollArea(content fyne.CanvasObject, onScroll func(*fyne.ScrollEvent)) *scrollArea {
+	s := &scrollArea{content: content, onScroll: onScroll}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// Scrolled implements fyne.Scrollable
+func (s *scrollArea) Scrolled(ev *fyne.ScrollEvent) {
+	if s.onScroll != nil {
+		s.onScroll(ev)
+	}
+}
+
+// CreateRenderer draws the wrapped content unchanged
+func (s *scrollArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.content)
+}
+
+// tapArea wraps content and calls onTap when it is clicked, for making
+// images and other plain canvas objects clickable
+type tapArea struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+	onTap   func()
+}
+
+// newTapArea creates a tapArea calling onTap on each click
+func newTapArea(content fyne.CanvasObject, onTap func()) *tapArea {
+	t := &tapArea{content: content, onTap: onTap}
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+// Tapped implements fyne.Tappable
+func (t *tapArea) Tapped(*fyne.PointEvent) {
+	if t.onTap != nil {
+		t.onTap()
+	}
+}
+
+// CreateRenderer draws the wrapped content unchanged
+func (t *tapArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(t.content)
+}
+
+// showMessageWindow opens a window with text and a Close button. Like the
+// other popups it is a separate window, since dialogs would be clipped by
+// the bar.
+func showMessageWindow(a fyne.App, title, text string) {
+	win := a.NewWindow(title)
+	win.SetContent(container.NewBorder(nil, widget.NewButton("Close", win.Close), nil, nil,
+		widget.NewLabel(text)))
+	win.Show()
+}
+
+// regionArea lays its parts out in a row and sends each click to the action
+// of the part under the pointer, so one widget can offer several actions,
+// e.g. an up and a down glyph
+type regionArea struct {
+	widget.BaseWidget
+	box     *fyne.Container
+	actions []func()
+}
+
+// newRegionArea creates a regionArea; actions[i] runs when parts[i] is
+// clicked, and a nil action ignores clicks on its part
+func newRegionArea(parts []fyne.CanvasObject, actions []func()) *regionArea {
+	r := &regionArea{box: container.NewHBox(parts...), actions: actions}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+// Tapped implements fyne.Tappable by hit-testing the visible parts
+func (r *regionArea) Tapped(ev *fyne.PointEvent) {
+	for i, part := range r.box.Objects {
+		if !part.Visible() || i >= len(r.actions) || r.actions[i] == nil {
+			continue
+		}
+		left := part.Position().X
+		if ev.Position.X >= left && ev.Position.X < left+part.Size().Width {
+			r.actions[i]()
+			return
+		}
+	}
+}
+
+// CreateRenderer draws the parts unchanged
+func (r *regionArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(r.box)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procNetRoute is the kernel's IPv4 routing table
+const procNetRoute = "/proc/net/route"
+
+// wifiPoll is how often the Wi-Fi network and signal are read
+const wifiPoll = 5 * time.Second
+
+// defaultRouteInterface returns the interface of the IPv4 default route,
+// the one carrying most traffic
+func defaultRouteInterface() (string, error) {
+	f, err := os.Open(procNetRoute)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway ...; the header line never matches
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	return "", errors.New("no default route")
+}
+
+// isWireless reports whether the kernel has a wireless extension for iface
+func isWireless(iface string) bool {
+	_, err := os.Stat(filepath.Join("/sys/class/net", iface, "wireless"))
+	return err == nil
+}
+
+// wifiLink is the association of a wireless interface
+type wifiLink struct {
+	ssid   string
+	signal int // dBm
+}
+
+// readWifiLink asks iw for the network iface is connected to
+func readWifiLink(iface string) (wifiLink, error) {
+	out, err := exec.Command("iw", "dev", iface, "link").Output()
+	if err != nil {
+		return wifiLink{}, err
+	}
+	return parseIwLink(string(out))
+}
+
+// parseIwLink reads the output of "iw dev <iface> link", e.g.
+//
+//	Connected to 12:34:56:78:9a:bc (on wlan0)
+//		SSID: home
+//		signal: -52 dBm
+func parseIwLink(out string) (wifiLink, error) {
+	if strings.HasPrefix(out, "Not connected") {
+		return wifiLink{}, errors.New("not connected")
+	}
+	var link wifiLink
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if ssid, ok := strings.CutPrefix(line, "SSID: "); ok {
+			link.ssid = ssid
+		} else if signal, ok := strings.CutPrefix(line, "signal: "); ok {
+			link.signal, _ = strconv.Atoi(strings.TrimSuffix(signal, " dBm"))
+		}
+	}
+	if link.ssid == "" {
+		return wifiLink{}, errors.New("no SSID in iw output")
+	}
+	return link, nil
+}
+
+// signalPercent maps a signal level to 0..100% the way NetworkManager does:
+// -100 dBm and below is 0%, -50 dBm and above is 100%
+func signalPercent(dBm int) int {
+	return min(max(2*(dBm+100), 0), 100)
+}
+
+// formatWifi renders the Wi-Fi part of the network widget, e.g. "home 96%"
+func formatWifi(link wifiLink) string {
+	return fmt.Sprintf("%s %d%%", link.ssid, signalPercent(link.signal))
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// EWMH _NET_WM_STATE client message actions
+const (
+	netWMStateRemove = 0
+	netWMStateAdd    = 1
+)
+
+// Converts uint32 slice to byte slice for X11 properties
+func uint32SliceToBytes(slice []uint32) []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range slice {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// internAtom looks up (creating if needed) the atom with the given name
+func internAtom(X *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(X, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return xproto.AtomNone, err
+	}
+	return reply.Atom, nil
+}
+
+// getProperty32 reads a 32-bit list property such as _NET_CLIENT_LIST
+func getProperty32(X *xgb.Conn, win xproto.Window, name string) ([]uint32, error) {
+	atom, err := internAtom(X, name)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := xproto.GetProperty(X, false, win, atom, xproto.GetPropertyTypeAny, 0, 1<<16).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format != 32 {
+		return nil, nil
+	}
+	values := make([]uint32, reply.ValueLen)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(reply.Value[i*4:])
+	}
+	return values, nil
+}
+
+// windowClass returns the instance and class parts of a window's WM_CLASS
+func windowClass(X *xgb.Conn, win xproto.Window) (instance, class string) {
+	reply, err := xproto.GetProperty(X, false, win, xproto.AtomWmClass, xproto.AtomString, 0, 256).Reply()
+	if err != nil || reply.ValueLen == 0 {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimRight(string(reply.Value), "\x00"), "\x00")
+	if len(parts) > 0 {
+		instance = parts[0]
+	}
+	if len(parts) > 1 {
+		class = parts[1]
+	}
+	return instance, class
+}
+
+// windowTitle returns _NET_WM_NAME, falling back to the legacy WM_NAME
+func windowTitle(X *xgb.Conn, win xproto.Window) string {
+	if netWMName, err := internAtom(X, "_NET_WM_NAME"); err == nil {
+		reply, err := xproto.GetProperty(X, false, win, netWMName, xproto.GetPropertyTypeAny, 0, 1024).Reply()
+		if err == nil && reply.ValueLen > 0 {
+			return string(reply.Value)
+		}
+	}
+	reply, err := xproto.GetProperty(X, false, win, xproto.AtomWmName, xproto.GetPropertyTypeAny, 0, 1024).Reply()
+	if err != nil {
+		return ""
+	}
+	return string(reply.Value)
+}
+
+// pointerPosition returns the pointer's root coordinates on the bar's
+// connection, or 0, 0 if they can't be read
+func pointerPosition() (x, y int32) {
+	X, err := barConn()
+	if err != nil {
+		return 0, 0
+	}
+	pointer, err := xproto.QueryPointer(X, xproto.Setup(X).DefaultScreen(X).Root).Reply()
+	if err != nil {
+		return 0, 0
+	}
+	return int32(pointer.RootX), int32(pointer.RootY)
+}
+
+// hasAtom reports whether a 32-bit atom list property of win contains any of names
+func hasAtom(X *xgb.Conn, win xproto.Window, property string, names ...string) bool {
+	values, err := getProperty32(X, win, property)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		atom, err := internAtom(X, name)
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			if xproto.Atom(v) == atom {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findWindowByClass searches _NET_CLIENT_LIST for a window whose WM_CLASS
+// instance or class matches name, ignoring case
+func findWindowByClass(X *xgb.Conn, name string) (xproto.Window, bool) {
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	clients, err := getProperty32(X, root, "_NET_CLIENT_LIST")
+	if err != nil {
+		return 0, false
+	}
+	for _, id := range clients {
+		win := xproto.Window(id)
+		instance, class := windowClass(X, win)
+		if strings.EqualFold(instance, name) || strings.EqualFold(class, name) {
+			return win, true
+		}
+	}
+	return 0, false
+}
+
+// activateWindow asks the window manager to raise and focus win,
+// switching to its group if needed
+func activateWindow(X *xgb.Conn, win xproto.Window) error {
+	netActiveWindow, err := internAtom(X, "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+	// Source indication 2 marks the request as coming from a pager/taskbar
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   netActiveWindow,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{2, xproto.TimeCurrentTime, 0, 0, 0}),
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	return xproto.SendEventChecked(X, false, root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect, string(ev.Bytes())).Check()
+}
+
+// minimizeWindow asks the window manager to iconify win with the ICCCM
+// WM_CHANGE_STATE message
+func minimizeWindow(X *xgb.Conn, win xproto.Window) error {
+	wmChangeState, err := internAtom(X, "WM_CHANGE_STATE")
+	if err != nil {
+		return err
+	}
+	const iconicState = 3
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   wmChangeState,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{iconicState, 0, 0, 0, 0}),
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	return xproto.SendEventChecked(X, false, root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect, string(ev.Bytes())).Check()
+}
+
+// x11WindowID waits for the native X11 window behind w to be created.
+// Fyne creates the window asynchronously after Show, so poll until the
+// handle is available or the timeout expires.
+func x11WindowID(w fyne.Window, timeout time.Duration) (uint32, bool) {
+	nw, ok := w.(driver.NativeWindow)
+	if !ok {
+		return 0, false
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		var handle uintptr
+		isX11 := false
+		nw.RunNative(func(ctx any) {
+			if x11, ok := ctx.(driver.X11WindowContext); ok {
+				handle, isX11 = x11.WindowHandle, true
+			}
+		})
+		if !isX11 {
+			return 0, false
+		}
+		if handle != 0 {
+			return uint32(handle), true
+		}
+		if time.Now().After(deadline) {
+			return 0, false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// sendWMState asks the window manager to add or remove a _NET_WM_STATE atom
+func sendWMState(X *xgb.Conn, win xproto.Window, state xproto.Atom, add bool) error {
+	netWMState, err := internAtom(X, "_NET_WM_STATE")
+	if err != nil {
+		return err
+	}
+	action := uint32(netWMStateRemove)
+	if add {
+		action = netWMStateAdd
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   netWMState,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{action, uint32(state), 0, 1, 0}),
+	}
+	root := xproto.Setup(X).DefaultScreen(X).Root
+	return xproto.SendEventChecked(X, false, root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect, string(ev.Bytes())).Check()
+}
+
+// detectXWayland explains why the X server looks like XWayland, or returns ""
+// for a native X11 session. Wayland compositors decide panel placement
+// themselves, so strut reservations may be ignored there.
+func detectXWayland(X *xgb.Conn) string {
+	if session := os.Getenv("XDG_SESSION_TYPE"); session == "wayland" {
+		return "XDG_SESSION_TYPE=wayland"
+	}
+	if display := os.Getenv("WAYLAND_DISPLAY"); display != "" {
+		return "WAYLAND_DISPLAY=" + display
+	}
+	name := "XWAYLAND"
+	if ext, err := xproto.QueryExtension(X, uint16(len(name)), name).Reply(); err == nil && ext.Present {
+		return "X server provides the XWAYLAND extension"
+	}
+	return ""
+}
+
+// barGeometry is where the bar docks: a screen edge ("top", "bottom", "left"
+// or "right"), its thickness across that edge and its length along it, and the
+// window position
+type barGeometry struct {
+	edge      string
+	thickness int
+	length    int
+	x, y      int
+}
+
+// Set X11 Dock properties. When reserveSpace is false the bar floats above
+// other windows without shrinking the work area; above keeps it stacked over
+// normal windows. An error means the bar is not docked and behaves like a
+// normal window.
+func setDockProperties(winID uint32, g barGeometry, reserveSpace, above bool) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+
+	if reason := detectXWayland(X); reason != "" {
+		log.Printf("Running under XWayland (%s): setting dock hints, but the Wayland compositor may ignore the strut reservation", reason)
+	}
+
+	// Get atoms; an EWMH window manager has interned them, so a missing
+	// atom comes back as AtomNone
+	netWMWindowType, err := xproto.InternAtom(X, true, uint16(len("_NET_WM_WINDOW_TYPE")), "_NET_WM_WINDOW_TYPE").Reply()
+	if err != nil {
+		return fmt.Errorf("failed to look up _NET_WM_WINDOW_TYPE: %w", err)
+	}
+	netWMWindowTypeDock, err := xproto.InternAtom(X, true, uint16(len("_NET_WM_WINDOW_TYPE_DOCK")), "_NET_WM_WINDOW_TYPE_DOCK").Reply()
+	if err != nil {
+		return fmt.Errorf("failed to look up _NET_WM_WINDOW_TYPE_DOCK: %w", err)
+	}
+
+	// Set window type to DOCK
+	if netWMWindowType.Atom == xproto.AtomNone || netWMWindowTypeDock.Atom == xproto.AtomNone {
+		return errors.New("window manager does not support _NET_WM_WINDOW_TYPE_DOCK")
+	}
+	data := uint32SliceToBytes([]uint32{uint32(netWMWindowTypeDock.Atom)})
+	if err := xproto.ChangePropertyChecked(X, xproto.PropModeReplace, xproto.Window(winID),
+		netWMWindowType.Atom, xproto.AtomAtom, 32, 1, data).Check(); err != nil {
+		return fmt.Errorf("failed to set dock window type: %w", err)
+	}
+
+	// Keep the bar above normal windows
+	if above {
+		if err := writeAbove(X, xproto.Window(winID), true); err != nil {
+			log.Println("Failed to set _NET_WM_STATE_ABOVE:", err)
+		}
+	}
+
+	if reserveSpace {
+		// Reserve space so Qtile does not overlap the bar
+		_ = writeStrut(X, xproto.Window(winID), g, true)
+	}
+
+	// Move window to its edge
+	_ = xproto.ConfigureWindowChecked(X, xproto.Window(winID),
+		xproto.ConfigWindowX|xproto.ConfigWindowY, []uint32{uint32(g.x), uint32(g.y)}).Check()
+	return nil
+}
+
+// writeAbove adds or removes _NET_WM_STATE_ABOVE on the bar window
+func writeAbove(X *xgb.Conn, win xproto.Window, above bool) error {
+	atom, err := internAtom(X, "_NET_WM_STATE_ABOVE")
+	if err != nil {
+		return err
+	}
+	return sendWMState(X, win, atom, above)
+}
+
+// setAlwaysOnTop changes whether the mapped bar stays above other windows
+func setAlwaysOnTop(winID uint32, above bool) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	return writeAbove(X, xproto.Window(winID), above)
+}
+
+// writeStrut sets _NET_WM_STRUT_PARTIAL for the bar's edge; reserve false
+// releases the reserved space
+func writeStrut(X *xgb.Conn, win xproto.Window, g barGeometry, reserve bool) error {
+	netWMStrut, err := internAtom(X, "_NET_WM_STRUT_PARTIAL")
+	if err != nil {
+		return err
+	}
+	// left, right, top, bottom, then start/end pairs for each edge in that order.
+	// Struts are measured from the root window's edges, so a bar on a monitor
+	// that isn't at the edge of the screen reserves up to its far side.
+	strutPartial := make([]uint32, 12)
+	if reserve {
+		switch g.edge {
+		case "left":
+			strutPartial[0] = uint32(g.x + g.thickness)
+			strutPartial[4], strutPartial[5] = uint32(g.y), uint32(g.y+g.length-1)
+		case "right":
+			rootWidth := int(xproto.Setup(X).DefaultScreen(X).WidthInPixels)
+			strutPartial[1] = uint32(max(rootWidth-g.x, g.thickness))
+			strutPartial[6], strutPartial[7] = uint32(g.y), uint32(g.y+g.length-1)
+		case "bottom":
+			rootHeight := int(xproto.Setup(X).DefaultScreen(X).HeightInPixels)
+			strutPartial[3] = uint32(max(rootHeight-g.y, g.thickness))
+			strutPartial[10], strutPartial[11] = uint32(g.x), uint32(g.x+g.length-1)
+		default:
+			strutPartial[2] = uint32(g.y + g.thickness)
+			strutPartial[8], strutPartial[9] = uint32(g.x), uint32(g.x+g.length-1)
+		}
+	}
+	data := uint32SliceToBytes(strutPartial)
+	return xproto.ChangePropertyChecked(X, xproto.PropModeReplace, win,
+		netWMStrut, xproto.AtomCardinal, 32, uint32(len(strutPartial)), data).Check()
+}
+
+// clearStrut deletes the bar's strut properties when it exits, so the window
+// manager reclaims the space even before it notices the window is gone
+func clearStrut(winID uint32) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"_NET_WM_STRUT_PARTIAL", "_NET_WM_STRUT"} {
+		atom, err := internAtom(X, name)
+		if err != nil {
+			return err
+		}
+		if err := xproto.DeletePropertyChecked(X, xproto.Window(winID), atom).Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmapWindow hides the bar's window, e.g. while gobar shuts down, so no
+// stale bar lingers on screen until the window is destroyed
+func unmapWindow(winID uint32) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	return xproto.UnmapWindowChecked(X, xproto.Window(winID)).Check()
+}
+
+// setStrut updates the bar's reserved space
+func setStrut(winID uint32, g barGeometry, reserve bool) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	return writeStrut(X, xproto.Window(winID), g, reserve)
+}
+
+// detectScreenGeometry returns the default screen's size in pixels. The
+// root window's geometry, unlike the connection setup, follows RandR
+// changes.
+func detectScreenGeometry() (width, height int, err error) {
+	X, err := barConn()
+	if err != nil {
+		return 0, 0, err
+	}
+	root, err := xproto.GetGeometry(X, xproto.Drawable(xproto.Setup(X).DefaultScreen(X).Root)).Reply()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(root.Width), int(root.Height), nil
+}
+
+// setWindowOpacity sets _NET_WM_WINDOW_OPACITY, which compositors such as
+// picom apply to the whole window; opacity runs from 0 to 1
+func setWindowOpacity(winID uint32, opacity float64) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	atom, err := internAtom(X, "_NET_WM_WINDOW_OPACITY")
+	if err != nil {
+		return err
+	}
+	data := uint32SliceToBytes([]uint32{uint32(opacity * 0xffffffff)})
+	return xproto.ChangePropertyChecked(X, xproto.PropModeReplace, xproto.Window(winID),
+		atom, xproto.AtomCardinal, 32, 1, data).Check()
+}
+
+// redock moves the bar window to g after a screen change, and with strut
+// reserves the space of its new edge
+func redock(winID uint32, g barGeometry, strut bool) error {
+	X, err := barConn()
+	if err != nil {
+		return err
+	}
+	if strut {
+		if err := writeStrut(X, xproto.Window(winID), g, true); err != nil {
+			return err
+		}
+	}
+	return xproto.ConfigureWindowChecked(X, xproto.Window(winID),
+		xproto.ConfigWindowX|xproto.ConfigWindowY, []uint32{uint32(g.x), uint32(g.y)}).Check()
+}
+
+// watchRootProperties calls fn, from its own goroutine, whenever one of the
+// named root window properties changes, until the returned watch is closed
+func watchRootProperties(names []string, fn func()) (*xWatch, error) {
+	events, err := watchX()
+	if err != nil {
+		return nil, err
+	}
+	watched := map[xproto.Atom]bool{}
+	for _, name := range names {
+		atom, err := internAtom(events.X, name)
+		if err != nil {
+			events.Close()
+			return nil, err
+		}
+		watched[atom] = true
+	}
+	root := xproto.Setup(events.X).DefaultScreen(events.X).Root
+	if err := events.Select(root, xproto.EventMaskPropertyChange); err != nil {
+		events.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			ev, ok := events.Next()
+			if !ok {
+				return
+			}
+			if pn, ok := ev.(xproto.PropertyNotifyEvent); ok && pn.Window == root && watched[pn.Atom] {
+				fn()
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// sharedX is gobar's one X connection. The bar's window updates, queries
+// such as the RandR outputs, and every watcher of X events use it; a single
+// dispatcher reads its events and hands each to every xWatch, which picks
+// out its own.
+var sharedX struct {
+	sync.Mutex
+	X          *xgb.Conn
+	extensions map[string]bool // set up on X, by name
+	watches    map[*xWatch]bool
+}
+
+// xSelect serialises event selections. X keeps one event mask per window
+// and client, so the mask selected on a window is the union of what its
+// watches asked for.
+var xSelect sync.Mutex
+
+// barConn returns the shared connection, opening it on first use and
+// reopening it on a later call once it has failed or closed
+func barConn() (*xgb.Conn, error) {
+	sharedX.Lock()
+	defer sharedX.Unlock()
+	if sharedX.X != nil {
+		return sharedX.X, nil
+	}
+	X, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	sharedX.X = X
+	sharedX.extensions = map[string]bool{}
+	sharedX.watches = map[*xWatch]bool{}
+	go dispatchX(X)
+	return X, nil
+}
+
+// barExtension returns the shared connection with an extension set up by
+// init, e.g. randr.Init, which runs once per connection
+func barExtension(name string, init func(*xgb.Conn) error) (*xgb.Conn, error) {
+	X, err := barConn()
+	if err != nil {
+		return nil, err
+	}
+	sharedX.Lock()
+	ready := sharedX.X == X && sharedX.extensions[name]
+	sharedX.Unlock()
+	if ready {
+		return X, nil
+	}
+	if err := init(X); err != nil {
+		return nil, fmt.Errorf("%s unavailable: %w", name, err)
+	}
+	sharedX.Lock()
+	if sharedX.X == X {
+		sharedX.extensions[name] = true
+	}
+	sharedX.Unlock()
+	return X, nil
+}
+
+// dispatchX hands the events of X to its watches until it closes, e.g. by
+// an X server restart; the next barConn call then reconnects. Errors of
+// unchecked requests are only logged.
+func dispatchX(X *xgb.Conn) {
+	for {
+		ev, err := X.WaitForEvent()
+		if ev == nil && err == nil {
+			break
+		}
+		if err != nil {
+			log.Println("X error on the bar's connection:", err)
+			continue
+		}
+		sharedX.Lock()
+		for w := range sharedX.watches {
+			w.push(ev)
+		}
+		sharedX.Unlock()
+	}
+	sharedX.Lock()
+	defer sharedX.Unlock()
+	if sharedX.X != X {
+		return
+	}
+	for w := range sharedX.watches {
+		w.end()
+	}
+	sharedX.X, sharedX.watches = nil, nil
+}
+
+// xWatch receives the events of the shared connection for one watcher,
+// queued so a slow watcher never holds up the others
+type xWatch struct {
+	X *xgb.Conn
+
+	mu    sync.Mutex
+	queue []xgb.Event
+	done  bool
+	ready chan struct{}
+
+	selected map[xSelection]uint32 // under xSelect
+}
+
+// xSelection is a window and the kind of events selected on it
+type xSelection struct {
+	win   xproto.Window
+	randr bool // the RandR notify mask rather than the core event mask
+}
+
+// watchX starts a watch on the shared connection
+func watchX() (*xWatch, error) {
+	X, err := barConn()
+	if err != nil {
+		return nil, err
+	}
+	w := &xWatch{X: X, ready: make(chan struct{}, 1), selected: map[xSelection]uint32{}}
+	sharedX.Lock()
+	defer sharedX.Unlock()
+	if sharedX.X != X {
+		return nil, fmt.Errorf("X connection closed")
+	}
+	sharedX.watches[w] = true
+	return w, nil
+}
+
+// push queues an event; sharedX is held
+func (w *xWatch) push(ev xgb.Event) {
+	w.mu.Lock()
+	if !w.done {
+		w.queue = append(w.queue, ev)
+	}
+	w.mu.Unlock()
+	w.wake()
+}
+
+// end makes Next report the end of the watch
+func (w *xWatch) end() {
+	w.mu.Lock()
+	w.done, w.queue = true, nil
+	w.mu.Unlock()
+	w.wake()
+}
+
+// wake lets a waiting Next look at the queue again
+func (w *xWatch) wake() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for the next event; it reports false once the watch is closed
+// or the connection has gone
+func (w *xWatch) Next() (xgb.Event, bool) {
+	for {
+		w.mu.Lock()
+		if w.done {
+			w.mu.Unlock()
+			return nil, false
+		}
+		if len(w.queue) > 0 {
+			ev := w.queue[0]
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.mu.Unlock()
+			return ev, true
+		}
+		w.mu.Unlock()
+		<-w.ready
+	}
+}
+
+// Select asks for the core events in mask on win, adding to what other
+// watches selected there; 0 drops the watch's selection
+func (w *xWatch) Select(win xproto.Window, mask uint32) error {
+	return w.setMask(xSelection{win: win}, mask)
+}
+
+// SelectRandr is Select for RandR notify events; the caller has set up
+// RandR with barExtension
+func (w *xWatch) SelectRandr(win xproto.Window, mask uint16) error {
+	return w.setMask(xSelection{win: win, randr: true}, uint32(mask))
+}
+
+// setMask records the watch's mask for s and selects the union
+func (w *xWatch) setMask(s xSelection, mask uint32) error {
+	xSelect.Lock()
+	defer xSelect.Unlock()
+	if mask == 0 {
+		delete(w.selected, s)
+	} else {
+		w.selected[s] = mask
+	}
+	return selectUnion(w.X, s)
+}
+
+// selectUnion selects on s what the watches of X ask for; xSelect is held
+func selectUnion(X *xgb.Conn, s xSelection) error {
+	var mask uint32
+	sharedX.Lock()
+	if sharedX.X != X {
+		sharedX.Unlock()
+		return fmt.Errorf("X connection closed")
+	}
+	for w := range sharedX.watches {
+		mask |= w.selected[s]
+	}
+	sharedX.Unlock()
+	if s.randr {
+		return randr.SelectInputChecked(X, s.win, uint16(mask)).Check()
+	}
+	return xproto.ChangeWindowAttributesChecked(X, s.win, xproto.CwEventMask, []uint32{mask}).Check()
+}
+
+// Close ends the watch and withdraws its selections
+func (w *xWatch) Close() {
+	sharedX.Lock()
+	delete(sharedX.watches, w)
+	sharedX.Unlock()
+	w.end()
+	xSelect.Lock()
+	defer xSelect.Unlock()
+	for s := range w.selected {
+		delete(w.selected, s)
+		// The window may be gone already
+		selectUnion(w.X, s)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"slices"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// System tray protocol opcodes and XEmbed messages
+const (
+	systemTrayRequestDock = 0
+	xembedEmbeddedNotify  = 0
+	xembedVersion         = 0
+	xembedMapped          = 1 << 0 // _XEMBED_INFO flag of an icon to show
+)
+
+// xembedTray is an XEmbed system tray (freedesktop System Tray protocol)
+// for applications that don't speak StatusNotifierItem. It owns the
+// _NET_SYSTEM_TRAY_Sn selection, reparents each docking icon window into the
+// bar window and keeps it over a placeholder the bar's layout positions.
+type xembedTray struct {
+	X        *xgb.Conn
+	events   *xWatch // set by Start
+	root     xproto.Window
+	iconSize int
+	box      *fyne.Container
+	info     xproto.Atom // _XEMBED_INFO, interned by Start
+
+	mu     sync.Mutex
+	bar    xproto.Window
+	canvas fyne.Canvas
+	icons  []*xembedIcon
+}
+
+// xembedIcon is a docked client window and the slot it covers in the bar
+type xembedIcon struct {
+	win  xproto.Window
+	slot *canvas.Rectangle
+	at   fyne.Position // last position sent to X, in window pixels
+}
+
+// newXembedTray uses the shared X connection; the tray starts once Start
+// knows the bar window
+func newXembedTray(iconSize int) (*xembedTray, error) {
+	X, err := barConn()
+	if err != nil {
+		return nil, err
+	}
+	t := &xembedTray{X: X, root: xproto.Setup(X).DefaultScreen(X).Root, iconSize: iconSize, box: container.NewHBox()}
+	t.box.Hide()
+	return t, nil
+}
+
+// CanvasObject returns the row of icon slots to place in the bar
+func (t *xembedTray) CanvasObject() fyne.CanvasObject {
+	return t.box
+}
+
+// Start claims the tray selection for the bar window bar, drawn on c, and
+// docks icons from then on. It fails when another tray, such as Qtile's
+// Systray widget, already owns the selection.
+func (t *xembedTray) Start(bar uint32, c fyne.Canvas) (err error) {
+	if t.events, err = watchX(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			t.events.Close()
+		}
+	}()
+	selection, err := internAtom(t.X, fmt.Sprintf("_NET_SYSTEM_TRAY_S%d", t.X.DefaultScreen))
+	if err != nil {
+		return err
+	}
+	owner, err := xproto.GetSelectionOwner(t.X, selection).Reply()
+	if err != nil {
+		return err
+	}
+	if owner.Owner != xproto.WindowNone {
+		return fmt.Errorf("another system tray owns the selection (window %d)", owner.Owner)
+	}
+	if t.info, err = internAtom(t.X, "_XEMBED_INFO"); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.bar, t.canvas = xproto.Window(bar), c
+	t.mu.Unlock()
+
+	// The selection belongs to an invisible window, as the spec suggests
+	manager, err := xproto.NewWindowId(t.X)
+	if err != nil {
+		return err
+	}
+	if err := xproto.CreateWindowChecked(t.X, 0, manager, t.root, -1, -1, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskStructureNotify}).Check(); err != nil {
+		return err
+	}
+	if err := xproto.SetSelectionOwnerChecked(t.X, manager, selection, xproto.TimeCurrentTime).Check(); err != nil {
+		return err
+	}
+	if orientation, err := internAtom(t.X, "_NET_SYSTEM_TRAY_ORIENTATION"); err == nil {
+		// Horizontal
+		xproto.ChangeProperty(t.X, xproto.PropModeReplace, manager, orientation, xproto.AtomCardinal, 32, 1,
+			uint32SliceToBytes([]uint32{0}))
+	}
+	// Announce the new tray so running applications dock their icons
+	managerAtom, err := internAtom(t.X, "MANAGER")
+	if err != nil {
+		return err
+	}
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: t.root,
+		Type:   managerAtom,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{xproto.TimeCurrentTime, uint32(selection), uint32(manager), 0, 0}),
+	}
+	xproto.SendEvent(t.X, false, t.root, xproto.EventMaskStructureNotify, string(ev.Bytes()))
+	go t.run()
+	return nil
+}
+
+// run handles dock requests and icon windows going away until the X
+// connection closes
+func (t *xembedTray) run() {
+	opcode, err := internAtom(t.X, "_NET_SYSTEM_TRAY_OPCODE")
+	if err != nil {
+		log.Println("XEmbed tray stopped:", err)
+		t.events.Close()
+		return
+	}
+	for {
+		ev, ok := t.events.Next()
+		if !ok {
+			return
+		}
+		switch e := ev.(type) {
+		case xproto.ClientMessageEvent:
+			data := e.Data.Data32
+			if e.Type == opcode && data[1] == systemTrayRequestDock {
+				t.dock(xproto.Window(data[2]))
+			}
+		case xproto.DestroyNotifyEvent:
+			t.remove(e.Window)
+		case xproto.UnmapNotifyEvent:
+			// Icons hide by being unmapped; only destroying the window or
+			// taking it out of the bar removes one
+			t.setShown(e.Window, false)
+		case xproto.MapNotifyEvent:
+			t.setShown(e.Window, true)
+		case xproto.PropertyNotifyEvent:
+			if e.Atom == t.info && t.docked(e.Window) {
+				t.mapIcon(e.Window)
+			}
+		case xproto.ReparentNotifyEvent:
+			if e.Parent != t.bar {
+				t.remove(e.Window)
+			}
+		case xproto.ConfigureNotifyEvent:
+			t.keepSize(e)
+		}
+	}
+}
+
+// dock embeds the icon window win into the bar
+func (t *xembedTray) dock(win xproto.Window) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if slices.ContainsFunc(t.icons, func(i *xembedIcon) bool { return i.win == win }) {
+		return
+	}
+	size := uint32(t.iconSize)
+	// Follow the icon's lifetime and _XEMBED_INFO, and keep it alive if
+	// gobar exits first
+	t.events.Select(win, xproto.EventMaskStructureNotify|xproto.EventMaskPropertyChange)
+	xproto.ChangeSaveSet(t.X, xproto.SetModeInsert, win)
+	if err := xproto.ReparentWindowChecked(t.X, win, t.bar, 0, 0).Check(); err != nil {
+		log.Println("Failed to dock tray icon:", err)
+		return
+	}
+	xproto.ConfigureWindow(t.X, win, xproto.ConfigWindowWidth|xproto.ConfigWindowHeight, []uint32{size, size})
+	if xembed, err := internAtom(t.X, "_XEMBED"); err == nil {
+		ev := xproto.ClientMessageEvent{
+			Format: 32,
+			Window: win,
+			Type:   xembed,
+			Data:   xproto.ClientMessageDataUnionData32New([]uint32{xproto.TimeCurrentTime, xembedEmbeddedNotify, 0, uint32(t.bar), xembedVersion}),
+		}
+		xproto.SendEvent(t.X, false, win, xproto.EventMaskNoEvent, string(ev.Bytes()))
+	}
+
+	// The slot shows once the window is mapped, see setShown
+	slot := canvas.NewRectangle(color.Transparent)
+	slot.SetMinSize(fyne.NewSize(float32(t.iconSize)/t.canvas.Scale(), float32(t.iconSize)/t.canvas.Scale()))
+	slot.Hide()
+	t.icons = append(t.icons, &xembedIcon{win: win, slot: slot, at: fyne.NewPos(-1, -1)})
+	t.box.Add(slot)
+	t.mapIcon(win)
+}
+
+// mapIcon maps or unmaps an icon window as the XEMBED_MAPPED flag of its
+// _XEMBED_INFO asks; an icon without the property is shown
+func (t *xembedTray) mapIcon(win xproto.Window) {
+	info, err := getProperty32(t.X, win, "_XEMBED_INFO")
+	if err == nil && len(info) >= 2 && info[1]&xembedMapped == 0 {
+		xproto.UnmapWindow(t.X, win)
+		return
+	}
+	xproto.MapWindow(t.X, win)
+}
+
+// docked reports whether win is one of the tray's icons
+func (t *xembedTray) docked(win xproto.Window) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.ContainsFunc(t.icons, func(i *xembedIcon) bool { return i.win == win })
+}
+
+// setShown shows or hides the slot of an icon whose window was mapped or
+// unmapped
+func (t *xembedTray) setShown(win xproto.Window, shown bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := slices.IndexFunc(t.icons, func(i *xembedIcon) bool { return i.win == win })
+	if i < 0 {
+		return
+	}
+	icon := t.icons[i]
+	if shown {
+		icon.slot.Show()
+	} else {
+		icon.slot.Hide()
+	}
+	// Update moves it over its slot again
+	icon.at = fyne.NewPos(-1, -1)
+	t.showBox()
+}
+
+// showBox hides the tray while none of its icons is shown; t.mu is held
+func (t *xembedTray) showBox() {
+	if slices.ContainsFunc(t.icons, func(i *xembedIcon) bool { return i.slot.Visible() }) {
+		t.box.Show()
+	} else {
+		t.box.Hide()
+	}
+	t.box.Refresh()
+}
+
+// remove drops the slot of an icon window that closed or left the bar
+func (t *xembedTray) remove(win xproto.Window) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := slices.IndexFunc(t.icons, func(i *xembedIcon) bool { return i.win == win })
+	if i < 0 {
+		return
+	}
+	t.box.Remove(t.icons[i].slot)
+	t.icons = slices.Delete(t.icons, i, i+1)
+	// Fails harmlessly for a window that is gone
+	t.events.Select(win, 0)
+	t.showBox()
+}
+
+// keepSize puts an icon that resized itself, as some clients do after
+// docking, back to the tray's icon size
+func (t *xembedTray) keepSize(e xproto.ConfigureNotifyEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !slices.ContainsFunc(t.icons, func(i *xembedIcon) bool { return i.win == e.Window }) {
+		return
+	}
+	size := uint16(t.iconSize)
+	if e.Width != size || e.Height != size {
+		xproto.ConfigureWindow(t.X, e.Window, xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+			[]uint32{uint32(size), uint32(size)})
+	}
+}
+
+// Update moves each icon window over its slot after the bar's layout
+// changed; it runs on the scheduler
+func (t *xembedTray) Update() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.icons) == 0 {
+		return
+	}
+	driver := fyne.CurrentApp().Driver()
+	scale := t.canvas.Scale()
+	for _, icon := range t.icons {
+		if !icon.slot.Visible() {
+			continue
+		}
+		pos := driver.AbsolutePositionForObject(icon.slot)
+		at := fyne.NewPos(pos.X*scale, pos.Y*scale)
+		if at == icon.at {
+			continue
+		}
+		icon.at = at
+		xproto.ConfigureWindow(t.X, icon.win, xproto.ConfigWindowX|xproto.ConfigWindowY,
+			[]uint32{uint32(int32(at.X)), uint32(int32(at.Y))})
+	}
+	t.X.Sync()
+}
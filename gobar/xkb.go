@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// xgb has no XKB bindings, so the few XKB requests the keyboard widget
+// needs are built by hand, like xgb's generated extension code does
+const (
+	xkbExtension = "XKEYBOARD"
+
+	// Request minor opcodes
+	xkbUseExtension   = 0
+	xkbSelectEvents   = 1
+	xkbLatchLockState = 5
+
+	// Device spec of the core keyboard
+	xkbUseCoreKbd = 0x100
+	// XKB event subtype, and its bit in SelectEvents masks
+	xkbStateNotify     = 2
+	xkbStateNotifyMask = 1 << xkbStateNotify
+	// StateNotify details: the effective group and the locked modifiers,
+	// the parts the indicator shows
+	xkbGroupState   = 1 << 4
+	xkbModifierLock = 1 << 3
+)
+
+// xkbStateEvent is an XkbStateNotify event: the keyboard's effective group
+// or modifiers changed, e.g. after a layout switch or Caps Lock
+type xkbStateEvent struct {
+	Group      byte
+	LockedMods byte
+	raw        []byte
+}
+
+// Bytes implements xgb.Event
+func (e xkbStateEvent) Bytes() []byte {
+	return e.raw
+}
+
+// String implements xgb.Event
+func (e xkbStateEvent) String() string {
+	return fmt.Sprintf("XkbStateNotify {Group: %d, LockedMods: %#x}", e.Group, e.LockedMods)
+}
+
+// xkbEvent parses an XKB event. All XKB events share one event code, and
+// only StateNotify is selected; others are kept raw.
+func xkbEvent(buf []byte) xgb.Event {
+	ev := xkbStateEvent{raw: append([]byte(nil), buf...)}
+	if len(buf) > 13 && buf[1] == xkbStateNotify {
+		ev.LockedMods, ev.Group = buf[12], buf[13]
+	}
+	return ev
+}
+
+// initXkb enables XKB on X and registers its event, like the Init of xgb's
+// extension packages
+func initXkb(X *xgb.Conn) error {
+	ext, err := xproto.QueryExtension(X, uint16(len(xkbExtension)), xkbExtension).Reply()
+	switch {
+	case err != nil:
+		return err
+	case !ext.Present:
+		return errors.New("no XKEYBOARD extension on the X server")
+	}
+	X.ExtLock.Lock()
+	X.Extensions[xkbExtension] = ext.MajorOpcode
+	X.ExtLock.Unlock()
+	xgb.NewEventFuncs[int(ext.FirstEvent)] = xkbEvent
+
+	// UseExtension must come first and negotiates version 1.0
+	buf := xkbRequest(ext.MajorOpcode, xkbUseExtension, 8)
+	xgb.Put16(buf[4:], 1)
+	xgb.Put16(buf[6:], 0)
+	cookie := X.NewCookie(true, true)
+	X.NewRequest(buf, cookie)
+	reply, err := cookie.Reply()
+	if err != nil {
+		return err
+	}
+	if len(reply) < 2 || reply[1] == 0 {
+		return errors.New("the X server's XKB version is not supported")
+	}
+	return nil
+}
+
+// xkbSelectState subscribes X to the core keyboard's StateNotify events for
+// group and lock changes, not the stream sent for every key press by the
+// other state parts
+func xkbSelectState(X *xgb.Conn) error {
+	buf := xkbRequest(X.Extensions[xkbExtension], xkbSelectEvents, 20)
+	xgb.Put16(buf[4:], xkbUseCoreKbd)
+	xgb.Put16(buf[6:], xkbStateNotifyMask)             // affectWhich
+	xgb.Put16(buf[8:], 0)                              // clear
+	xgb.Put16(buf[10:], 0)                             // selectAll
+	xgb.Put16(buf[12:], 0)                             // affectMap
+	xgb.Put16(buf[14:], 0)                             // map
+	xgb.Put16(buf[16:], xkbGroupState|xkbModifierLock) // affectState
+	xgb.Put16(buf[18:], xkbGroupState|xkbModifierLock) // stateDetails
+	cookie := X.NewCookie(true, false)
+	X.NewRequest(buf, cookie)
+	return cookie.Check()
+}
+
+// xkbLockGroup switches the core keyboard to layout group, as a layout
+// switch key would
+func xkbLockGroup(X *xgb.Conn, group int) error {
+	buf := xkbRequest(X.Extensions[xkbExtension], xkbLatchLockState, 16)
+	xgb.Put16(buf[4:], xkbUseCoreKbd)
+	buf[8] = 1 // lockGroup
+	buf[9] = byte(group)
+	cookie := X.NewCookie(true, false)
+	X.NewRequest(buf, cookie)
+	return cookie.Check()
+}
+
+// xkbRequest starts an XKB request of size bytes, a multiple of four
+func xkbRequest(major, minor byte, size int) []byte {
+	buf := make([]byte, size)
+	buf[0], buf[1] = major, minor
+	xgb.Put16(buf[2:], uint16(size/4))
+	return buf
+}